@@ -45,20 +45,6 @@ func setupArenaTestDB(t *testing.T) func() {
 	// Admin-User für Auth
 	db.Create(&User{ID: 1, Username: "testadmin", IsAdmin: true})
 
-	// ohlcvMemCache initialisieren falls nil
-	ohlcvMemCacheMu.Lock()
-	if ohlcvMemCache == nil {
-		ohlcvMemCache = make(map[string]map[string]*ohlcvCacheEntry)
-	}
-	ohlcvMemCacheMu.Unlock()
-
-	// arenaOHLCVMemCache initialisieren falls nil
-	arenaOHLCVMemCacheMu.Lock()
-	if arenaOHLCVMemCache == nil {
-		arenaOHLCVMemCache = make(map[string]map[string]*ohlcvCacheEntry)
-	}
-	arenaOHLCVMemCacheMu.Unlock()
-
 	return func() {
 		db = origDB
 	}
@@ -147,7 +133,7 @@ func TestArenaBatch_SmallWatchlist(t *testing.T) {
 				t.Logf("  PREFETCH %s: FEHLER nach %v — %v", s, dur, err)
 			} else {
 				atomic.AddInt64(&fetched, 1)
-				saveArenaOHLCVCache(s, "60m", ohlcv)
+				barStore.Put(barNSArena, s, "60m", ohlcv)
 			}
 		}(sym)
 	}
@@ -174,7 +160,7 @@ func TestArenaBatch_SmallWatchlist(t *testing.T) {
 	var totalTrades int64
 
 	for _, sym := range symbols {
-		ohlcv, ok := getArenaOHLCVFromMemCache(sym, "60m")
+		ohlcv, ok := barStore.Get(barNSArena, sym, "60m")
 		if !ok || len(ohlcv) < 50 {
 			continue
 		}
@@ -258,7 +244,7 @@ func TestArenaBatch_FullWatchlist(t *testing.T) {
 
 			if lastErr == nil && len(ohlcv) > 0 {
 				atomic.AddInt64(&prefetched, 1)
-				saveArenaOHLCVCache(s, "60m", ohlcv)
+				barStore.Put(barNSArena, s, "60m", ohlcv)
 			} else {
 				atomic.AddInt64(&prefetchFailed, 1)
 			}
@@ -299,7 +285,7 @@ func TestArenaBatch_FullWatchlist(t *testing.T) {
 			btSem <- struct{}{}
 			defer func() { <-btSem }()
 
-			ohlcv, ok := getArenaOHLCVFromMemCache(s, "60m")
+			ohlcv, ok := barStore.Get(barNSArena, s, "60m")
 			if !ok || len(ohlcv) < 50 {
 				atomic.AddInt64(&skipped, 1)
 				return
@@ -506,11 +492,9 @@ func TestArenaBatch_PrefetchTiming(t *testing.T) {
 	}
 
 	// Cache leeren für diese Symbole (damit wirklich Yahoo gefetcht wird)
-	ohlcvMemCacheMu.Lock()
 	for _, sym := range symbols {
-		delete(ohlcvMemCache, sym)
+		barStore.Delete(barNSLive, sym, "60m")
 	}
-	ohlcvMemCacheMu.Unlock()
 
 	t.Logf("=== Prefetch Timing: %d Symbole (Cache geleert) ===", len(symbols))
 
@@ -827,7 +811,7 @@ func TestArenaBatch_HannTrend(t *testing.T) {
 			defer func() { <-sem }()
 			ohlcv, err := fetchOHLCVFromYahoo(s, "2y", "60m")
 			if err == nil && len(ohlcv) > 0 {
-				saveArenaOHLCVCache(s, "60m", ohlcv)
+				barStore.Put(barNSArena, s, "60m", ohlcv)
 				atomic.AddInt64(&fetched, 1)
 			}
 		}(sym)
@@ -842,7 +826,7 @@ func TestArenaBatch_HannTrend(t *testing.T) {
 
 	start := time.Now()
 	for _, sym := range symbols {
-		ohlcv, ok := getArenaOHLCVFromMemCache(sym, "60m")
+		ohlcv, ok := barStore.Get(barNSArena, sym, "60m")
 		if !ok || len(ohlcv) < 50 {
 			continue
 		}
@@ -8,38 +8,20 @@ import (
 	"time"
 )
 
-// setupArenaCacheTest initializes the arena cache with a temp directory
+// setupArenaCacheTest points the bar store at a temp directory
 // and returns a cleanup function that restores original state.
 func setupArenaCacheTest(t *testing.T) func() {
 	t.Helper()
 
-	origDir := arenaOHLCVCacheDir
-	origCache := arenaOHLCVMemCache
-
-	tmpDir := t.TempDir()
-	arenaOHLCVCacheDir = tmpDir
-
-	arenaOHLCVMemCacheMu.Lock()
-	arenaOHLCVMemCache = make(map[string]map[string]*ohlcvCacheEntry)
-	arenaOHLCVMemCacheMu.Unlock()
-
-	// Also init the shared cache to ensure isolation tests work
-	ohlcvMemCacheMu.Lock()
-	if ohlcvMemCache == nil {
-		ohlcvMemCache = make(map[string]map[string]*ohlcvCacheEntry)
-	}
-	ohlcvMemCacheMu.Unlock()
+	origStore := barStore
+	barStore = newBarStore(t.TempDir())
 
 	return func() {
-		time.Sleep(100 * time.Millisecond) // wait for async file writes to finish
-		arenaOHLCVCacheDir = origDir
-		arenaOHLCVMemCacheMu.Lock()
-		arenaOHLCVMemCache = origCache
-		arenaOHLCVMemCacheMu.Unlock()
+		barStore = origStore
 	}
 }
 
-// TestArenaCache_SetAndGet verifies basic write→read cycle in arena memory cache
+// TestArenaCache_SetAndGet verifies basic write→read cycle in the arena namespace
 func TestArenaCache_SetAndGet(t *testing.T) {
 	cleanup := setupArenaCacheTest(t)
 	defer cleanup()
@@ -50,9 +32,9 @@ func TestArenaCache_SetAndGet(t *testing.T) {
 		{Time: 3000, Open: 110, High: 120, Low: 100, Close: 115, Volume: 800},
 	}
 
-	setArenaOHLCVInMemCache("AAPL", "60m", bars)
+	barStore.Put(barNSArena, "AAPL", "60m", bars)
 
-	got, ok := getArenaOHLCVFromMemCache("AAPL", "60m")
+	got, ok := barStore.Get(barNSArena, "AAPL", "60m")
 	if !ok {
 		t.Fatal("expected to find AAPL in arena cache")
 	}
@@ -69,23 +51,18 @@ func TestArenaCache_MissReturnsEmpty(t *testing.T) {
 	cleanup := setupArenaCacheTest(t)
 	defer cleanup()
 
-	_, ok := getArenaOHLCVFromMemCache("UNKNOWN", "60m")
+	_, ok := barStore.Get(barNSArena, "UNKNOWN", "60m")
 	if ok {
 		t.Error("expected cache miss for unknown symbol")
 	}
 }
 
-// TestArenaCache_IsolationFromLiveCache verifies that arena and live caches are independent.
-// Writing to arena cache must NOT appear in live cache and vice versa.
+// TestArenaCache_IsolationFromLiveCache verifies that arena and live namespaces are independent.
+// Writing to the arena namespace must NOT appear in the live namespace and vice versa.
 func TestArenaCache_IsolationFromLiveCache(t *testing.T) {
 	cleanup := setupArenaCacheTest(t)
 	defer cleanup()
 
-	// Also set up a temp dir for the live cache
-	origLiveDir := ohlcvCacheDir
-	ohlcvCacheDir = t.TempDir()
-	defer func() { ohlcvCacheDir = origLiveDir }()
-
 	arenaBars := []OHLCV{
 		{Time: 1000, Open: 100, High: 110, Low: 90, Close: 105, Volume: 500},
 	}
@@ -93,13 +70,10 @@ func TestArenaCache_IsolationFromLiveCache(t *testing.T) {
 		{Time: 2000, Open: 200, High: 220, Low: 190, Close: 210, Volume: 999},
 	}
 
-	// Write to arena cache
-	setArenaOHLCVInMemCache("TEST", "60m", arenaBars)
-	// Write to live cache
-	setOHLCVInMemCache("TEST", "60m", liveBars)
+	barStore.Put(barNSArena, "TEST", "60m", arenaBars)
+	barStore.Put(barNSLive, "TEST", "60m", liveBars)
 
-	// Arena should have arena data
-	arenaGot, ok := getArenaOHLCVFromMemCache("TEST", "60m")
+	arenaGot, ok := barStore.Get(barNSArena, "TEST", "60m")
 	if !ok {
 		t.Fatal("expected to find TEST in arena cache")
 	}
@@ -107,8 +81,7 @@ func TestArenaCache_IsolationFromLiveCache(t *testing.T) {
 		t.Errorf("arena cache: expected Close=105, got %f", arenaGot[0].Close)
 	}
 
-	// Live should have live data
-	liveGot, ok := getOHLCVFromMemCache("TEST", "60m")
+	liveGot, ok := barStore.Get(barNSLive, "TEST", "60m")
 	if !ok {
 		t.Fatal("expected to find TEST in live cache")
 	}
@@ -117,15 +90,21 @@ func TestArenaCache_IsolationFromLiveCache(t *testing.T) {
 	}
 
 	// Cross-check: arena symbol in a different interval should NOT be in live
-	setArenaOHLCVInMemCache("ARENA_ONLY", "5m", arenaBars)
-	_, ok = getOHLCVFromMemCache("ARENA_ONLY", "5m")
+	barStore.Put(barNSArena, "ARENA_ONLY", "5m", arenaBars)
+	_, ok = barStore.Get(barNSLive, "ARENA_ONLY", "5m")
 	if ok {
 		t.Error("arena-only symbol should NOT appear in live cache")
 	}
+
+	// Also after a restart (fresh memory, same directory)
+	barStore = newBarStore(barStore.dir)
+	if _, ok := barStore.Get(barNSLive, "ARENA_ONLY", "5m"); ok {
+		t.Error("arena-only symbol should NOT appear in live segments")
+	}
 }
 
-// TestArenaCache_FileWriteAndRead verifies that data persists to gzip files
-// and can be read back via the arena file path
+// TestArenaCache_FileWriteAndRead verifies that data persists to segment files
+// and can be read back after a restart
 func TestArenaCache_FileWriteAndRead(t *testing.T) {
 	cleanup := setupArenaCacheTest(t)
 	defer cleanup()
@@ -135,44 +114,40 @@ func TestArenaCache_FileWriteAndRead(t *testing.T) {
 		{Time: 2000, Open: 105, High: 115, Low: 95, Close: 110, Volume: 600},
 	}
 
-	err := writeArenaOHLCVFile("MSFT", "60m", bars)
-	if err != nil {
-		t.Fatalf("writeArenaOHLCVFile failed: %v", err)
-	}
+	barStore.Put(barNSArena, "MSFT", "60m", bars)
 
-	// File should exist in arena dir, not in live dir
-	arenaPath := arenaOHLCVFilePath("MSFT", "60m")
-	if _, err := os.Stat(arenaPath); os.IsNotExist(err) {
-		t.Fatal("expected arena file to exist")
+	// File should exist in the arena namespace, not in live
+	if _, err := os.Stat(barStore.segmentPath(barNSArena, "MSFT", "60m")); os.IsNotExist(err) {
+		t.Fatal("expected arena segment to exist")
+	}
+	if _, err := os.Stat(barStore.segmentPath(barNSLive, "MSFT", "60m")); !os.IsNotExist(err) {
+		t.Fatal("expected no live segment")
 	}
 
-	readBars, modTime, err := readArenaOHLCVFile("MSFT", "60m")
-	if err != nil {
-		t.Fatalf("readArenaOHLCVFile failed: %v", err)
+	barStore = newBarStore(barStore.dir)
+	readBars, ok := barStore.Get(barNSArena, "MSFT", "60m")
+	if !ok {
+		t.Fatal("expected MSFT after restart")
 	}
 	if len(readBars) != 2 {
 		t.Fatalf("expected 2 bars, got %d", len(readBars))
 	}
-	if readBars[1].Close != 110 {
-		t.Errorf("expected Close=110, got %f", readBars[1].Close)
+	if readBars[1] != bars[1] {
+		t.Errorf("expected %+v, got %+v", bars[1], readBars[1])
 	}
-	if modTime.IsZero() {
-		t.Error("expected non-zero mod time")
+	if modTime, err := barStore.UpdatedAt(barNSArena, "MSFT", "60m"); err != nil || modTime.IsZero() {
+		t.Errorf("expected non-zero update time, got %v (%v)", modTime, err)
 	}
 }
 
-// TestArenaCache_FilePathSeparation verifies that arena and live file paths
-// point to different directories
+// TestArenaCache_FilePathSeparation verifies that arena and live segments
+// live in different directories
 func TestArenaCache_FilePathSeparation(t *testing.T) {
 	cleanup := setupArenaCacheTest(t)
 	defer cleanup()
 
-	origLiveDir := ohlcvCacheDir
-	ohlcvCacheDir = t.TempDir()
-	defer func() { ohlcvCacheDir = origLiveDir }()
-
-	arenaPath := arenaOHLCVFilePath("AAPL", "60m")
-	livePath := ohlcvFilePath("AAPL", "60m")
+	arenaPath := barStore.segmentPath(barNSArena, "AAPL", "60m")
+	livePath := barStore.segmentPath(barNSLive, "AAPL", "60m")
 
 	if arenaPath == livePath {
 		t.Errorf("arena and live paths should differ:\n  arena: %s\n  live:  %s", arenaPath, livePath)
@@ -185,8 +160,8 @@ func TestArenaCache_FilePathSeparation(t *testing.T) {
 	}
 }
 
-// TestArenaCache_LazyLoadFromFile verifies that getArenaOHLCVFromMemCache
-// lazy-loads from file when not in memory
+// TestArenaCache_LazyLoadFromFile verifies that Get lazy-loads from the
+// segment file when not in memory
 func TestArenaCache_LazyLoadFromFile(t *testing.T) {
 	cleanup := setupArenaCacheTest(t)
 	defer cleanup()
@@ -196,21 +171,16 @@ func TestArenaCache_LazyLoadFromFile(t *testing.T) {
 	}
 
 	// Write directly to file (bypass memory)
-	err := writeArenaOHLCVFile("LAZY", "60m", bars)
-	if err != nil {
-		t.Fatalf("writeArenaOHLCVFile failed: %v", err)
+	if _, err := writeBarSegment(barStore.segmentPath(barNSArena, "LAZY", "60m"), bars); err != nil {
+		t.Fatalf("writeBarSegment failed: %v", err)
 	}
 
 	// Memory should be empty
-	arenaOHLCVMemCacheMu.RLock()
-	_, inMem := arenaOHLCVMemCache["LAZY"]
-	arenaOHLCVMemCacheMu.RUnlock()
-	if inMem {
+	if barStore.cached(barStoreKey(barNSArena, "LAZY", "60m")) != nil {
 		t.Fatal("expected LAZY not to be in memory yet")
 	}
 
-	// getArenaOHLCVFromMemCache should lazy-load from file
-	got, ok := getArenaOHLCVFromMemCache("LAZY", "60m")
+	got, ok := barStore.Get(barNSArena, "LAZY", "60m")
 	if !ok {
 		t.Fatal("expected lazy-load to succeed")
 	}
@@ -219,51 +189,24 @@ func TestArenaCache_LazyLoadFromFile(t *testing.T) {
 	}
 
 	// Now it should be in memory
-	arenaOHLCVMemCacheMu.RLock()
-	_, inMem = arenaOHLCVMemCache["LAZY"]
-	arenaOHLCVMemCacheMu.RUnlock()
-	if !inMem {
+	if barStore.cached(barStoreKey(barNSArena, "LAZY", "60m")) == nil {
 		t.Error("expected LAZY to be in memory after lazy-load")
 	}
 }
 
-// TestArenaCache_SaveOHLCVCacheWrapper verifies that saveArenaOHLCVCache
-// stores data retrievable from memory
-func TestArenaCache_SaveOHLCVCacheWrapper(t *testing.T) {
-	cleanup := setupArenaCacheTest(t)
-	defer cleanup()
-
-	bars := []OHLCV{
-		{Time: 1000, Open: 100, High: 110, Low: 90, Close: 105, Volume: 500},
-	}
-
-	saveArenaOHLCVCache("WRAP", "5m", bars)
-
-	got, ok := getArenaOHLCVFromMemCache("WRAP", "5m")
-	if !ok {
-		t.Fatal("expected to find WRAP after saveArenaOHLCVCache")
-	}
-	if len(got) != 1 {
-		t.Fatalf("expected 1 bar, got %d", len(got))
-	}
-}
-
-// TestArenaCache_GetSymbols verifies that getArenaOHLCVMemCacheSymbols returns
+// TestArenaCache_GetSymbols verifies that Symbols returns
 // correct symbols from both memory and disk
 func TestArenaCache_GetSymbols(t *testing.T) {
 	cleanup := setupArenaCacheTest(t)
 	defer cleanup()
 
-	// Put AAPL in memory
-	setArenaOHLCVInMemCache("AAPL", "60m", []OHLCV{{Time: 1000, Close: 100}})
+	// Put AAPL in memory (and on disk)
+	barStore.Put(barNSArena, "AAPL", "60m", []OHLCV{{Time: 1000, Close: 100}})
 
 	// Put MSFT on disk only
-	writeArenaOHLCVFile("MSFT", "60m", []OHLCV{{Time: 2000, Close: 200}})
-
-	// Wait a tiny bit for async file write from setArenaOHLCVInMemCache
-	time.Sleep(50 * time.Millisecond)
+	writeBarSegment(barStore.segmentPath(barNSArena, "MSFT", "60m"), []OHLCV{{Time: 2000, Close: 200}})
 
-	symbols := getArenaOHLCVMemCacheSymbols("60m")
+	symbols := barStore.Symbols(barNSArena, "60m")
 	if !symbols["AAPL"] {
 		t.Error("expected AAPL in symbols")
 	}
@@ -272,13 +215,17 @@ func TestArenaCache_GetSymbols(t *testing.T) {
 	}
 
 	// Different interval should not include these
-	other := getArenaOHLCVMemCacheSymbols("5m")
+	other := barStore.Symbols(barNSArena, "5m")
 	if other["AAPL"] {
 		t.Error("AAPL should not be in 5m symbols")
 	}
+	// Nor a different namespace
+	if barStore.Symbols(barNSLive, "60m")["AAPL"] {
+		t.Error("AAPL should not be in live symbols")
+	}
 }
 
-// TestArenaCache_ConcurrentAccess verifies thread safety of arena cache
+// TestArenaCache_ConcurrentAccess verifies thread safety of the arena namespace
 func TestArenaCache_ConcurrentAccess(t *testing.T) {
 	cleanup := setupArenaCacheTest(t)
 	defer cleanup()
@@ -292,7 +239,8 @@ func TestArenaCache_ConcurrentAccess(t *testing.T) {
 		go func(s string) {
 			defer wg.Done()
 			bars := []OHLCV{{Time: 1000, Open: 100, High: 110, Low: 90, Close: 105, Volume: 500}}
-			setArenaOHLCVInMemCache(s, "60m", bars)
+			barStore.Put(barNSArena, s, "60m", bars)
+			barStore.Append(barNSArena, s, "60m", []OHLCV{{Time: 2000, Close: 106}})
 		}(sym)
 	}
 	wg.Wait()
@@ -302,19 +250,19 @@ func TestArenaCache_ConcurrentAccess(t *testing.T) {
 		wg.Add(1)
 		go func(s string) {
 			defer wg.Done()
-			got, ok := getArenaOHLCVFromMemCache(s, "60m")
+			got, ok := barStore.Get(barNSArena, s, "60m")
 			if !ok {
 				t.Errorf("expected to find %s in arena cache", s)
 			}
-			if len(got) != 1 {
-				t.Errorf("%s: expected 1 bar, got %d", s, len(got))
+			if len(got) != 2 {
+				t.Errorf("%s: expected 2 bars, got %d", s, len(got))
 			}
 		}(sym)
 	}
 	wg.Wait()
 
-	// Verify all symbols appear in getArenaOHLCVMemCacheSymbols
-	allSymbols := getArenaOHLCVMemCacheSymbols("60m")
+	// Verify all symbols appear in Symbols
+	allSymbols := barStore.Symbols(barNSArena, "60m")
 	for _, sym := range symbols {
 		if !allSymbols[sym] {
 			t.Errorf("expected %s in arena cache symbols", sym)
@@ -323,60 +271,52 @@ func TestArenaCache_ConcurrentAccess(t *testing.T) {
 }
 
 // TestArenaCache_FilePathSanitization verifies that special characters
-// in symbols are sanitized (e.g. BRK.B → BRK_B in filename)
+// in symbols map to a single, reversible path element
 func TestArenaCache_FilePathSanitization(t *testing.T) {
 	cleanup := setupArenaCacheTest(t)
 	defer cleanup()
 
-	bars := []OHLCV{{Time: 1000, Close: 100}}
-
-	err := writeArenaOHLCVFile("BRK.B", "60m", bars)
-	if err != nil {
-		t.Fatalf("writeArenaOHLCVFile failed: %v", err)
+	for _, sym := range []string{"BRK.B", "^GSPC", "EUR/USD", ".."} {
+		barStore.Put(barNSArena, sym, "60m", []OHLCV{{Time: 1000, Close: 100}})
+		path := barStore.segmentPath(barNSArena, sym, "60m")
+		if filepath.Dir(filepath.Dir(path)) != filepath.Join(barStore.dir, barNSArena) {
+			t.Errorf("%s: segment escapes the namespace directory: %s", sym, path)
+		}
 	}
-
-	// Path should use underscore
-	path := arenaOHLCVFilePath("BRK.B", "60m")
-	expected := filepath.Join(arenaOHLCVCacheDir, "BRK.B_60m.json.gz")
-	if path != expected {
-		t.Errorf("expected path %s, got %s", expected, path)
+	if got := barStore.segmentPath(barNSArena, "BRK.B", "60m"); got != filepath.Join(barStore.dir, barNSArena, "BRK.B", "60m.bars") {
+		t.Errorf("unexpected path for BRK.B: %s", got)
 	}
 
-	// Read back should work
-	readBars, _, err := readArenaOHLCVFile("BRK.B", "60m")
-	if err != nil {
-		t.Fatalf("readArenaOHLCVFile failed: %v", err)
-	}
-	if len(readBars) != 1 {
-		t.Fatalf("expected 1 bar, got %d", len(readBars))
+	barStore = newBarStore(barStore.dir)
+	symbols := barStore.Symbols(barNSArena, "60m")
+	for _, sym := range []string{"BRK.B", "^GSPC", "EUR/USD", ".."} {
+		if !symbols[sym] {
+			t.Errorf("expected %s to round-trip, got %v", sym, symbols)
+		}
 	}
 }
 
-// TestArenaCache_ReadNonExistentFile verifies error handling for missing files
+// TestArenaCache_ReadNonExistentFile verifies error handling for missing segments
 func TestArenaCache_ReadNonExistentFile(t *testing.T) {
 	cleanup := setupArenaCacheTest(t)
 	defer cleanup()
 
-	_, _, err := readArenaOHLCVFile("NONEXISTENT", "60m")
-	if err == nil {
-		t.Error("expected error when reading non-existent file")
+	if _, err := barStore.UpdatedAt(barNSArena, "NONEXISTENT", "60m"); err == nil {
+		t.Error("expected error for non-existent segment")
+	}
+	if _, err := barStore.Range(barNSArena, "NONEXISTENT", "60m", 0, time.Now().Unix()); err == nil {
+		t.Error("expected error for range read of non-existent segment")
 	}
 }
 
-// TestArenaCache_EmptyBarsNotCached verifies that getArenaOHLCVFromMemCache
-// returns false for empty bar slices
+// TestArenaCache_EmptyBarsNotCached verifies that empty bar slices are not stored
 func TestArenaCache_EmptyBarsNotCached(t *testing.T) {
 	cleanup := setupArenaCacheTest(t)
 	defer cleanup()
 
-	// Write empty bars to memory
-	arenaOHLCVMemCacheMu.Lock()
-	arenaOHLCVMemCache["EMPTY"] = map[string]*ohlcvCacheEntry{
-		"60m": {Bars: []OHLCV{}, LastAccess: time.Now()},
-	}
-	arenaOHLCVMemCacheMu.Unlock()
+	barStore.Put(barNSArena, "EMPTY", "60m", []OHLCV{})
 
-	_, ok := getArenaOHLCVFromMemCache("EMPTY", "60m")
+	_, ok := barStore.Get(barNSArena, "EMPTY", "60m")
 	if ok {
 		t.Error("expected empty bars to return false")
 	}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func setupBackfillTestDB(t *testing.T) {
+	t.Helper()
+	setupLiveTestDB(t)
+	db.AutoMigrate(&BackfillJob{}, &BackfillJobItem{}, &BotLog{}, &GlobalSetting{}, &DataQualityReport{})
+}
+
+// withBackfillHandler registers a temporary job kind
+func withBackfillHandler(t *testing.T, kind string, h backfillHandler) {
+	t.Helper()
+	backfillHandlers[kind] = h
+	t.Cleanup(func() { delete(backfillHandlers, kind) })
+}
+
+func testBackfillItems(symbols ...string) []BackfillJobItem {
+	items := make([]BackfillJobItem, len(symbols))
+	for i, s := range symbols {
+		items[i] = BackfillJobItem{Symbol: s, Interval: "1d"}
+	}
+	return items
+}
+
+func TestBackfillJob_RunsItemsAndFinishes(t *testing.T) {
+	setupBackfillTestDB(t)
+	finished := false
+	withBackfillHandler(t, "test", backfillHandler{
+		concurrency: 2,
+		item: func(run *backfillRun, item BackfillJobItem) (backfillItemResult, error) {
+			switch item.Symbol {
+			case "SKIP":
+				return backfillItemResult{}, fmt.Errorf("%w: test", errBackfillSkip)
+			case "FAIL":
+				return backfillItemResult{}, errors.New("kaputt")
+			}
+			return backfillItemResult{Bars: len(item.Symbol)}, nil
+		},
+		finish: func(run *backfillRun) { finished = run.Job.Done == 2 },
+	})
+
+	job, err := enqueueBackfillJob("test", "tester", backfillParams{MaxAttempts: 1}, testBackfillItems("AAPL", "MSFT", "SKIP", "FAIL"))
+	if err != nil || job.Status != "queued" || job.Total != 4 {
+		t.Fatalf("enqueue failed: %v %+v", err, job)
+	}
+	runBackfillJob(job, make(chan struct{}))
+
+	db.First(&job, job.ID)
+	if job.Status != "done" || job.Done != 2 || job.Skipped != 1 || job.Failed != 1 || job.FinishedAt == nil {
+		t.Fatalf("unexpected job state: %+v", job)
+	}
+	if !finished {
+		t.Error("finish hook should see the final counters")
+	}
+	items, results := backfillItemResults(job.ID)
+	if items[0].Status != "done" || results[0].Bars != 4 || items[3].LastError != "kaputt" {
+		t.Errorf("unexpected items: %+v", items)
+	}
+}
+
+func TestBackfillJob_RetryScheduling(t *testing.T) {
+	setupBackfillTestDB(t)
+	budget := &providerError{Provider: "twelvedata", Budget: true, Err: errors.New("TWELVE_DATA_RATE_LIMIT: daily budget exhausted (750/800)")}
+	chain := func(errs ...error) error {
+		c := &marketDataChainError{}
+		for _, err := range errs {
+			c.Errs = append(c.Errs, err.(*providerError))
+		}
+		return c
+	}
+	errs := map[string]error{
+		"RETRY":  errors.New("timeout"),
+		"BUDGET": chain(budget),
+		"RATE":   providerStatusError("yahoo", 429, "yahoo RATE status 429"),
+		"NODATA": fmt.Errorf("failed to fetch historical data: %w", chain(providerNoData("yahoo", "no data found"))),
+		"MIXED":  chain(providerStatusError("yahoo", 503, "yahoo MIXED status 503"), providerNoData("twelvedata", "no monthly data from Twelve Data")),
+		// Only the provider that ran decides: Yahoo doesn't know the symbol
+		"UNKNOWN": chain(providerStatusError("yahoo", 404, "yahoo UNKNOWN status 404"), budget),
+		// The message alone is no classification
+		"TEXT": errors.New("alle Provider fehlgeschlagen (yahoo: no data found)"),
+	}
+	h := backfillHandler{item: func(run *backfillRun, item BackfillJobItem) (backfillItemResult, error) {
+		return backfillItemResult{}, errs[item.Symbol]
+	}}
+	job, _ := enqueueBackfillJob("ohlcv", "tester", backfillParams{}, testBackfillItems("RETRY", "BUDGET", "RATE", "NODATA", "MIXED", "UNKNOWN", "TEXT"))
+	run := &backfillRun{Job: job, cancel: make(chan struct{})}
+
+	var items []BackfillJobItem
+	db.Where("job_id = ?", job.ID).Order("seq").Find(&items)
+	before := time.Now()
+	for _, item := range items {
+		runBackfillItem(run, h, item, 3)
+	}
+	db.Where("job_id = ?", job.ID).Order("seq").Find(&items)
+
+	retry, budgetItem, rate, nodata, mixed, unknown, text := items[0], items[1], items[2], items[3], items[4], items[5], items[6]
+	if retry.Status != "pending" || retry.Attempts != 1 || retry.NextAttemptAt.Before(before.Add(backfillBaseBackoff-time.Second)) {
+		t.Errorf("expected a backoff retry, got %+v", retry)
+	}
+	if budgetItem.Status != "pending" || budgetItem.Attempts != 0 || budgetItem.NextAttemptAt.YearDay() == before.YearDay() {
+		t.Errorf("expected the budget item to wait for the next day without using an attempt, got %+v", budgetItem)
+	}
+	if rate.Status != "pending" || time.Until(run.pausedUntil) < time.Minute {
+		t.Errorf("expected a rate limit to pause the job, got %+v (paused until %v)", rate, run.pausedUntil)
+	}
+
+	if nodata.Status != "failed" || nodata.Attempts != 1 {
+		t.Errorf("an unknown symbol must fail without retries, got %+v", nodata)
+	}
+	if mixed.Status != "pending" {
+		t.Errorf("a provider outage must be retried even if another provider had no data, got %+v", mixed)
+	}
+	if unknown.Status != "failed" || unknown.Attempts != 1 {
+		t.Errorf("a budget refusal must not defer a symbol the other provider doesn't know, got %+v", unknown)
+	}
+	if text.Status != "pending" || text.Attempts != 1 {
+		t.Errorf("an untyped error must be retried, got %+v", text)
+	}
+
+	// Jobs that must finish the same day skip budget-limited items instead of deferring them
+	skipping := h
+	skipping.skipBudget = true
+	budgetItem.Status, budgetItem.Attempts = "pending", 0
+	runBackfillItem(run, skipping, budgetItem, 3)
+	db.First(&budgetItem, budgetItem.ID)
+	if budgetItem.Status != "skipped" {
+		t.Errorf("expected the budget item to be skipped, got %+v", budgetItem)
+	}
+	if !backfillHandlers["full_update"].skipBudget {
+		t.Error("the full update must not wait for the next day's budget")
+	}
+
+	// Backoff doubles and is capped
+	if backfillBackoff(1) != 30*time.Second || backfillBackoff(2) != time.Minute || backfillBackoff(20) != backfillMaxBackoff {
+		t.Error("unexpected backoff schedule")
+	}
+
+	// The last allowed attempt fails the item for good
+	retry.Attempts = 2
+	runBackfillItem(run, h, retry, 3)
+	db.First(&retry, retry.ID)
+	if retry.Status != "failed" || retry.Attempts != 3 {
+		t.Errorf("expected the item to fail after 3 attempts, got %+v", retry)
+	}
+}
+
+func TestBackfillJob_InteractiveLane(t *testing.T) {
+	setupBackfillTestDB(t)
+	release := make(chan struct{})
+	withBackfillHandler(t, "lanetest", backfillHandler{concurrency: 1, item: func(run *backfillRun, item BackfillJobItem) (backfillItemResult, error) {
+		select {
+		case <-release:
+		case <-run.cancel:
+			return backfillItemResult{}, errBackfillCancelled
+		}
+		return backfillItemResult{}, nil
+	}})
+	long, _ := enqueueBackfillJob("lanetest", "admin", backfillParams{}, testBackfillItems("A"))
+	queued, _ := enqueueBackfillJob("lanetest", "admin", backfillParams{}, testBackfillItems("B"))
+	interactive, _ := enqueueBackfillJob("lanetest", "admin", backfillParams{Interactive: true}, testBackfillItems("C"))
+
+	backfillDispatch()
+	backfillMu.Lock()
+	admin, fast := backfillActive["lanetest"], backfillActive["lanetest/interactive"]
+	backfillMu.Unlock()
+	if admin != long.ID || fast != interactive.ID {
+		t.Errorf("expected job %d and the interactive job %d to run side by side, got %d/%d", long.ID, interactive.ID, admin, fast)
+	}
+
+	cancelBackfillJob(queued.ID)
+	close(release)
+	for i := 0; i < 100; i++ {
+		backfillMu.Lock()
+		_, busy := backfillActive["lanetest"]
+		_, busyFast := backfillActive["lanetest/interactive"]
+		backfillMu.Unlock()
+		if !busy && !busyFast {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	db.First(&interactive, interactive.ID)
+	if interactive.Status != "done" {
+		t.Errorf("expected the interactive job to finish, got %s", interactive.Status)
+	}
+}
+
+func TestBackfillJob_ResumeAfterRestart(t *testing.T) {
+	setupBackfillTestDB(t)
+	processed := map[string]int{}
+	withBackfillHandler(t, "test", backfillHandler{
+		concurrency: 1,
+		item: func(run *backfillRun, item BackfillJobItem) (backfillItemResult, error) {
+			processed[item.Symbol]++
+			return backfillItemResult{}, nil
+		},
+	})
+	job, _ := enqueueBackfillJob("test", "tester", backfillParams{}, testBackfillItems("A", "B", "C"))
+
+	// Simulate a crash: job running, A done, B in flight
+	db.Model(&BackfillJob{}).Where("id = ?", job.ID).Update("status", "running")
+	db.Model(&BackfillJobItem{}).Where("job_id = ? AND symbol = ?", job.ID, "A").Update("status", "done")
+	db.Model(&BackfillJobItem{}).Where("job_id = ? AND symbol = ?", job.ID, "B").Update("status", "running")
+
+	if n := resumeBackfillJobs(); n != 1 {
+		t.Fatalf("expected 1 resumed job, got %d", n)
+	}
+	db.First(&job, job.ID)
+	runBackfillJob(job, make(chan struct{}))
+
+	if processed["A"] != 0 || processed["B"] != 1 || processed["C"] != 1 {
+		t.Errorf("expected only unfinished items to run, got %v", processed)
+	}
+	db.First(&job, job.ID)
+	if job.Status != "done" || job.Done != 3 {
+		t.Errorf("unexpected job state after resume: %+v", job)
+	}
+}
+
+func TestBackfillJob_CancelEndpoint(t *testing.T) {
+	setupBackfillTestDB(t)
+	r, token := setupLiveRouter(t)
+	r.GET("/api/admin/jobs/:id", authMiddleware(), adminOnly(), getBackfillJob)
+	r.POST("/api/admin/jobs/:id/cancel", authMiddleware(), adminOnly(), cancelBackfillJobHandler)
+
+	job, _ := enqueueBackfillJob("ohlcv", "tester", backfillParams{Namespace: barNSBot}, testBackfillItems("AAPL", "MSFT"))
+	w := postJSON(r, fmt.Sprintf("/api/admin/jobs/%d/cancel", job.ID), token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("cancel failed: %d %s", w.Code, w.Body.String())
+	}
+	w = getJSON(r, fmt.Sprintf("/api/admin/jobs/%d", job.ID), token)
+	var resp struct {
+		Job    BackfillJob      `json:"job"`
+		Params backfillParams   `json:"params"`
+		Items  []map[string]any `json:"items"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Job.Status != "cancelled" || resp.Params.Namespace != barNSBot || len(resp.Items) != 2 || resp.Items[0]["status"] != "cancelled" {
+		t.Fatalf("unexpected job after cancel: %s", w.Body.String())
+	}
+
+	// Finished jobs can't be cancelled again
+	if w := postJSON(r, fmt.Sprintf("/api/admin/jobs/%d/cancel", job.ID), token, nil); w.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d", w.Code)
+	}
+
+	// A running job stops at the next item
+	started := make(chan struct{})
+	withBackfillHandler(t, "test", backfillHandler{
+		concurrency: 1,
+		item: func(run *backfillRun, item BackfillJobItem) (backfillItemResult, error) {
+			if item.Symbol == "A" {
+				close(started)
+				<-run.cancel
+			}
+			return backfillItemResult{}, nil
+		},
+	})
+	job, _ = enqueueBackfillJob("test", "tester", backfillParams{}, testBackfillItems("A", "B", "C"))
+	cancel := make(chan struct{})
+	backfillMu.Lock()
+	backfillCancels[job.ID] = cancel
+	backfillMu.Unlock()
+	done := make(chan struct{})
+	go func() { runBackfillJob(job, cancel); close(done) }()
+	<-started
+	if !cancelBackfillJob(job.ID) {
+		t.Fatal("expected running job to be cancellable")
+	}
+	<-done
+	var pending int64
+	db.Model(&BackfillJobItem{}).Where("job_id = ? AND status = ?", job.ID, "cancelled").Count(&pending)
+	db.First(&job, job.ID)
+	if job.Status != "cancelled" || pending != 2 {
+		t.Errorf("expected B and C to be cancelled, got status %s / %d cancelled", job.Status, pending)
+	}
+}
+
+func TestBackfillJob_CreateOHLCVJobEndpoint(t *testing.T) {
+	setupBackfillTestDB(t)
+	db.AutoMigrate(&Stock{})
+	r, token := setupLiveRouter(t)
+	r.POST("/api/admin/jobs", authMiddleware(), adminOnly(), createBackfillJob)
+	db.Create(&Stock{Symbol: "AAPL", Name: "Apple", MarketCap: 3})
+	db.Create(&Stock{Symbol: "SAP.DE", Name: "SAP", MarketCap: 2})
+
+	w := postJSON(r, "/api/admin/jobs", token, map[string]interface{}{"kind": "ohlcv", "namespace": "bot", "intervals": []string{"1mo", "1wk"}, "fresh_hours": 12})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create failed: %d %s", w.Code, w.Body.String())
+	}
+	var job BackfillJob
+	json.Unmarshal(w.Body.Bytes(), &job)
+	if job.Kind != "ohlcv" || job.Total != 4 || job.CreatedBy != "admin" {
+		t.Errorf("unexpected job: %+v", job)
+	}
+	var params backfillParams
+	db.First(&job, job.ID)
+	json.Unmarshal([]byte(job.ParamsJSON), &params)
+	if params.Namespace != barNSBot || params.FreshHours != 12 {
+		t.Errorf("unexpected params: %+v", params)
+	}
+
+	if w := postJSON(r, "/api/admin/jobs", token, map[string]interface{}{"kind": "ohlcv", "namespace": "nope"}); w.Code != 400 {
+		t.Errorf("expected 400 for invalid namespace, got %d", w.Code)
+	}
+	if w := postJSON(r, "/api/admin/jobs", token, map[string]interface{}{"kind": "magic"}); w.Code != 400 {
+		t.Errorf("expected 400 for unknown kind, got %d", w.Code)
+	}
+}
+
+func TestBackfillJob_OHLCVItemUsesFreshCache(t *testing.T) {
+	origStore := barStore
+	defer func() { barStore = origStore }()
+	barStore = newBarStore(t.TempDir())
+	barStore.Put(barNSBot, "AAPL", "1mo", makeTestBars(0, 12, 30*86400))
+
+	run := &backfillRun{Params: backfillParams{Namespace: barNSBot, FreshHours: 12}, cancel: make(chan struct{})}
+	res, err := ohlcvBackfillItem(run, BackfillJobItem{Symbol: "AAPL", Interval: "1mo"})
+	if err != nil || res.Source != "cache" || res.Bars != 12 {
+		t.Errorf("expected a cache hit, got %+v %v", res, err)
+	}
+
+	run.Params.Source = "alpaca"
+	if _, err := ohlcvBackfillItem(run, BackfillJobItem{Symbol: "SAP.DE", Interval: "1d"}); !errors.Is(err, errBackfillSkip) {
+		t.Errorf("expected non-US symbols to be skipped for Alpaca, got %v", err)
+	}
+}
+
+func TestBackfillJob_BotBackfill(t *testing.T) {
+	setupBackfillTestDB(t)
+	db.AutoMigrate(&StockPerformance{}, &FlipperBotTrade{}, &FlipperBotPosition{}, &PortfolioPosition{}, &BotStockAllowlist{}, &BotFilterConfig{})
+
+	entry1 := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC).Unix()
+	exit1 := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC).Unix()
+	entry2 := time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC).Unix()
+	exitPrice := 120.0
+	trades, _ := json.Marshal([]TradeData{
+		{EntryDate: entry1, EntryPrice: 100, ExitDate: &exit1, ExitPrice: &exitPrice, ReturnPct: 20},
+		{EntryDate: entry2, EntryPrice: 110, IsOpen: true},
+	})
+	db.Create(&StockPerformance{Symbol: "AAPL", Name: "Apple", TradesJSON: string(trades)})
+	db.Create(&StockPerformance{Symbol: "EMPTY", Name: "Empty"})
+
+	job, err := enqueueBackfillJob("bot_backfill", "tester", backfillParams{Bot: "flipperbot", FromDate: "2021-01-01", SessionID: "bf-test"}, testBackfillItems("AAPL", "EMPTY"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runBackfillJob(job, make(chan struct{}))
+
+	var count int64
+	db.Model(&FlipperBotTrade{}).Where("symbol = ?", "AAPL").Count(&count)
+	if count != 3 {
+		t.Errorf("expected BUY/SELL/BUY, got %d trades", count)
+	}
+	db.Model(&FlipperBotPosition{}).Where("symbol = ?", "AAPL").Count(&count)
+	if count != 1 {
+		t.Errorf("expected one open position, got %d", count)
+	}
+	if tr, pos := botBackfillTotals(job.ID); tr != 3 || pos != 1 {
+		t.Errorf("unexpected totals %d/%d", tr, pos)
+	}
+	var last BotLog
+	db.Where("session_id = ?", "bf-test").Order("id desc").First(&last)
+	if last.Message != "Backfill abgeschlossen: 3 Trades, 1 Positionen erstellt" {
+		t.Errorf("unexpected final log: %q", last.Message)
+	}
+
+	// Running it again creates nothing new
+	job2, _ := enqueueBackfillJob("bot_backfill", "tester", backfillParams{Bot: "flipperbot", FromDate: "2021-01-01", SessionID: "bf-test-2"}, testBackfillItems("AAPL"))
+	runBackfillJob(job2, make(chan struct{}))
+	db.Model(&FlipperBotTrade{}).Where("symbol = ?", "AAPL").Count(&count)
+	if count != 3 {
+		t.Errorf("expected the backfill to be idempotent, got %d trades", count)
+	}
+}
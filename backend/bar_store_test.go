@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func makeTestBars(start int64, n int, step int64) []OHLCV {
+	bars := make([]OHLCV, n)
+	for i := range bars {
+		p := 100 + float64(i)
+		bars[i] = OHLCV{Time: start + int64(i)*step, Open: p, High: p + 1, Low: p - 1, Close: p + 0.5, Volume: float64(1000 + i)}
+	}
+	return bars
+}
+
+func segmentSize(t *testing.T, s *BarStore, ns, symbol, interval string) int64 {
+	t.Helper()
+	fi, err := os.Stat(s.segmentPath(ns, symbol, interval))
+	if err != nil {
+		t.Fatalf("segment missing: %v", err)
+	}
+	return fi.Size()
+}
+
+func TestBarStore_AppendWritesOnlyNewBlock(t *testing.T) {
+	s := newBarStore(t.TempDir())
+	bars := makeTestBars(1000, 100, 60)
+	s.Put(barNSLive, "AAPL", "1m", bars)
+	before := segmentSize(t, s, barNSLive, "AAPL", "1m")
+
+	// Last bar revised, two new bars: one block with three rows
+	fresh := makeTestBars(1000+99*60, 3, 60)
+	fresh[0].Close = 999
+	merged := s.Append(barNSLive, "AAPL", "1m", fresh)
+	if len(merged) != 102 || merged[99].Close != 999 {
+		t.Fatalf("unexpected merge result: len=%d", len(merged))
+	}
+
+	after := segmentSize(t, s, barNSLive, "AAPL", "1m")
+	if after-before != barBlockHeaderSize+3*48 {
+		t.Errorf("expected one appended 3-row block, file grew by %d bytes", after-before)
+	}
+	entry := s.cached(barStoreKey(barNSLive, "AAPL", "1m"))
+	if entry.blocks != 2 || entry.stored != 103 {
+		t.Errorf("expected 2 blocks / 103 stored rows, got %d / %d", entry.blocks, entry.stored)
+	}
+
+	reloaded := newBarStore(s.dir)
+	got, ok := reloaded.Get(barNSLive, "AAPL", "1m")
+	if !ok || !reflect.DeepEqual(got, merged) {
+		t.Fatalf("reloaded series differs from merged series")
+	}
+}
+
+func TestBarStore_PutUnchangedOnlyTouches(t *testing.T) {
+	s := newBarStore(t.TempDir())
+	bars := makeTestBars(1000, 10, 60)
+	s.Put(barNSBot, "MSFT", "1d", bars)
+	before := segmentSize(t, s, barNSBot, "MSFT", "1d")
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(s.segmentPath(barNSBot, "MSFT", "1d"), old, old)
+
+	s.Put(barNSBot, "MSFT", "1d", append([]OHLCV(nil), bars...))
+	if after := segmentSize(t, s, barNSBot, "MSFT", "1d"); after != before {
+		t.Errorf("unchanged put should not write data (%d → %d)", before, after)
+	}
+	fresh := newBarStore(s.dir)
+	if updated, err := fresh.UpdatedAt(barNSBot, "MSFT", "1d"); err != nil || time.Since(updated) > time.Minute {
+		t.Errorf("expected refreshed mtime, got %v (%v)", updated, err)
+	}
+}
+
+func TestBarStore_RangeFromDiskMatchesMemory(t *testing.T) {
+	s := newBarStore(t.TempDir())
+	s.Put(barNSArena, "SPY", "5m", makeTestBars(0, 50, 300))
+	// Several overlapping appends, each superseding the tail of the previous blocks
+	for k := 1; k <= 4; k++ {
+		fresh := makeTestBars(int64(40+k*5)*300, 10, 300)
+		for i := range fresh {
+			fresh[i].Close = float64(k * 1000)
+		}
+		s.Append(barNSArena, "SPY", "5m", fresh)
+	}
+	all, _ := s.Get(barNSArena, "SPY", "5m")
+
+	disk := newBarStore(s.dir)
+	for _, r := range [][2]int64{{0, 1 << 40}, {45 * 300, 52 * 300}, {59 * 300, 59 * 300}, {70 * 300, 80 * 300}, {-10, 5}} {
+		want, _ := s.Range(barNSArena, "SPY", "5m", r[0], r[1])
+		got, err := disk.Range(barNSArena, "SPY", "5m", r[0], r[1])
+		if err != nil {
+			t.Fatalf("range %v: %v", r, err)
+		}
+		if len(got) != len(want) || (len(want) > 0 && !reflect.DeepEqual(got, want)) {
+			t.Errorf("range %v: disk=%d bars, memory=%d bars", r, len(got), len(want))
+		}
+	}
+	if disk.cached(barStoreKey(barNSArena, "SPY", "5m")) != nil {
+		t.Error("range reads must not load the series into memory")
+	}
+	if len(all) != 70 || all[69].Close != 4000 {
+		t.Errorf("unexpected merged series: len=%d", len(all))
+	}
+}
+
+func TestBarStore_CompactsAfterManyBlocks(t *testing.T) {
+	s := newBarStore(t.TempDir())
+	s.Put(barNSLive, "TSLA", "1m", makeTestBars(0, 10, 60))
+	for i := 0; i < barCompactBlocks+5; i++ {
+		s.Append(barNSLive, "TSLA", "1m", makeTestBars(int64(10+i)*60, 1, 60))
+	}
+	entry := s.cached(barStoreKey(barNSLive, "TSLA", "1m"))
+	if entry.blocks > barCompactBlocks {
+		t.Errorf("expected compaction, got %d blocks", entry.blocks)
+	}
+	reloaded, err := readBarSegment(s.segmentPath(barNSLive, "TSLA", "1m"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.blocks != entry.blocks || !reflect.DeepEqual(reloaded.Bars, entry.Bars) {
+		t.Errorf("segment and memory disagree after compaction")
+	}
+}
+
+func TestBarStore_TornBlockIgnoredAndTruncated(t *testing.T) {
+	s := newBarStore(t.TempDir())
+	bars := makeTestBars(0, 20, 60)
+	s.Put(barNSLive, "AMD", "1m", bars)
+	path := s.segmentPath(barNSLive, "AMD", "1m")
+	valid := segmentSize(t, s, barNSLive, "AMD", "1m")
+
+	// Simulate a crash in the middle of an append
+	block := encodeBarBlock(makeTestBars(20*60, 5, 60))
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.Write(block[:len(block)-7])
+	f.Close()
+
+	s2 := newBarStore(s.dir)
+	got, ok := s2.Get(barNSLive, "AMD", "1m")
+	if !ok || !reflect.DeepEqual(got, bars) {
+		t.Fatalf("expected the torn block to be ignored")
+	}
+	s2.Append(barNSLive, "AMD", "1m", makeTestBars(20*60, 2, 60))
+	if size := segmentSize(t, s2, barNSLive, "AMD", "1m"); size != valid+barBlockHeaderSize+2*48 {
+		t.Errorf("expected the torn block to be cut before appending, size=%d", size)
+	}
+	got, _ = newBarStore(s.dir).Get(barNSLive, "AMD", "1m")
+	if len(got) != 22 {
+		t.Errorf("expected 22 bars after recovery, got %d", len(got))
+	}
+}
+
+func TestBarStore_MigratesLegacyFiles(t *testing.T) {
+	dataDir := t.TempDir()
+	legacy := filepath.Join(dataDir, "ohlcv_arena")
+	os.MkdirAll(legacy, 0755)
+	bars := makeTestBars(0, 5, 3600)
+	// Legacy files were not necessarily sorted
+	unsorted := []OHLCV{bars[3], bars[0], bars[1], bars[4], bars[2]}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	json.NewEncoder(gz).Encode(unsorted)
+	gz.Close()
+	os.WriteFile(filepath.Join(legacy, "BRK.B_60m.json.gz"), buf.Bytes(), 0644)
+
+	s := newBarStore(filepath.Join(dataDir, "bars"))
+	if n := s.migrateLegacyDir(barNSArena, legacy); n != 1 {
+		t.Fatalf("expected 1 migrated file, got %d", n)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Error("expected the legacy directory to be removed")
+	}
+	got, ok := s.Get(barNSArena, "BRK.B", "60m")
+	if !ok || !reflect.DeepEqual(got, bars) {
+		t.Errorf("migrated series differs: %v", got)
+	}
+}
+
+func TestBarStore_MigratesLegacyTables(t *testing.T) {
+	origDB := db
+	defer func() { db = origDB }()
+	var err error
+	db, err = gorm.Open(sqlite.Open(fmt.Sprintf("file:memdb_%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test DB: %v", err)
+	}
+	db.AutoMigrate(&OHLCVCache{}, &WeeklyOHLCVCache{})
+	daily, _ := json.Marshal(makeTestBars(0, 3, 86400))
+	weekly, _ := json.Marshal(makeTestBars(0, 2, 7*86400))
+	db.Create(&OHLCVCache{Symbol: "NVDA", Interval: "1d", DataJSON: string(daily), BarCount: 3})
+	db.Create(&WeeklyOHLCVCache{Symbol: "NVDA", DataJSON: string(weekly)})
+
+	s := newBarStore(t.TempDir())
+	s.migrateLegacyTables()
+
+	if bars, ok := s.Get(barNSLive, "NVDA", "1d"); !ok || len(bars) != 3 {
+		t.Errorf("expected 3 daily bars, got %d", len(bars))
+	}
+	if bars, ok := s.Get(barNSLive, "NVDA", "1wk"); !ok || len(bars) != 2 {
+		t.Errorf("expected 2 weekly bars, got %d", len(bars))
+	}
+	if db.Migrator().HasTable(&OHLCVCache{}) || db.Migrator().HasTable(&WeeklyOHLCVCache{}) {
+		t.Error("expected legacy tables to be dropped")
+	}
+}
+
+func TestBarStore_MigrationKeepsFailedSources(t *testing.T) {
+	origDB := db
+	defer func() { db = origDB }()
+	var err error
+	db, err = gorm.Open(sqlite.Open(fmt.Sprintf("file:memdb_%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test DB: %v", err)
+	}
+	db.AutoMigrate(&OHLCVCache{})
+	daily, _ := json.Marshal(makeTestBars(0, 3, 86400))
+	db.Create(&OHLCVCache{Symbol: "NVDA", Interval: "1d", DataJSON: string(daily), BarCount: 3})
+	db.Create(&OHLCVCache{Symbol: "AMD", Interval: "1d", DataJSON: "{broken", BarCount: 3})
+
+	dataDir := t.TempDir()
+	s := newBarStore(filepath.Join(dataDir, "bars"))
+	s.migrateLegacyTables()
+	if _, ok := s.Get(barNSLive, "NVDA", "1d"); !ok {
+		t.Error("expected the valid row to be migrated")
+	}
+	if !db.Migrator().HasTable(&OHLCVCache{}) {
+		t.Error("a table with unmigrated rows must be kept")
+	}
+
+	legacy := filepath.Join(dataDir, "ohlcv")
+	os.MkdirAll(legacy, 0755)
+	broken := filepath.Join(legacy, "AMD_1d.json.gz")
+	os.WriteFile(broken, []byte("not gzip"), 0644)
+	if n := s.migrateLegacyDir(barNSLive, legacy); n != 0 {
+		t.Fatalf("expected nothing migrated, got %d", n)
+	}
+	if _, err := os.Stat(broken); err != nil {
+		t.Error("an unreadable legacy file must not be deleted")
+	}
+}
+
+func TestBarStore_EvictIdle(t *testing.T) {
+	s := newBarStore(t.TempDir())
+	s.Put(barNSLive, "A", "1m", makeTestBars(0, 3, 60))
+	s.Put(barNSArena, "A", "1m", makeTestBars(0, 3, 60))
+
+	// 45 minutes: past the live idle time but not the arena one
+	if n := s.evictIdle(time.Now().Add(45 * time.Minute)); n != 1 {
+		t.Fatalf("expected 1 eviction, got %d", n)
+	}
+	if s.cached(barStoreKey(barNSLive, "A", "1m")) != nil {
+		t.Error("live series should be evicted")
+	}
+	if s.cached(barStoreKey(barNSArena, "A", "1m")) == nil {
+		t.Error("arena series should still be cached")
+	}
+	// Evicted series are lazy-loaded again
+	if bars, ok := s.Get(barNSLive, "A", "1m"); !ok || len(bars) != 3 {
+		t.Error("expected evicted series to reload from disk")
+	}
+}
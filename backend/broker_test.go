@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ============ IBKR Client Portal mock ============
+
+type ibkrMock struct {
+	mu          sync.Mutex
+	placed      []map[string]interface{}
+	replies     int
+	historyBars []map[string]interface{}
+}
+
+func newIBKRMockServer(t *testing.T) (*httptest.Server, *ibkrMock) {
+	t.Helper()
+	m := &ibkrMock{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/api/iserver/secdef/search", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("symbol") {
+		case "SAP":
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"conid": "9999", "description": "NYSE", "sections": []map[string]string{{"secType": "STK", "exchange": "NYSE"}}},
+				{"conid": "14204", "description": "IBIS", "sections": []map[string]string{{"secType": "STK", "exchange": "IBIS;FWB"}}},
+			})
+		case "ASML":
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"conid": "117589399", "description": "AEB"},
+			})
+		case "AAPL":
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"conid": "265598", "description": "NASDAQ"},
+			})
+		default:
+			json.NewEncoder(w).Encode([]map[string]interface{}{})
+		}
+	})
+	mux.HandleFunc("/v1/api/portfolio/U123/summary", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"netliquidation":{"amount":25000.5,"currency":"EUR"},"totalcashvalue":{"amount":5000,"currency":"EUR"},"buyingpower":{"amount":20000,"currency":"EUR"}}`))
+	})
+	mux.HandleFunc("/v1/api/portfolio/U123/positions/0", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"conid":14204,"ticker":"SAP","listingExchange":"IBIS","position":10,"avgPrice":180.5,"mktValue":1850,"unrealizedPnl":45,"currency":"EUR"}]`))
+	})
+	mux.HandleFunc("/v1/api/iserver/account/orders", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"orders":[{"orderId":77,"ticker":"ASML","listingExchange":"AEB","side":"BUY","status":"Filled","totalSize":"2","filledQuantity":"2","avgPrice":"650.1"}]}`))
+	})
+	mux.HandleFunc("/v1/api/iserver/account/U123/orders", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Orders []map[string]interface{} `json:"orders"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		m.mu.Lock()
+		m.placed = append(m.placed, body.Orders...)
+		m.mu.Unlock()
+		// First answer is a precautionary prompt that has to be confirmed
+		w.Write([]byte(`[{"id":"reply-1","message":["Order size exceeds limit. Continue?"]}]`))
+	})
+	mux.HandleFunc("/v1/api/iserver/reply/reply-1", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		m.replies++
+		m.mu.Unlock()
+		w.Write([]byte(`[{"order_id":"1001","order_status":"Submitted"}]`))
+	})
+	mux.HandleFunc("/v1/api/iserver/marketdata/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"conid":14204,"31":"185.20"},{"conid":117589399,"31":"C652.40"}]`))
+	})
+	mux.HandleFunc("/v1/api/iserver/marketdata/history", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		bars := m.historyBars
+		m.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": bars})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, m
+}
+
+func TestIBKRBroker_SymbolSupport(t *testing.T) {
+	b := newIBKRBroker("http://localhost:5000/v1/api", "U123")
+	for _, sym := range []string{"AAPL", "SAP.DE", "ASML.AS"} {
+		if !b.SupportsSymbol(sym) {
+			t.Errorf("expected IBKR to support %s", sym)
+		}
+	}
+	if b.SupportsSymbol("7203.T") {
+		t.Errorf("expected unmapped suffix .T to be unsupported")
+	}
+
+	alpaca := &AlpacaBroker{}
+	if alpaca.SupportsSymbol("SAP.DE") {
+		t.Errorf("Alpaca must not accept .DE symbols")
+	}
+}
+
+func TestIBKRBroker_ResolveConidByExchange(t *testing.T) {
+	srv, _ := newIBKRMockServer(t)
+	b := newIBKRBroker(srv.URL+"/v1/api", "U123")
+
+	conid, err := b.resolveConid("SAP.DE")
+	if err != nil || conid != 14204 {
+		t.Fatalf("expected XETRA conid 14204, got %d (%v)", conid, err)
+	}
+	conid, err = b.resolveConid("AAPL")
+	if err != nil || conid != 265598 {
+		t.Fatalf("expected NASDAQ conid 265598, got %d (%v)", conid, err)
+	}
+	if _, err := b.resolveConid("UNKNOWN.DE"); err == nil {
+		t.Fatalf("expected error for unknown symbol")
+	}
+}
+
+func TestIBKRBroker_AccountPositionsOrders(t *testing.T) {
+	srv, _ := newIBKRMockServer(t)
+	b := newIBKRBroker(srv.URL+"/v1/api", "U123")
+
+	acc, err := b.GetAccount()
+	if err != nil {
+		t.Fatalf("GetAccount: %v", err)
+	}
+	if acc.Equity != 25000.5 || acc.Currency != "EUR" || acc.Cash != 5000 {
+		t.Fatalf("unexpected account: %+v", acc)
+	}
+
+	positions, err := b.GetPositions()
+	if err != nil || len(positions) != 1 {
+		t.Fatalf("GetPositions: %v (%d)", err, len(positions))
+	}
+	if positions[0].Symbol != "SAP.DE" || positions[0].Qty != 10 {
+		t.Fatalf("expected 10x SAP.DE, got %+v", positions[0])
+	}
+
+	orders, err := b.GetOrders()
+	if err != nil || len(orders) != 1 {
+		t.Fatalf("GetOrders: %v (%d)", err, len(orders))
+	}
+	if orders[0].Symbol != "ASML.AS" || orders[0].Side != "buy" || orders[0].FilledAvgPrice != 650.1 {
+		t.Fatalf("unexpected order: %+v", orders[0])
+	}
+}
+
+func TestIBKRBroker_PlaceOrderConfirmsPrompt(t *testing.T) {
+	srv, m := newIBKRMockServer(t)
+	b := newIBKRBroker(srv.URL+"/v1/api", "U123")
+
+	order, err := b.PlaceOrder("SAP.DE", 3, "buy")
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if order.OrderID != "1001" || order.Status != "Submitted" {
+		t.Fatalf("unexpected order result: %+v", order)
+	}
+	if m.replies != 1 {
+		t.Fatalf("expected 1 confirmation reply, got %d", m.replies)
+	}
+	if len(m.placed) != 1 || m.placed[0]["side"] != "BUY" || m.placed[0]["conid"].(float64) != 14204 {
+		t.Fatalf("unexpected order payload: %+v", m.placed)
+	}
+}
+
+func TestIBKRBroker_LatestPrices(t *testing.T) {
+	srv, _ := newIBKRMockServer(t)
+	b := newIBKRBroker(srv.URL+"/v1/api", "U123")
+
+	prices := b.GetLatestPrices([]string{"SAP.DE", "ASML.AS", "NOPE.DE"})
+	if prices["SAP.DE"] != 185.20 {
+		t.Fatalf("expected SAP.DE 185.20, got %v", prices["SAP.DE"])
+	}
+	// "C" prefix marks the prior close — still a usable price
+	if prices["ASML.AS"] != 652.40 {
+		t.Fatalf("expected ASML.AS 652.40, got %v", prices["ASML.AS"])
+	}
+	if _, ok := prices["NOPE.DE"]; ok {
+		t.Fatalf("unknown symbol must not have a price")
+	}
+}
+
+func TestIBKRBroker_StreamBarsOnlyClosed(t *testing.T) {
+	srv, m := newIBKRMockServer(t)
+	b := newIBKRBroker(srv.URL+"/v1/api", "U123")
+
+	now := time.Now().Unix()
+	closedBar := (now/60 - 2) * 60
+	openBar := (now / 60) * 60
+	m.historyBars = []map[string]interface{}{
+		{"t": closedBar * 1000, "o": 1.0, "h": 2.0, "l": 0.5, "c": 1.5, "v": 100.0},
+		{"t": openBar * 1000, "o": 1.5, "h": 1.6, "l": 1.4, "c": 1.55, "v": 10.0},
+	}
+
+	var mu sync.Mutex
+	var got []OHLCV
+	stop := make(chan struct{})
+	defer close(stop)
+	if err := b.StreamBars([]string{"SAP.DE"}, time.Minute, func(symbol string, bar OHLCV) {
+		mu.Lock()
+		got = append(got, bar)
+		mu.Unlock()
+	}, stop); err != nil {
+		t.Fatalf("StreamBars: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(got)
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 1 || got[0].Time != closedBar || got[0].Close != 1.5 {
+		t.Fatalf("expected only the closed bar, got %+v", got)
+	}
+}
+
+func TestLiveBrokerSelection(t *testing.T) {
+	if liveBroker(LiveTradingConfig{}) != nil {
+		t.Fatalf("expected no broker without credentials")
+	}
+	if b := liveBroker(LiveTradingConfig{AlpacaEnabled: true, AlpacaApiKey: "k"}); b == nil || b.Name() != "alpaca" {
+		t.Fatalf("expected alpaca broker")
+	}
+	if liveBroker(LiveTradingConfig{Broker: "ibkr", BrokerURL: "https://localhost:5000/v1/api"}) != nil {
+		t.Fatalf("expected no ibkr broker without account")
+	}
+	b := liveBroker(LiveTradingConfig{Broker: "ibkr", BrokerURL: "https://localhost:5000/v1/api/", BrokerAccountRef: "U123"})
+	if b == nil || b.Name() != "ibkr" {
+		t.Fatalf("expected ibkr broker")
+	}
+	if strings.HasSuffix(b.(*IBKRBroker).baseURL, "/") {
+		t.Fatalf("expected trailing slash to be trimmed")
+	}
+	// One broker per gateway and account keeps the contract id cache across calls
+	if again := liveBroker(LiveTradingConfig{Broker: "ibkr", BrokerURL: "https://localhost:5000/v1/api/", BrokerAccountRef: "U123"}); again != b {
+		t.Fatalf("expected the cached ibkr broker")
+	}
+}
+
+func TestIBKRSnapshotPrice(t *testing.T) {
+	cases := map[interface{}]float64{"185.20": 185.20, "C652.40": 652.40, "H12.5": 12.5, "X1": 0, "": 0, 3.5: 3.5}
+	for in, want := range cases {
+		if got := ibkrSnapshotPrice(in); got != want {
+			t.Errorf("ibkrSnapshotPrice(%v) = %v, want %v", in, got, want)
+		}
+	}
+	if brokerFloat("C652.40") != 0 {
+		t.Error("brokerFloat must not strip IBKR prefixes")
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func dailyBars(start time.Time, closes ...float64) []OHLCV {
+	bars := make([]OHLCV, len(closes))
+	for i, c := range closes {
+		t := start.AddDate(0, 0, i).Add(14*time.Hour + 30*time.Minute)
+		bars[i] = OHLCV{Time: t.Unix(), Open: c, High: c * 1.01, Low: c * 0.99, Close: c, Volume: 100}
+	}
+	return bars
+}
+
+func TestSplitAdjustBars(t *testing.T) {
+	start := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	ex := start.AddDate(0, 0, 3)
+
+	// Cached raw bars + freshly appended post-split bars: jump of 4x at the ex-date
+	mixed := dailyBars(start, 400, 404, 408, 103, 104)
+	out := splitAdjustBars(mixed, "1d", ex, 4, ex.Add(24*time.Hour))
+	if out == nil {
+		t.Fatal("expected mixed series to be adjusted")
+	}
+	if math.Abs(out[2].Close-102) > 1e-9 || out[2].Volume != 400 || out[3].Close != 103 {
+		t.Fatalf("unexpected adjustment: %+v / %+v", out[2], out[3])
+	}
+	if mixed[0].Close != 400 {
+		t.Fatal("input must not be modified")
+	}
+
+	// Provider history fetched after the split: no jump, nothing to do
+	adjusted := dailyBars(start, 100, 101, 102, 103, 104)
+	if splitAdjustBars(adjusted, "1d", ex, 4, time.Now()) != nil {
+		t.Fatal("already adjusted series must not be adjusted again")
+	}
+
+	// Series cached before the ex-date: everything is pre-split
+	old := dailyBars(start, 400, 404, 408)
+	out = splitAdjustBars(old, "1d", ex, 4, ex.Add(-time.Hour))
+	if out == nil || out[2].Close != 102 {
+		t.Fatalf("expected pre-split cache to be adjusted, got %+v", out)
+	}
+	// ... but the same bars refreshed after the ex-date came from the provider adjusted
+	if splitAdjustBars(dailyBars(start, 100, 101, 102), "1d", ex, 4, ex.Add(time.Hour)) != nil {
+		t.Fatal("expected no adjustment for series refreshed after the ex-date")
+	}
+
+	// Reverse split 1:10
+	rev := dailyBars(start, 1, 1.1, 1.2, 12.5)
+	out = splitAdjustBars(rev, "1d", ex, 0.1, time.Now())
+	if out == nil || math.Abs(out[2].Close-12) > 1e-9 {
+		t.Fatalf("expected reverse split adjustment, got %+v", out)
+	}
+
+	// Monthly: the current month bar contains the ex-date and was refreshed after it
+	months := []OHLCV{
+		{Time: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC).Unix(), Open: 380, High: 400, Low: 370, Close: 390},
+		{Time: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC).Unix(), Open: 390, High: 420, Low: 385, Close: 410},
+		{Time: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC).Unix(), Open: 102, High: 106, Low: 100, Close: 105},
+	}
+	out = splitAdjustBars(months, "1mo", ex, 4, ex.Add(48*time.Hour))
+	if out == nil || math.Abs(out[1].Close-102.5) > 1e-9 || out[2].Close != 105 {
+		t.Fatalf("expected months before the refreshed bar to be adjusted, got %+v", out)
+	}
+}
+
+func TestDividendAdjustBars(t *testing.T) {
+	start := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	bars := dailyBars(start, 100, 100, 98, 99)
+	divs := []CorporateAction{
+		{Type: "dividend", ExDate: start.AddDate(0, 0, 2), Amount: 2},
+		{Type: "dividend", ExDate: start.AddDate(0, 0, 10), Amount: 1}, // after the last bar
+	}
+	out := dividendAdjustBars(bars, divs)
+	if math.Abs(out[0].Close-98) > 1e-9 || math.Abs(out[1].Open-98) > 1e-9 {
+		t.Fatalf("expected pre-ex bars scaled by 0.98, got %+v", out[:2])
+	}
+	if out[2].Close != 98 || out[3].Close != 99 || out[0].Volume != 100 {
+		t.Fatalf("post-ex bars and volumes must be unchanged, got %+v", out[2:])
+	}
+	if bars[0].Close != 100 {
+		t.Fatal("input must not be modified")
+	}
+}
+
+func setupCorporateActionTest(t *testing.T) {
+	t.Helper()
+	setupLiveTestDB(t)
+	db.AutoMigrate(&CorporateAction{}, &CorporateActionLog{}, &PortfolioPosition{}, &StockPerformance{},
+		&FlipperBotPosition{}, &LutzPosition{}, &QuantPosition{}, &DitzPosition{}, &TraderPosition{},
+		&PortfolioTransaction{}, &PortfolioTradeHistory{}, &BotDividend{},
+		&FlipperBotTrade{}, &LutzTrade{}, &QuantTrade{}, &DitzTrade{}, &TraderTrade{})
+	origStore := barStore
+	barStore = newBarStore(t.TempDir())
+	t.Cleanup(func() {
+		barStore = origStore
+		corporateActionSeen.Range(func(k, _ interface{}) bool {
+			corporateActionSeen.Delete(k)
+			return true
+		})
+	})
+}
+
+// waitCorporateActionApplied waits for the background apply started by the handlers
+func waitCorporateActionApplied(t *testing.T, id uint) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		var a CorporateAction
+		if db.First(&a, id).Error == nil && a.AppliedAt != nil {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("corporate action %d was not applied", id)
+}
+
+func TestApplyCorporateActionSplit(t *testing.T) {
+	setupCorporateActionTest(t)
+
+	start := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	ex := start.AddDate(0, 0, 3)
+	before := ex.AddDate(0, -1, 0)
+	after := ex.AddDate(0, 0, 1)
+
+	barStore.Put(barNSBot, "SPLT", "1d", dailyBars(start, 400, 404, 408, 103))
+	barStore.Put(barNSArena, "SPLT", "1d", dailyBars(start, 100, 101, 102, 103)) // already adjusted
+
+	qty := 10.0
+	pos := PortfolioPosition{UserID: 7, Symbol: "SPLT", Name: "Split Inc", AvgPrice: 400, Quantity: &qty, CreatedAt: before, UpdatedAt: before}
+	db.Create(&pos)
+	lateQty := 8.0
+	late := PortfolioPosition{UserID: 7, Symbol: "SPLT", Name: "Split Inc", AvgPrice: 100, Quantity: &lateQty, CreatedAt: after, UpdatedAt: after}
+	db.Create(&late)
+	bot := FlipperBotPosition{Symbol: "SPLT", Quantity: 2, AvgPrice: 380, HighestPrice: 410, StopLossPrice: 328, BuyDate: before, CreatedAt: before, UpdatedAt: before}
+	db.Create(&bot)
+	live := LiveTradingPosition{SessionID: 1, Symbol: "SPLT", Direction: "LONG", EntryPrice: 400, StopLoss: 380, TakeProfit: 440, Quantity: 3, EntryTime: before}
+	db.Create(&live)
+	sold := before.AddDate(0, 0, 7)
+	closed := FlipperBotPosition{Symbol: "SPLT", Quantity: 1, AvgPrice: 360, SellPrice: 380, SellDate: &sold, IsClosed: true, BuyDate: before, CreatedAt: before}
+	db.Create(&closed)
+	trade := FlipperBotTrade{Symbol: "SPLT", Action: "BUY", Quantity: 1, Price: 360, SignalDate: before, ExecutedAt: before, CreatedAt: before}
+	db.Create(&trade)
+
+	created := recordCorporateActions([]CorporateAction{{Symbol: "SPLT", Type: "split", ExDate: ex, Ratio: 4, Source: "manual"}})
+	if len(created) != 1 {
+		t.Fatalf("expected 1 new action, got %d", len(created))
+	}
+	if again := recordCorporateActions([]CorporateAction{{Symbol: "SPLT", Type: "split", ExDate: ex, Ratio: 4}}); len(again) != 0 {
+		t.Fatal("duplicate action must not be recorded")
+	}
+
+	applyCorporateAction(created[0])
+	applyCorporateAction(created[0]) // idempotent
+
+	bars, _ := barStore.Get(barNSBot, "SPLT", "1d")
+	if bars[0].Close != 100 || bars[3].Close != 103 {
+		t.Fatalf("expected bot bars to be back-adjusted, got %+v", bars)
+	}
+	arena, _ := barStore.Get(barNSArena, "SPLT", "1d")
+	if arena[0].Close != 100 {
+		t.Fatalf("already adjusted arena bars must stay unchanged, got %+v", arena[0])
+	}
+
+	db.First(&pos, pos.ID)
+	if pos.AvgPrice != 100 || *pos.Quantity != 40 {
+		t.Fatalf("expected portfolio position 40 @ 100, got %v @ %v", *pos.Quantity, pos.AvgPrice)
+	}
+	db.First(&late, late.ID)
+	if late.AvgPrice != 100 || *late.Quantity != 8 {
+		t.Fatalf("position created after the ex-date must stay unchanged, got %v @ %v", *late.Quantity, late.AvgPrice)
+	}
+	db.First(&bot, bot.ID)
+	if bot.Quantity != 8 || bot.AvgPrice != 95 || bot.HighestPrice != 102.5 || bot.StopLossPrice != 82 {
+		t.Fatalf("unexpected bot position: %+v", bot)
+	}
+	db.First(&live, live.ID)
+	if live.Quantity != 12 || live.EntryPrice != 100 || live.StopLoss != 95 || live.TakeProfit != 110 {
+		t.Fatalf("unexpected live position: %+v", live)
+	}
+	db.First(&closed, closed.ID)
+	if closed.Quantity != 4 || closed.AvgPrice != 90 || closed.SellPrice != 95 {
+		t.Fatalf("closed bot position must be restated, got %+v", closed)
+	}
+	db.First(&trade, trade.ID)
+	if trade.Quantity != 4 || trade.Price != 90 {
+		t.Fatalf("bot trade must be restated, got %+v", trade)
+	}
+
+	var entries []CorporateActionLog
+	db.Where("action_id = ?", created[0].ID).Find(&entries)
+	targets := map[string]int{}
+	for _, e := range entries {
+		targets[e.Target]++
+	}
+	if targets["bars"] != 1 || targets["portfolio"] != 1 || targets["flipperbot"] != 2 || targets["flipperbot_trade"] != 1 || targets["live"] != 1 || len(entries) != 6 {
+		t.Fatalf("unexpected audit log: %v", targets)
+	}
+
+	var action CorporateAction
+	db.First(&action, created[0].ID)
+	if action.AppliedAt == nil {
+		t.Fatal("expected applied_at to be set")
+	}
+}
+
+func TestSplitRebuildsBotStopFromAdjustedBars(t *testing.T) {
+	setupCorporateActionTest(t)
+
+	buy := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	ex := buy.AddDate(0, 0, 3)
+	// 1:4 reverse split, the last bar is already quoted in post-split terms
+	barStore.Put(barNSBot, "RVRS", "1d", dailyBars(buy, 100, 105, 102, 412))
+
+	// The stop-loss check ran after the ex-date and took the post-split quote as new high
+	moved := FlipperBotPosition{Symbol: "RVRS", Quantity: 8, AvgPrice: 100, HighestPrice: 412, StopLossPrice: 329.6, StopLossType: "trailing", BuyDate: buy, CreatedAt: buy}
+	db.Create(&moved)
+	fixed := LutzPosition{Symbol: "RVRS", Quantity: 4, AvgPrice: 100, HighestPrice: 412, StopLossPrice: 90, StopLossType: "fixed", BuyDate: buy, CreatedAt: buy}
+	db.Create(&fixed)
+	// Bought before the cached bars start and saved after the ex-date: high and stop stay
+	early := buy.AddDate(0, -2, 0)
+	uncovered := QuantPosition{Symbol: "RVRS", Quantity: 4, AvgPrice: 100, HighestPrice: 412, StopLossPrice: 329.6, BuyDate: early, CreatedAt: early}
+	db.Create(&uncovered)
+
+	applyCorporateAction(CorporateAction{ID: 1, Symbol: "RVRS", Type: "split", ExDate: ex, Ratio: 0.25})
+
+	db.First(&moved, moved.ID)
+	if moved.Quantity != 2 || moved.AvgPrice != 400 || moved.HighestPrice != 420 || !near(moved.StopLossPrice, 336) {
+		t.Fatalf("expected high and trailing stop from the adjusted bars, got %+v", moved)
+	}
+	db.First(&fixed, fixed.ID)
+	if fixed.AvgPrice != 400 || fixed.HighestPrice != 420 || fixed.StopLossPrice != 360 {
+		t.Fatalf("expected the fixed stop to move with the entry price, got %+v", fixed)
+	}
+	db.First(&uncovered, uncovered.ID)
+	if uncovered.Quantity != 1 || uncovered.AvgPrice != 400 || uncovered.HighestPrice != 412 || uncovered.StopLossPrice != 329.6 {
+		t.Fatalf("expected high and stop to stay without bars, got %+v", uncovered)
+	}
+}
+
+func TestCorporateActionEndpoints(t *testing.T) {
+	setupCorporateActionTest(t)
+	r, token := setupLiveRouter(t)
+	r.GET("/api/admin/corporate-actions", authMiddleware(), adminOnly(), getCorporateActions)
+	r.POST("/api/admin/corporate-actions", authMiddleware(), adminOnly(), createCorporateAction)
+	r.GET("/api/admin/corporate-actions/log", authMiddleware(), adminOnly(), getCorporateActionLog)
+
+	bad := []map[string]interface{}{
+		{"symbol": "ABC", "type": "split", "ex_date": "2024-06-06", "numerator": 2, "denominator": 2},
+		{"symbol": "ABC", "type": "dividend", "ex_date": "2024-06-06"},
+		{"symbol": "ABC", "type": "merger", "ex_date": "2024-06-06"},
+		{"symbol": "", "type": "split", "ex_date": "2024-06-06", "numerator": 2, "denominator": 1},
+		{"symbol": "ABC", "type": "split", "ex_date": "06.06.2024", "numerator": 2, "denominator": 1},
+	}
+	for _, body := range bad {
+		if w := postJSON(r, "/api/admin/corporate-actions", token, body); w.Code != 400 {
+			t.Fatalf("expected 400 for %v, got %d", body, w.Code)
+		}
+	}
+
+	w := postJSON(r, "/api/admin/corporate-actions", token, map[string]interface{}{"symbol": "abc", "type": "dividend", "ex_date": "2024-06-06", "amount": 0.5})
+	if w.Code != 201 {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created CorporateAction
+	json.Unmarshal(w.Body.Bytes(), &created)
+	waitCorporateActionApplied(t, created.ID)
+	w = postJSON(r, "/api/admin/corporate-actions", token, map[string]interface{}{"symbol": "ABC", "type": "dividend", "ex_date": "2024-06-06", "amount": 0.5})
+	if w.Code != 409 {
+		t.Fatalf("expected 409 for duplicate, got %d", w.Code)
+	}
+
+	w = getJSON(r, "/api/admin/corporate-actions?symbol=ABC", token)
+	var actions []CorporateAction
+	json.Unmarshal(w.Body.Bytes(), &actions)
+	if len(actions) != 1 || actions[0].Symbol != "ABC" || actions[0].Amount != 0.5 || actions[0].Source != "manual" {
+		t.Fatalf("unexpected actions: %s", w.Body.String())
+	}
+	if w := getJSON(r, "/api/admin/corporate-actions/log", token); w.Code != 200 {
+		t.Fatalf("expected 200 for log, got %d", w.Code)
+	}
+}
+
+func TestRecordYahooCorporateActions(t *testing.T) {
+	setupCorporateActionTest(t)
+
+	var resp YahooChartResponse
+	raw := `{"chart":{"result":[{"timestamp":[1],"events":{
+		"dividends":{"1717594200":{"amount":0.25,"date":1717594200}},
+		"splits":{"1598880600":{"date":1598880600,"numerator":4,"denominator":1,"splitRatio":"4:1"}}}}]}}`
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+		t.Fatal(err)
+	}
+	recordYahooCorporateActions("AAPL", resp.Chart.Result[0].Events)
+
+	divs := corporateActionsFor("AAPL", "dividend")
+	splits := corporateActionsFor("AAPL", "split")
+	if len(divs) != 1 || divs[0].Amount != 0.25 || !divs[0].ExDate.Equal(time.Date(2024, 6, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected dividends: %+v", divs)
+	}
+	if len(splits) != 1 || splits[0].Ratio != 4 || splits[0].Source != "yahoo" || !splits[0].ExDate.Equal(time.Date(2020, 8, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected splits: %+v", splits)
+	}
+
+	// Both events predate the go-live date: recorded, but only applied on request
+	r, token := setupLiveRouter(t)
+	r.POST("/api/admin/corporate-actions/:id/apply", authMiddleware(), adminOnly(), applyCorporateActionHandler)
+	time.Sleep(50 * time.Millisecond)
+	if a := corporateActionsFor("AAPL", "split")[0]; a.AppliedAt != nil {
+		t.Fatal("historical split must not be applied automatically")
+	}
+	if w := postJSON(r, "/api/admin/corporate-actions/"+itoa(splits[0].ID)+"/apply", token, nil); w.Code != 202 {
+		t.Fatalf("expected 202, got %d", w.Code)
+	}
+	waitCorporateActionApplied(t, splits[0].ID)
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// tradingDayBars returns n clean daily bars on consecutive NYSE trading days starting at from
+func tradingDayBars(from time.Time, n int) []OHLCV {
+	bars := make([]OHLCV, 0, n)
+	// Bars sit at the session open of each day, whatever time of day from carries
+	for d := from.UTC().Truncate(24 * time.Hour); len(bars) < n; d = d.AddDate(0, 0, 1) {
+		if !calendarNYSE.IsTradingDay(d) {
+			continue
+		}
+		i := float64(len(bars))
+		c := 100 + 2*math.Sin(i/3) + i*0.1
+		bars = append(bars, OHLCV{Time: d.Add(14*time.Hour + 30*time.Minute).Unix(), Open: c - 0.2, High: c + 1, Low: c - 1, Close: c, Volume: 10000 + 100*i})
+	}
+	return bars
+}
+
+func issuesOfType(issues []DataQualityIssue, typ string) []DataQualityIssue {
+	var out []DataQualityIssue
+	for _, i := range issues {
+		if i.Type == typ {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+var dqNow = time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+func TestValidateBars_CleanSeries(t *testing.T) {
+	bars := tradingDayBars(time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), 80)
+	if issues := validateBars("AAPL", "1d", bars, bars, 6, dqNow); len(issues) != 0 {
+		t.Fatalf("expected no issues, got %+v", issues)
+	}
+}
+
+func TestValidateBars_DetectsIssues(t *testing.T) {
+	bars := tradingDayBars(time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), 80)
+	bars[10].High = bars[10].Close - 0.5 // high below close
+	bars[20].Close *= 1.6                // spike on no volume
+	bars[20].High = bars[20].Close + 1
+	bars[20].Volume = 0
+	// Three missing trading days and one single missing day
+	stored := append(append(append([]OHLCV(nil), bars[:40]...), bars[43:60]...), bars[61:]...)
+
+	issues := validateBars("AAPL", "1d", stored, stored, 6, dqNow)
+	if got := issuesOfType(issues, "ohlc"); len(got) != 1 || got[0].Time != bars[10].Time || got[0].Severity != "error" {
+		t.Errorf("expected one ohlc error, got %+v", got)
+	}
+	if got := issuesOfType(issues, "spike"); len(got) == 0 || got[0].Time != bars[20].Time {
+		t.Errorf("expected a spike at bar 20, got %+v", got)
+	}
+	gaps := issuesOfType(issues, "gap")
+	if len(gaps) != 2 || gaps[0].Severity != "error" || gaps[0].Time != bars[43].Time || gaps[1].Severity != "warning" {
+		t.Errorf("expected a 3-day gap error and a 1-day gap warning, got %+v", gaps)
+	}
+
+	// Errors in old history are only warnings
+	for _, i := range validateBars("AAPL", "1d", stored, stored, 6, dqNow.AddDate(3, 0, 0)) {
+		if i.Severity == "error" {
+			t.Errorf("expected old issues to be downgraded, got %+v", i)
+		}
+	}
+}
+
+func TestValidateBars_OrderingAndDuplicates(t *testing.T) {
+	bars := tradingDayBars(time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), 30)
+	conflicting := bars[5]
+	conflicting.Close += 3
+	incoming := []OHLCV{bars[0], bars[2], bars[1], bars[3], bars[3], bars[5], conflicting}
+	stored := normalizeBars(incoming)
+
+	issues := validateBars("MSFT", "60m", incoming, stored, 6, dqNow)
+	if got := issuesOfType(issues, "out_of_order"); len(got) != 1 || got[0].Severity != "warning" {
+		t.Errorf("expected one out-of-order warning, got %+v", got)
+	}
+	dups := issuesOfType(issues, "duplicate")
+	if len(dups) != 2 || dups[0].Severity != "warning" || dups[1].Severity != "error" {
+		t.Errorf("expected an identical and a conflicting duplicate, got %+v", dups)
+	}
+	if len(issuesOfType(issues, "gap")) != 0 {
+		t.Error("intraday series must not be checked for gaps")
+	}
+}
+
+func TestValidateBars_WeeklyAndMonthlyGaps(t *testing.T) {
+	var weekly, monthly []OHLCV
+	monday := time.Date(2026, 1, 5, 14, 30, 0, 0, time.UTC)
+	for i := 0; i < 30; i++ {
+		if i == 12 || i == 13 {
+			continue
+		}
+		weekly = append(weekly, OHLCV{Time: monday.AddDate(0, 0, 7*i).Unix(), Open: 100, High: 101, Low: 99, Close: 100, Volume: 1000})
+	}
+	for m := 1; m <= 9; m++ {
+		if m == 4 {
+			continue
+		}
+		monthly = append(monthly, OHLCV{Time: time.Date(2026, time.Month(m), 1, 4, 0, 0, 0, time.UTC).Unix(), Open: 100, High: 101, Low: 99, Close: 100, Volume: 1000})
+	}
+	if gaps := issuesOfType(validateBars("SAP.DE", "1wk", weekly, weekly, 6, dqNow), "gap"); len(gaps) != 1 || gaps[0].Severity != "error" {
+		t.Errorf("expected one weekly gap, got %+v", gaps)
+	}
+	if gaps := issuesOfType(validateBars("SAP.DE", "1mo", monthly, monthly, 6, dqNow), "gap"); len(gaps) != 1 || gaps[0].Time != monthly[3].Time {
+		t.Errorf("expected one monthly gap, got %+v", gaps)
+	}
+}
+
+func TestDataQuality_QuarantineAndResolve(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&DataQualityReport{}, &GlobalSetting{})
+	r, token := setupLiveRouter(t)
+	r.GET("/api/admin/data-quality", authMiddleware(), adminOnly(), getDataQualityReports)
+	r.GET("/api/admin/data-quality/:id", authMiddleware(), adminOnly(), getDataQualityReport)
+	r.POST("/api/admin/data-quality/:id/resolve", authMiddleware(), adminOnly(), resolveDataQualityReport)
+
+	s := newBarStore("")
+	s.onWrite = observeBarQuality
+	bars := tradingDayBars(time.Now().AddDate(0, -4, 0), 60)
+	bad := append([]OHLCV(nil), bars...)
+	bad[30].High = bad[30].Close * 0.9
+	s.Put(barNSBot, "DQTEST", "1d", bad)
+
+	if quarantined, reason := isSymbolQuarantined("DQTEST", botQuarantineIntervals...); !quarantined || reason != "bot/1d: 1 Fehler" {
+		t.Fatalf("expected quarantine, got %v %q", quarantined, reason)
+	}
+	if quarantined, _ := isSymbolQuarantined("DQTEST", "1mo"); quarantined {
+		t.Error("interval filter must be respected")
+	}
+
+	w := getJSON(r, "/api/admin/data-quality?quarantined=true", token)
+	var list struct {
+		Reports []struct {
+			ID     uint   `json:"id"`
+			Symbol string `json:"symbol"`
+		} `json:"reports"`
+		Quarantined int `json:"quarantined"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || list.Quarantined != 1 || len(list.Reports) != 1 || list.Reports[0].Symbol != "DQTEST" {
+		t.Fatalf("unexpected list: %d %s", w.Code, w.Body.String())
+	}
+	id := list.Reports[0].ID
+
+	w = getJSON(r, fmt.Sprintf("/api/admin/data-quality/%d", id), token)
+	var detail struct {
+		Issues []DataQualityIssue `json:"issues"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &detail)
+	if len(detail.Issues) != 1 || detail.Issues[0].Type != "ohlc" {
+		t.Fatalf("unexpected issues: %s", w.Body.String())
+	}
+
+	// Admin accepts the bar: released, and re-fetching the same data keeps it released
+	w = postJSON(r, fmt.Sprintf("/api/admin/data-quality/%d/resolve", id), token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("resolve failed: %d %s", w.Code, w.Body.String())
+	}
+	s.Put(barNSBot, "DQTEST", "1d", bad)
+	var report DataQualityReport
+	db.First(&report, id)
+	if report.Quarantined || report.ResolvedBy != "admin" {
+		t.Fatalf("expected report resolved by admin, got %+v", report)
+	}
+
+	// A new error quarantines again
+	worse := append([]OHLCV(nil), bad...)
+	worse[40].Low = worse[40].Close * 1.2
+	s.Put(barNSBot, "DQTEST", "1d", worse)
+	if quarantined, _ := isSymbolQuarantined("DQTEST"); !quarantined {
+		t.Fatal("expected quarantine after a new error")
+	}
+
+	// Clean data from the provider lifts the quarantine automatically
+	s.Put(barNSBot, "DQTEST", "1d", bars)
+	db.First(&report, id)
+	if report.Quarantined || report.ResolvedBy != "auto" || report.Errors != 0 {
+		t.Fatalf("expected automatic release, got %+v", report)
+	}
+}
+
+func TestDataQuality_AppendValidatesTail(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&DataQualityReport{}, &GlobalSetting{})
+	t.Cleanup(func() { dataQualityStates.Delete(barStoreKey(barNSLive, "DQTAIL", "1d")) })
+
+	s := newBarStore("")
+	s.onWrite = observeBarQuality
+	bars := tradingDayBars(time.Now().AddDate(-2, 0, 0), 450)
+	bars[10].High = bars[10].Close * 0.9
+	s.Put(barNSLive, "DQTAIL", "1d", bars[:400])
+
+	// The early error lies outside the revalidated window but stays reported
+	s.Append(barNSLive, "DQTAIL", "1d", bars[400:420])
+	if quarantined, _ := isSeriesQuarantined(barNSLive, "DQTAIL", "1d"); !quarantined {
+		t.Fatal("an append must keep the findings before the revalidated tail")
+	}
+	if quarantined, _ := isSeriesQuarantined(barNSBot, "DQTAIL", "1d"); quarantined {
+		t.Error("namespace filter must be respected")
+	}
+
+	tail := append([]OHLCV(nil), bars[420:]...)
+	tail[5].Low = tail[5].Close * 1.2
+	s.Append(barNSLive, "DQTAIL", "1d", tail)
+	var report DataQualityReport
+	db.Where("symbol = ?", "DQTAIL").First(&report)
+	var issues []DataQualityIssue
+	json.Unmarshal([]byte(report.IssuesJSON), &issues)
+	if got := issuesOfType(issues, "ohlc"); len(got) != 2 || got[0].Time != bars[10].Time || got[1].Time != tail[5].Time {
+		t.Fatalf("expected the old and the new OHLC error, got %+v", issues)
+	}
+}
+
+func TestDataQuality_CleanSeriesHasNoReport(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&DataQualityReport{}, &GlobalSetting{})
+
+	s := newBarStore("")
+	s.onWrite = observeBarQuality
+	bars := tradingDayBars(time.Now().AddDate(0, -4, 0), 60)
+	s.Put(barNSLive, "DQCLEAN", "1d", bars[:50])
+	s.Append(barNSLive, "DQCLEAN", "1d", bars[50:])
+
+	var count int64
+	db.Model(&DataQualityReport{}).Where("symbol = ?", "DQCLEAN").Count(&count)
+	if count != 0 {
+		t.Errorf("expected no report for a clean series, got %d", count)
+	}
+}
+
+func TestDeleteDataQualityReport_DropsSeries(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&DataQualityReport{}, &GlobalSetting{})
+	r, token := setupLiveRouter(t)
+	r.DELETE("/api/admin/data-quality/:id", authMiddleware(), adminOnly(), deleteDataQualityReport)
+
+	origStore := barStore
+	defer func() { barStore = origStore }()
+	barStore = newBarStore(t.TempDir())
+	barStore.onWrite = observeBarQuality
+
+	bars := tradingDayBars(time.Now().AddDate(0, -4, 0), 60)
+	bars = append(bars[:20], bars[25:]...)
+	barStore.Put(barNSLive, "DQGAP", "1d", bars)
+	var report DataQualityReport
+	if db.Where("symbol = ?", "DQGAP").First(&report).Error != nil || !report.Quarantined {
+		t.Fatalf("expected a quarantined report, got %+v", report)
+	}
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/api/admin/data-quality/%d", report.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("delete failed: %d %s", w.Code, w.Body.String())
+	}
+	if _, ok := barStore.Get(barNSLive, "DQGAP", "1d"); ok {
+		t.Error("expected the cached series to be dropped")
+	}
+	if quarantined, _ := isSymbolQuarantined("DQGAP"); quarantined {
+		t.Error("expected the quarantine to be gone with the report")
+	}
+}
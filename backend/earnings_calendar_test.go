@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func iptr(v int) *int { return &v }
+
+// weeklyTestBars builds flat weekly bars (open = close = price) starting at start
+func weeklyTestBars(start time.Time, prices ...float64) []OHLCV {
+	bars := make([]OHLCV, len(prices))
+	for i, p := range prices {
+		bars[i] = OHLCV{Time: start.AddDate(0, 0, 7*i).Unix(), Open: p, High: p * 1.01, Low: p * 0.99, Close: p, Volume: 1000}
+	}
+	return bars
+}
+
+func TestEarningsGuard_BlocksEntry(t *testing.T) {
+	dates := []time.Time{day(2024, 1, 25), day(2024, 4, 25)}
+	g := EarningsGuard{EarningsBlockDays: iptr(5)}
+
+	if blocked, reason := g.blocksEntry(dates, time.Date(2024, 1, 22, 15, 30, 0, 0, time.UTC)); !blocked || reason != "Earnings am 25.01.2024 (in 3 Tagen)" {
+		t.Fatalf("expected blackout, got %v %q", blocked, reason)
+	}
+	if blocked, _ := g.blocksEntry(dates, day(2024, 1, 25)); !blocked {
+		t.Error("release day itself must be blocked")
+	}
+	if blocked, _ := g.blocksEntry(dates, day(2024, 1, 19)); blocked {
+		t.Error("6 days before the release is outside a 5 day blackout")
+	}
+	if blocked, _ := g.blocksEntry(dates, day(2024, 1, 26)); blocked {
+		t.Error("the day after the release must not be blocked")
+	}
+	if blocked, _ := (EarningsGuard{}).blocksEntry(dates, day(2024, 1, 24)); blocked {
+		t.Error("inactive guard must not block")
+	}
+}
+
+func TestEarningsGuard_Validate(t *testing.T) {
+	cases := []struct {
+		g  EarningsGuard
+		ok bool
+	}{
+		{EarningsGuard{}, true},
+		{EarningsGuard{EarningsBlockDays: iptr(3), EarningsExitDays: iptr(1), EarningsExitMode: "exit"}, true},
+		{EarningsGuard{EarningsExitDays: iptr(1), EarningsExitMode: "tighten", EarningsTightenPct: fptr(3)}, true},
+		{EarningsGuard{EarningsExitDays: iptr(1), EarningsExitMode: "tighten"}, false},
+		{EarningsGuard{EarningsBlockDays: iptr(-1)}, false},
+		{EarningsGuard{EarningsExitMode: "hedge"}, false},
+	}
+	for i, c := range cases {
+		if err := c.g.validate(); (err == nil) != c.ok {
+			t.Errorf("case %d: validate() = %v, want ok=%v", i, err, c.ok)
+		}
+	}
+}
+
+func TestEarningsGuard_TightenedStop(t *testing.T) {
+	g := EarningsGuard{EarningsExitMode: "tighten", EarningsTightenPct: fptr(5)}
+	if stop, moved := g.tightenedStop("LONG", 100, 80); !moved || stop != 95 {
+		t.Errorf("long: expected 95, got %.2f %v", stop, moved)
+	}
+	if stop, moved := g.tightenedStop("LONG", 100, 97); moved || stop != 97 {
+		t.Errorf("long: a tighter stop must be kept, got %.2f %v", stop, moved)
+	}
+	if stop, moved := g.tightenedStop("SHORT", 100, 0); !moved || stop != 105 {
+		t.Errorf("short without stop: expected 105, got %.2f %v", stop, moved)
+	}
+}
+
+func TestEarningsGuard_ApplyToTrades(t *testing.T) {
+	start := day(2024, 1, 1) // Monday
+	bars := weeklyTestBars(start, 100, 102, 104, 106, 108, 90, 92, 94, 96, 98)
+	release := day(2024, 2, 7) // Wednesday of week 6 (bar index 5, gap down to 90)
+	trades := []ArenaBacktestTrade{
+		{Direction: "LONG", EntryPrice: 100, EntryTime: bars[0].Time, ExitPrice: 98, ExitTime: bars[9].Time, ReturnPct: -2, ExitReason: "SIGNAL"},
+		{Direction: "LONG", EntryPrice: 108, EntryTime: bars[4].Time, IsOpen: true},
+	}
+
+	// Block: the second entry is 9 days before the release
+	kept, blocked, exits := EarningsGuard{EarningsBlockDays: iptr(10)}.applyToTrades([]time.Time{release}, bars, trades)
+	if blocked != 1 || exits != 0 || len(kept) != 1 || kept[0].EntryPrice != 100 {
+		t.Fatalf("block: got %d blocked, %d exits, %+v", blocked, exits, kept)
+	}
+
+	// Exit 3 days before: window starts Sunday 04.02 → bar of 29.01 (index 4), exit at its open
+	kept, _, exits = EarningsGuard{EarningsExitDays: iptr(3), EarningsExitMode: "exit"}.applyToTrades([]time.Time{release}, bars, trades)
+	if exits != 2 {
+		t.Fatalf("exit: expected 2 exits, got %d", exits)
+	}
+	if kept[0].ExitPrice != 108 || kept[0].ExitTime != bars[4].Time || kept[0].ExitReason != "EARNINGS" || kept[0].ReturnPct != 8 {
+		t.Errorf("exit: unexpected first trade %+v", kept[0])
+	}
+	if kept[1].IsOpen || kept[1].ExitPrice != 108 || kept[1].ReturnPct != 0 {
+		t.Errorf("exit: open trade entered in the window must be closed at entry, got %+v", kept[1])
+	}
+
+	// Tighten to 5%: stop 102.6 from the bar of 29.01, the release gap opens below it
+	kept, _, exits = EarningsGuard{EarningsExitDays: iptr(3), EarningsExitMode: "tighten", EarningsTightenPct: fptr(5)}.applyToTrades([]time.Time{release}, bars, trades[:1])
+	if exits != 1 || kept[0].ExitPrice != 90 || kept[0].ExitTime != bars[5].Time || kept[0].ExitReason != "EARNINGS_SL" {
+		t.Fatalf("tighten: expected gap exit at 90, got %d %+v", exits, kept[0])
+	}
+
+	// A release after the trade closed changes nothing
+	kept, blocked, exits = EarningsGuard{EarningsBlockDays: iptr(3), EarningsExitDays: iptr(3), EarningsExitMode: "exit"}.applyToTrades([]time.Time{day(2024, 6, 1)}, bars, trades[:1])
+	if blocked != 0 || exits != 0 || kept[0].ExitReason != "SIGNAL" {
+		t.Errorf("later release must not touch the trade: %+v", kept[0])
+	}
+}
+
+func TestEarningsMarkers(t *testing.T) {
+	weekly := weeklyTestBars(day(2024, 1, 1), 1, 2, 3, 4)
+	markers := earningsMarkers([]time.Time{day(2023, 6, 1), day(2024, 1, 10), day(2024, 1, 26), day(2024, 3, 1)}, weekly)
+	if len(markers) != 2 || markers[0].Time != weekly[1].Time || markers[1].Time != weekly[3].Time || markers[0].Text != "E" {
+		t.Fatalf("weekly: unexpected markers %+v", markers)
+	}
+
+	// Intraday: first bar of the release day, not the last bar of the day before
+	var hourly []OHLCV
+	for d := 0; d < 3; d++ {
+		for h := 14; h < 21; h++ {
+			hourly = append(hourly, OHLCV{Time: time.Date(2024, 1, 8+d, h, 30, 0, 0, time.UTC).Unix(), Close: 1})
+		}
+	}
+	markers = earningsMarkers([]time.Time{day(2024, 1, 9)}, hourly)
+	if len(markers) != 1 || markers[0].Time != time.Date(2024, 1, 9, 14, 30, 0, 0, time.UTC).Unix() {
+		t.Fatalf("intraday: unexpected markers %+v", markers)
+	}
+}
+
+func TestRecordEarningsEvent_MovedDateAndImportPrecedence(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&EarningsEvent{})
+
+	recordEarningsEvent(EarningsEvent{Symbol: "aapl", Date: time.Date(2024, 4, 25, 20, 0, 0, 0, time.UTC), Source: "yahoo"})
+	recordEarningsEvent(EarningsEvent{Symbol: "AAPL", Date: day(2024, 5, 2), Source: "yahoo"})
+	var events []EarningsEvent
+	db.Find(&events)
+	if len(events) != 1 || !events[0].Date.Equal(day(2024, 5, 2)) {
+		t.Fatalf("moved provider date must replace the estimate: %+v", events)
+	}
+
+	recordEarningsEvent(EarningsEvent{Symbol: "AAPL", Date: day(2024, 5, 1), Timing: "amc", Source: "csv"})
+	recordEarningsEvent(EarningsEvent{Symbol: "AAPL", Date: day(2024, 5, 3), Source: "yahoo"})
+	events = nil
+	db.Find(&events)
+	if len(events) != 1 || !events[0].Date.Equal(day(2024, 5, 1)) || events[0].Source != "csv" || events[0].Timing != "amc" {
+		t.Fatalf("imported date must win over the provider: %+v", events)
+	}
+
+	recordEarningsEvent(EarningsEvent{Symbol: "AAPL", Date: day(2024, 8, 1), Source: "yahoo"})
+	if dates := upcomingEarnings("AAPL", day(2024, 5, 2)); len(dates) != 1 || !dates[0].Equal(day(2024, 8, 1)) {
+		t.Errorf("expected next release 01.08.2024, got %v", dates)
+	}
+}
+
+func TestImportEarningsEvents(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&EarningsEvent{})
+	r, token := setupLiveRouter(t)
+	r.POST("/api/admin/earnings/import", authMiddleware(), adminOnly(), importEarningsEvents)
+	r.GET("/api/earnings", authMiddleware(), getEarningsEvents)
+
+	csv := "Symbol;Date;Timing\nMSFT;2024-01-30;amc\nMSFT;25.04.2024;AMC\nNVDA;kein Datum;\n"
+	req, _ := http.NewRequest("POST", "/api/admin/earnings/import", bytes.NewBufferString(csv))
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var resp struct {
+		Imported int      `json:"imported"`
+		Errors   []string `json:"errors"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Imported != 2 || len(resp.Errors) != 1 || !strings.HasPrefix(resp.Errors[0], "Zeile 4") {
+		t.Fatalf("unexpected import result: %d %s", w.Code, w.Body.String())
+	}
+
+	w = getJSON(r, "/api/earnings?symbol=msft&from=2024-03-01", token)
+	var events []EarningsEvent
+	json.Unmarshal(w.Body.Bytes(), &events)
+	if len(events) != 1 || !events[0].Date.Equal(day(2024, 4, 25)) || events[0].Timing != "amc" || events[0].Source != "csv" {
+		t.Fatalf("unexpected events: %s", w.Body.String())
+	}
+
+	req, _ = http.NewRequest("POST", "/api/admin/earnings/import", bytes.NewBufferString("ticker,when\nX,2024-01-01\n"))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("missing columns must be rejected, got %d", w.Code)
+	}
+}
+
+func TestBotEarningsGuard_StopAndEntry(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&EarningsEvent{}, &BotFilterConfig{})
+	now := time.Now()
+	recordEarningsEvent(EarningsEvent{Symbol: "NFLX", Date: now.AddDate(0, 0, 2), Source: "csv"})
+	db.Create(&BotFilterConfig{BotName: "lutz", Enabled: true, EarningsGuard: EarningsGuard{
+		EarningsBlockDays: iptr(7), EarningsExitDays: iptr(3), EarningsExitMode: "tighten", EarningsTightenPct: fptr(4),
+	}})
+
+	guard := botEarningsGuard("lutz")
+	if sl, exit := guard.botStop("NFLX", now, 20); exit || sl != 4 {
+		t.Errorf("expected stop capped at 4%%, got %.1f %v", sl, exit)
+	}
+	if sl, exit := guard.botStop("NFLX", now, 3); exit || sl != 3 {
+		t.Errorf("a tighter stop must be kept, got %.1f %v", sl, exit)
+	}
+	if sl, _ := guard.botStop("AAPL", now, 20); sl != 20 {
+		t.Errorf("symbol without release must keep its stop, got %.1f", sl)
+	}
+	if blocked, reason := checkBotFilterConfig("lutz", "NFLX", now, 50, 2, 5, 0); !blocked || !strings.Contains(reason, "Earnings am") {
+		t.Errorf("expected entry blackout, got %v %q", blocked, reason)
+	}
+	if blocked, _ := checkBotFilterConfig("lutz", "NFLX", time.Time{}, 50, 2, 5, 0); blocked {
+		t.Error("zero time (backfill pre-check) must skip the earnings check")
+	}
+	if blocked, _ := checkBotEarnings("lutz", "NFLX", now.AddDate(0, 0, -30)); blocked {
+		t.Error("historical entry a month before the release must pass")
+	}
+
+	db.Model(&BotFilterConfig{}).Where("bot_name = ?", "lutz").Update("earnings_exit_mode", "exit")
+	if _, exit := botEarningsGuard("lutz").botStop("NFLX", now, 20); !exit {
+		t.Error("exit mode must close the position")
+	}
+}
+
+func TestProcessLiveSymbol_EarningsGuard(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&EarningsEvent{})
+	marketOpen := true
+	testMarketOpenOverride = &marketOpen
+	t.Cleanup(func() { testMarketOpenOverride = nil })
+	go livePositionWriter()
+
+	session := LiveTradingSession{UserID: 1, Strategy: "regression_scalping", Interval: "5m", TradeAmount: 500, Currency: "USD",
+		IsActive: true, StartedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	db.Create(&session)
+	strategy := &RegressionScalpingStrategy{}
+	strategy.defaults()
+	data := generateOHLCV(300, 100, time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC).Unix(), 300)
+	if len(strategy.Analyze(data)) == 0 {
+		t.Skip("no signals generated with test data")
+	}
+
+	recordEarningsEvent(EarningsEvent{Symbol: "EARN", Date: time.Now().AddDate(0, 0, 1), Source: "csv"})
+	config := LiveTradingConfig{UserID: 1, EarningsGuard: EarningsGuard{EarningsBlockDays: iptr(2)}}
+
+	// Blackout: no entries for the symbol with a release tomorrow, entries elsewhere
+	processLiveSymbolWithData(session, "EARN", strategy, data, config)
+	processLiveSymbolWithData(session, "FREE", strategy, data, config)
+	time.Sleep(50 * time.Millisecond)
+	var earn, free int64
+	db.Model(&LiveTradingPosition{}).Where("session_id = ? AND symbol = ?", session.ID, "EARN").Count(&earn)
+	db.Model(&LiveTradingPosition{}).Where("session_id = ? AND symbol = ?", session.ID, "FREE").Count(&free)
+	if earn != 0 || free == 0 {
+		t.Fatalf("expected entries only outside the blackout, got EARN=%d FREE=%d", earn, free)
+	}
+
+	// Exit mode closes an open position ahead of the release (session started after all signals)
+	later := LiveTradingSession{UserID: 1, Strategy: "regression_scalping", Interval: "5m", TradeAmount: 500, Currency: "USD",
+		IsActive: true, StartedAt: time.Now()}
+	db.Create(&later)
+	pos := LiveTradingPosition{SessionID: later.ID, Symbol: "EARN", Direction: "LONG", EntryPrice: 100, EntryPriceUSD: 100,
+		EntryTime: time.Now(), Quantity: 1, InvestedAmount: 100, NativeCurrency: "USD", SignalIndex: -1}
+	db.Create(&pos)
+	liveOpenPosGuard.Store(openPosGuardKey(later.ID, 0, "EARN"), true)
+	config.EarningsGuard = EarningsGuard{EarningsExitDays: iptr(2), EarningsExitMode: "exit"}
+	recent := generateOHLCV(300, 100, time.Now().Add(-301*5*time.Minute).Unix(), 300)
+	processLiveSymbolWithData(later, "EARN", strategy, recent, config)
+	time.Sleep(50 * time.Millisecond)
+	db.First(&pos, pos.ID)
+	if !pos.IsClosed || pos.CloseReason != "EARNINGS" {
+		t.Fatalf("expected position closed before earnings, got closed=%v reason=%q", pos.IsClosed, pos.CloseReason)
+	}
+}
+
+func TestCheckBotStopLoss_EarningsExitNeedsFreshQuote(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&EarningsEvent{}, &BotFilterConfig{}, &BXtrenderConfig{}, &FlipperBotPosition{}, &FlipperBotTrade{}, &PortfolioPosition{})
+	marketOpen := false
+	testMarketOpenOverride = &marketOpen
+	t.Cleanup(func() {
+		testMarketOpenOverride = nil
+		latestPriceCache.Delete("ERNX")
+		latestPriceAt.Delete("ERNX")
+	})
+	now := time.Now()
+	recordEarningsEvent(EarningsEvent{Symbol: "ERNX", Date: now.AddDate(0, 0, 1), Source: "csv"})
+	db.Create(&BXtrenderConfig{Mode: "defensive", TslEnabled: true, TslPercent: 20})
+	db.Create(&BotFilterConfig{BotName: "flipper", Enabled: true, EarningsGuard: EarningsGuard{EarningsExitDays: iptr(2), EarningsExitMode: "exit"}})
+	pos := FlipperBotPosition{Symbol: "ERNX", AvgPrice: 100, HighestPrice: 100, Quantity: 1, BuyDate: now.AddDate(0, -1, 0)}
+	db.Create(&pos)
+	latestPriceCache.Store("ERNX", 101.0)
+
+	// After hours the cached price is no exit price
+	latestPriceAt.Store("ERNX", now)
+	checkFlipperStopLoss()
+	db.First(&pos, pos.ID)
+	if pos.IsClosed {
+		t.Fatal("earnings exit must wait for market hours")
+	}
+
+	// An old quote during market hours is not enough either
+	marketOpen = true
+	latestPriceAt.Store("ERNX", now.Add(-2*time.Hour))
+	checkFlipperStopLoss()
+	db.First(&pos, pos.ID)
+	if pos.IsClosed {
+		t.Fatal("earnings exit must not close at a stale quote")
+	}
+
+	latestPriceAt.Store("ERNX", now)
+	checkFlipperStopLoss()
+	db.First(&pos, pos.ID)
+	if !pos.IsClosed || pos.SellPrice != 101 {
+		t.Errorf("expected earnings exit at the live quote, got closed=%v price=%.2f", pos.IsClosed, pos.SellPrice)
+	}
+}
+
+func TestCheckBotStopLoss_TightenKeepsConfiguredStop(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&EarningsEvent{}, &BotFilterConfig{}, &BXtrenderConfig{}, &FlipperBotPosition{}, &FlipperBotTrade{}, &PortfolioPosition{})
+	t.Cleanup(func() { latestPriceCache.Delete("TGHT") })
+	now := time.Now()
+	recordEarningsEvent(EarningsEvent{Symbol: "TGHT", Date: now.AddDate(0, 0, 1), Source: "csv"})
+	db.Create(&BXtrenderConfig{Mode: "defensive", TslEnabled: true, TslPercent: 20})
+	db.Create(&BotFilterConfig{BotName: "flipper", Enabled: true, EarningsGuard: EarningsGuard{
+		EarningsExitDays: iptr(2), EarningsExitMode: "tighten", EarningsTightenPct: fptr(5),
+	}})
+	pos := FlipperBotPosition{Symbol: "TGHT", AvgPrice: 100, HighestPrice: 100, Quantity: 1, BuyDate: now.AddDate(0, -1, 0)}
+	db.Create(&pos)
+
+	latestPriceCache.Store("TGHT", 97.0)
+	checkFlipperStopLoss()
+	db.First(&pos, pos.ID)
+	if pos.IsClosed || pos.StopLossPrice != 80 {
+		t.Fatalf("expected the configured 20%% stop to stay stored, got closed=%v stop=%.2f", pos.IsClosed, pos.StopLossPrice)
+	}
+
+	latestPriceCache.Store("TGHT", 94.0)
+	checkFlipperStopLoss()
+	db.First(&pos, pos.ID)
+	if !pos.IsClosed {
+		t.Error("expected the stop tightened to 5%% to trigger ahead of the release")
+	}
+}
+
+func TestProcessLiveSymbol_EarningsRestoresStop(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&EarningsEvent{})
+	marketOpen := true
+	testMarketOpenOverride = &marketOpen
+	t.Cleanup(func() { testMarketOpenOverride = nil })
+	go livePositionWriter()
+
+	session := LiveTradingSession{UserID: 1, Strategy: "regression_scalping", Interval: "5m", TradeAmount: 500, Currency: "USD",
+		IsActive: true, StartedAt: time.Now()}
+	db.Create(&session)
+	strategy := &RegressionScalpingStrategy{}
+	strategy.defaults()
+	moved := time.Now().Add(-time.Hour)
+	pos := LiveTradingPosition{SessionID: session.ID, Symbol: "RSTR", Direction: "LONG", EntryPrice: 100, EntryPriceUSD: 100,
+		EntryTime: time.Now().Add(-48 * time.Hour), StopLoss: 95, OriginalStop: fptr(1), StopMovedAt: &moved,
+		Quantity: 1, InvestedAmount: 100, NativeCurrency: "USD", SignalIndex: -1}
+	db.Create(&pos)
+	liveOpenPosGuard.Store(openPosGuardKey(session.ID, 0, "RSTR"), true)
+	t.Cleanup(func() { liveOpenPosGuard.Delete(openPosGuardKey(session.ID, 0, "RSTR")) })
+
+	// No release ahead any more: the stop tightened before the release is put back
+	config := LiveTradingConfig{UserID: 1, EarningsGuard: EarningsGuard{EarningsExitDays: iptr(2), EarningsExitMode: "tighten", EarningsTightenPct: fptr(5)}}
+	recent := generateOHLCV(300, 100, time.Now().Add(-301*5*time.Minute).Unix(), 300)
+	processLiveSymbolWithData(session, "RSTR", strategy, recent, config)
+	time.Sleep(50 * time.Millisecond)
+	db.First(&pos, pos.ID)
+	if pos.IsClosed || pos.StopLoss != 1 || pos.OriginalStop != nil {
+		t.Errorf("expected the original stop restored, got closed=%v stop=%.2f original=%v", pos.IsClosed, pos.StopLoss, pos.OriginalStop)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestEasterSunday(t *testing.T) {
+	cases := map[int]time.Time{
+		2024: day(2024, time.March, 31),
+		2025: day(2025, time.April, 20),
+		2026: day(2026, time.April, 5),
+	}
+	for year, want := range cases {
+		if got := easterSunday(year); !got.Equal(want) {
+			t.Errorf("Easter %d: expected %s, got %s", year, want.Format("2006-01-02"), got.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestNYSEHolidays(t *testing.T) {
+	closed := []time.Time{
+		day(2026, time.January, 1),   // New Year
+		day(2026, time.January, 19),  // MLK
+		day(2026, time.April, 3),     // Good Friday
+		day(2026, time.May, 25),      // Memorial Day
+		day(2026, time.June, 19),     // Juneteenth
+		day(2026, time.July, 3),      // Independence Day observed (Jul 4 = Saturday)
+		day(2026, time.November, 26), // Thanksgiving
+		day(2026, time.December, 25), // Christmas
+		day(2025, time.January, 9),   // special closure
+	}
+	for _, d := range closed {
+		if calendarNYSE.IsTradingDay(d) {
+			t.Errorf("expected NYSE closed on %s", d.Format("2006-01-02"))
+		}
+	}
+	// New Year on Saturday (2022) is not observed on Friday Dec 31, 2021
+	if !calendarNYSE.IsTradingDay(day(2021, time.December, 31)) {
+		t.Errorf("expected NYSE open on 2021-12-31")
+	}
+	if !calendarNYSE.IsTradingDay(day(2026, time.April, 6)) {
+		t.Errorf("Easter Monday is a regular NYSE trading day")
+	}
+}
+
+func TestNYSEEarlyClose(t *testing.T) {
+	ny := calendarNYSE.location()
+	// Day after Thanksgiving 2026 closes at 13:00 ET
+	blackFriday := time.Date(2026, time.November, 27, 12, 0, 0, 0, ny)
+	closeAt, ok := calendarNYSE.SessionClose(blackFriday)
+	if !ok || closeAt.Hour() != 13 || closeAt.Minute() != 0 {
+		t.Fatalf("expected 13:00 close, got %v (%v)", closeAt, ok)
+	}
+	if calendarNYSE.IsOpen(time.Date(2026, time.November, 27, 14, 0, 0, 0, ny), false) {
+		t.Fatalf("expected market closed at 14:00 on early-close day")
+	}
+	// No post-market on early-close days
+	if calendarNYSE.IsOpen(time.Date(2026, time.November, 27, 15, 0, 0, 0, ny), true) {
+		t.Fatalf("expected no extended session after early close")
+	}
+	// Christmas Eve 2026 (Thursday) is an early close
+	if c, _ := calendarNYSE.SessionClose(time.Date(2026, time.December, 24, 10, 0, 0, 0, ny)); c.Hour() != 13 {
+		t.Fatalf("expected Christmas Eve early close, got %v", c)
+	}
+}
+
+func TestNYSEExtendedHours(t *testing.T) {
+	ny := calendarNYSE.location()
+	pre := time.Date(2026, time.March, 10, 7, 0, 0, 0, ny)
+	if calendarNYSE.IsOpen(pre, false) || !calendarNYSE.IsOpen(pre, true) || !calendarNYSE.IsExtendedHours(pre) {
+		t.Fatalf("07:00 ET should be pre-market only")
+	}
+	post := time.Date(2026, time.March, 10, 19, 30, 0, 0, ny)
+	if !calendarNYSE.IsExtendedHours(post) {
+		t.Fatalf("19:30 ET should be post-market")
+	}
+	if calendarNYSE.IsOpen(time.Date(2026, time.March, 10, 21, 0, 0, 0, ny), true) {
+		t.Fatalf("21:00 ET should be closed even with extended hours")
+	}
+	regular := time.Date(2026, time.March, 10, 10, 0, 0, 0, ny)
+	if !calendarNYSE.IsOpen(regular, false) || calendarNYSE.IsExtendedHours(regular) {
+		t.Fatalf("10:00 ET should be regular hours")
+	}
+}
+
+func TestXETRACalendar(t *testing.T) {
+	berlin := calendarXETRA.location()
+	if calendarXETRA.IsTradingDay(day(2026, time.April, 6)) {
+		t.Errorf("expected XETRA closed on Easter Monday")
+	}
+	if !calendarXETRA.IsTradingDay(day(2026, time.October, 5)) {
+		t.Errorf("expected XETRA open on a regular Monday")
+	}
+	if !calendarXETRA.IsOpen(time.Date(2026, time.October, 5, 17, 0, 0, 0, berlin), false) {
+		t.Errorf("expected XETRA open at 17:00 Berlin")
+	}
+	if calendarXETRA.IsOpen(time.Date(2026, time.October, 5, 7, 30, 0, 0, berlin), true) {
+		t.Errorf("XETRA has no pre-market session")
+	}
+}
+
+func TestLSESubstituteHolidays(t *testing.T) {
+	// Christmas 2021 fell on Saturday → Mon 27 + Tue 28 closed
+	for _, d := range []time.Time{day(2021, time.December, 27), day(2021, time.December, 28)} {
+		if calendarLSE.IsTradingDay(d) {
+			t.Errorf("expected LSE closed on %s", d.Format("2006-01-02"))
+		}
+	}
+	if c, ok := calendarLSE.SessionClose(time.Date(2026, time.December, 24, 10, 0, 0, 0, calendarLSE.location())); !ok || c.Hour() != 12 || c.Minute() != 30 {
+		t.Errorf("expected 12:30 close on Christmas Eve, got %v", c)
+	}
+}
+
+func TestExchangeForSymbol(t *testing.T) {
+	cases := map[string]string{
+		"AAPL":    "NYSE",
+		"BRK.B":   "NYSE",
+		"SAP.DE":  "XETRA",
+		"ASML.AS": "EURONEXT",
+		"VOD.L":   "LSE",
+		"NESN.SW": "SIX",
+	}
+	for sym, want := range cases {
+		if got := exchangeForSymbol(sym).Code; got != want {
+			t.Errorf("%s: expected %s, got %s", sym, want, got)
+		}
+	}
+}
+
+func TestAdjustToTradingDaySkipsHolidays(t *testing.T) {
+	// Good Friday 2026 → Thursday
+	if got := adjustToTradingDay(day(2026, time.April, 3)); !got.Equal(day(2026, time.April, 2)) {
+		t.Fatalf("expected 2026-04-02, got %s", got.Format("2006-01-02"))
+	}
+	// Sunday after Good Friday → Thursday
+	if got := adjustToTradingDay(day(2026, time.April, 5)); !got.Equal(day(2026, time.April, 2)) {
+		t.Fatalf("expected 2026-04-02, got %s", got.Format("2006-01-02"))
+	}
+}
+
+func TestShouldRunDailyUpdate(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	// Sunday 00:00 Berlin → reference day Saturday → skip
+	if shouldRunDailyUpdate(time.Date(2026, time.March, 15, 0, 0, 0, 0, berlin)) {
+		t.Errorf("expected skip after Saturday")
+	}
+	// Saturday 00:00 Berlin → reference day Friday → run
+	if !shouldRunDailyUpdate(time.Date(2026, time.March, 14, 0, 0, 0, 0, berlin)) {
+		t.Errorf("expected run after Friday")
+	}
+	// First of month always runs (2026-03-01 is a Sunday)
+	if !shouldRunDailyUpdate(time.Date(2026, time.March, 1, 0, 0, 0, 0, berlin)) {
+		t.Errorf("expected run on first of month")
+	}
+}
+
+func TestBarAggregatorFlushesAtEarlyClose(t *testing.T) {
+	ny := calendarNYSE.location()
+	// 12:00–13:00 bucket of a 2h aggregator on an early-close day — the close (13:00) ends it
+	start := time.Date(2025, time.November, 28, 12, 0, 0, 0, ny)
+	var got []OHLCV
+	agg := newBarAggregator(2*time.Hour, func(c OHLCV) { got = append(got, c) })
+	agg.calendar = calendarNYSE
+	agg.AddBar(OHLCV{Time: start.Unix(), Open: 1, High: 2, Low: 0.5, Close: 1.5, Volume: 10})
+	agg.FlushIfExpired(3 * time.Second)
+	if len(got) != 1 {
+		t.Fatalf("expected candle flushed at early close, got %d", len(got))
+	}
+}
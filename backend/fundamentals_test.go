@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fptr(v float64) *float64 { return &v }
+
+// testQuarter builds a quarterly report published 45 days after the period end
+func testQuarter(symbol string, end time.Time, revenue, netIncome, eps float64) StockFundamentalsPeriod {
+	return StockFundamentalsPeriod{
+		Symbol: symbol, PeriodType: "quarterly", PeriodEnd: end, AvailableAt: end.Add(fundamentalsQuarterlyLag),
+		Revenue: fptr(revenue), GrossProfit: fptr(revenue * 0.4), OperatingIncome: fptr(revenue * 0.2),
+		NetIncome: fptr(netIncome), DilutedEPS: fptr(eps), TotalDebt: fptr(50), Equity: fptr(100),
+	}
+}
+
+func TestParseFundamentalsTimeseries(t *testing.T) {
+	body := `{"timeseries":{"result":[
+		{"meta":{"symbol":["AAPL"],"type":["quarterlyTotalRevenue"]},"timestamp":[1],"quarterlyTotalRevenue":[
+			null,
+			{"asOfDate":"2024-03-31","periodType":"3M","reportedValue":{"raw":90.5,"fmt":"90.5B"}},
+			{"asOfDate":"2024-06-30","periodType":"3M","reportedValue":{"raw":85.7,"fmt":"85.7B"}}]},
+		{"meta":{"symbol":["AAPL"],"type":["quarterlyNetIncome"]},"quarterlyNetIncome":[
+			{"asOfDate":"2024-06-30","periodType":"3M","reportedValue":{"raw":21.4}}]},
+		{"meta":{"symbol":["AAPL"],"type":["annualDilutedEPS"]},"annualDilutedEPS":[
+			{"asOfDate":"2023-09-30","periodType":"12M","reportedValue":{"raw":6.13}}]},
+		{"meta":{"symbol":["AAPL"],"type":["annualUnknownThing"]},"annualUnknownThing":[
+			{"asOfDate":"2023-09-30","reportedValue":{"raw":1}}]}
+	]}}`
+	periods, err := parseFundamentalsTimeseries("AAPL", []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(periods) != 3 {
+		t.Fatalf("expected 3 periods, got %+v", periods)
+	}
+	annual, q2 := periods[0], periods[2]
+	if annual.PeriodType != "annual" || annual.DilutedEPS == nil || *annual.DilutedEPS != 6.13 || !annual.AvailableAt.Equal(day(2023, 9, 30).Add(fundamentalsAnnualLag)) {
+		t.Errorf("unexpected annual period: %+v", annual)
+	}
+	if q2.PeriodType != "quarterly" || *q2.Revenue != 85.7 || q2.NetIncome == nil || *q2.NetIncome != 21.4 || q2.DilutedEPS != nil {
+		t.Errorf("unexpected quarterly period: %+v", q2)
+	}
+}
+
+func TestFundamentalsSeries_PointInTime(t *testing.T) {
+	fetched := day(2025, 1, 10)
+	s := &fundamentalsSeries{
+		Snapshot: &StockFundamentals{Symbol: "ACME", Sector: "Technology", Country: "United States",
+			TrailingPE: fptr(30), ProfitMargin: fptr(25), NetIncome: fptr(100), FetchedAt: fetched},
+	}
+	// 2023: losses; 2024: profitable and growing
+	ends := []time.Time{day(2023, 3, 31), day(2023, 6, 30), day(2023, 9, 30), day(2023, 12, 31), day(2024, 3, 31), day(2024, 6, 30), day(2024, 9, 30)}
+	for _, end := range ends {
+		if end.Year() == 2023 {
+			s.Periods = append(s.Periods, testQuarter("ACME", end, 100, -5, -0.5))
+		} else {
+			s.Periods = append(s.Periods, testQuarter("ACME", end, 120, 10, 1))
+		}
+	}
+
+	// Mid 2024: the Q1/2024 report is public, Q2 isn't yet
+	p := s.at(day(2024, 7, 1), 50)
+	if p.Sector != "Technology" || p.PeriodEnd == nil || !p.PeriodEnd.Equal(day(2024, 3, 31)) {
+		t.Fatalf("expected TTM ending Q1/2024, got %+v", p)
+	}
+	// TTM net income: -5*3 + 10 = -5 → not profitable, no P/E
+	if p.NetIncome == nil || *p.NetIncome != -5 || p.PE != nil {
+		t.Errorf("expected a TTM loss without P/E, got %+v", p)
+	}
+	if p.GrossMargin == nil || *p.GrossMargin < 39.9 || *p.GrossMargin > 40.1 {
+		t.Errorf("unexpected gross margin %v", p.GrossMargin)
+	}
+	if p.RevenueGrowth != nil {
+		t.Errorf("growth needs eight quarters or two annual reports, got %v", *p.RevenueGrowth)
+	}
+	if p.DebtToEquity == nil || *p.DebtToEquity != 0.5 {
+		t.Errorf("unexpected debt/equity %v", p.DebtToEquity)
+	}
+
+	// End of 2024: four profitable quarters are public
+	p = s.at(day(2024, 12, 1), 80)
+	if p.NetIncome == nil || *p.NetIncome != 25 || p.PE == nil || *p.PE != 80.0/2.5 {
+		t.Errorf("expected TTM profit with P/E 32, got %+v", p)
+	}
+
+	// Before any report: nothing but the static fields
+	if p := s.at(day(2020, 1, 1), 10); p.NetIncome != nil || p.Sector != "Technology" || p.PeriodEnd != nil {
+		t.Errorf("expected only static fields, got %+v", p)
+	}
+
+	// From the snapshot fetch on, the snapshot is used
+	if p := s.at(fetched.Add(time.Hour), 0); p.PE == nil || *p.PE != 30 || p.PeriodEnd != nil {
+		t.Errorf("expected the snapshot values, got %+v", p)
+	}
+}
+
+func TestFundamentalsSeries_AnnualGrowthAndDividends(t *testing.T) {
+	s := &fundamentalsSeries{
+		Snapshot: &StockFundamentals{Symbol: "DIV", FetchedAt: day(2026, 1, 1)},
+		Periods: []StockFundamentalsPeriod{
+			{Symbol: "DIV", PeriodType: "annual", PeriodEnd: day(2022, 12, 31), AvailableAt: day(2023, 3, 1), Revenue: fptr(100), NetIncome: fptr(10)},
+			{Symbol: "DIV", PeriodType: "annual", PeriodEnd: day(2023, 12, 31), AvailableAt: day(2024, 3, 1), Revenue: fptr(110), NetIncome: fptr(12)},
+		},
+		Dividends: []CorporateAction{
+			{Symbol: "DIV", Type: "dividend", ExDate: day(2023, 11, 1), Amount: 1},
+			{Symbol: "DIV", Type: "dividend", ExDate: day(2024, 5, 1), Amount: 1},
+			{Symbol: "DIV", Type: "dividend", ExDate: day(2024, 11, 1), Amount: 1.5},
+		},
+	}
+	p := s.at(day(2024, 6, 1), 50)
+	if p.RevenueGrowth == nil || *p.RevenueGrowth < 9.99 || *p.RevenueGrowth > 10.01 {
+		t.Errorf("expected 10%% growth, got %v", p.RevenueGrowth)
+	}
+	if p.ProfitMargin == nil || *p.ProfitMargin < 10.9 || *p.ProfitMargin > 11 {
+		t.Errorf("unexpected profit margin %v", p.ProfitMargin)
+	}
+	// Dividends in the year before June 2024: 1 + 1 on a price of 50
+	if p.DividendYield == nil || *p.DividendYield != 4 {
+		t.Errorf("expected 4%% dividend yield, got %v", p.DividendYield)
+	}
+}
+
+func TestFundamentalFilter_Check(t *testing.T) {
+	point := FundamentalsPoint{Sector: "Financial Services", Country: "United States", PE: fptr(12), NetIncome: fptr(5)}
+
+	f := FundamentalFilter{ExcludeSectors: "Financial Services, Real Estate", ProfitableOnly: true}
+	if blocked, reason := f.check(point); !blocked || !strings.Contains(reason, "ausgeschlossen") {
+		t.Errorf("expected the sector to be excluded, got %v %q", blocked, reason)
+	}
+	point.Sector = "Technology"
+	if blocked, reason := f.check(point); blocked {
+		t.Errorf("expected a pass, got %q", reason)
+	}
+	point.NetIncome = fptr(-1)
+	if blocked, reason := f.check(point); !blocked || reason != "nicht profitabel" {
+		t.Errorf("expected a loss to be blocked, got %v %q", blocked, reason)
+	}
+
+	// Unknown values pass unless fundamentals are required
+	f = FundamentalFilter{MaxPE: fptr(20), MinDividendYield: fptr(2)}
+	if blocked, _ := f.check(FundamentalsPoint{PE: fptr(15)}); blocked {
+		t.Error("unknown dividend yield should pass")
+	}
+	f.RequireFundamentals = true
+	if blocked, reason := f.check(FundamentalsPoint{PE: fptr(25)}); !blocked || !strings.Contains(reason, "KGV 25.0 > Max 20.0") || !strings.Contains(reason, "Dividendenrendite unbekannt") {
+		t.Errorf("unexpected reason %q", reason)
+	}
+
+	if (FundamentalFilter{RequireFundamentals: true}).Active() {
+		t.Error("require alone is not a filter")
+	}
+	if !(FundamentalFilter{Countries: "Germany"}).Active() {
+		t.Error("country whitelist is a filter")
+	}
+}
+
+func TestFundamentalFilter_FilterTradesUsesEntryTime(t *testing.T) {
+	s := &fundamentalsSeries{Snapshot: &StockFundamentals{Symbol: "ACME", Sector: "Technology", NetIncome: fptr(50), FetchedAt: day(2025, 1, 10)}}
+	for _, end := range []time.Time{day(2023, 3, 31), day(2023, 6, 30), day(2023, 9, 30), day(2023, 12, 31)} {
+		s.Periods = append(s.Periods, testQuarter("ACME", end, 100, -5, -0.5))
+	}
+	trades := []ArenaBacktestTrade{
+		{EntryTime: day(2024, 3, 1).Unix(), EntryPrice: 10, ReturnPct: 5},  // after the loss year is public
+		{EntryTime: day(2025, 2, 1).Unix(), EntryPrice: 12, ReturnPct: 10}, // snapshot: profitable
+	}
+	kept, dropped := FundamentalFilter{ProfitableOnly: true}.filterTrades(s, trades)
+	if dropped != 1 || len(kept) != 1 || kept[0].ReturnPct != 10 {
+		t.Errorf("expected only the 2025 entry to remain, got %+v", kept)
+	}
+	if kept, dropped := (FundamentalFilter{}).filterTrades(s, trades); dropped != 0 || len(kept) != 2 {
+		t.Error("an inactive filter must keep all trades")
+	}
+}
+
+func TestBotFilterConfig_Fundamentals(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&BotFilterConfig{}, &StockFundamentals{}, &StockFundamentalsPeriod{}, &CorporateAction{})
+	r, token := setupLiveRouter(t)
+	r.PUT("/api/admin/bot-filter-config", authMiddleware(), adminOnly(), updateBotFilterConfig)
+
+	db.Create(&StockFundamentals{Symbol: "JPM", Sector: "Financial Services", NetIncome: fptr(50), FetchedAt: time.Now().Add(-time.Hour)})
+	db.Create(&StockFundamentals{Symbol: "AAPL", Sector: "Technology", NetIncome: fptr(90), FetchedAt: time.Now().Add(-time.Hour)})
+
+	body := map[string]interface{}{"bot_name": "flipper", "exclude_sectors": "Financial Services", "profitable_only": true}
+	if w := putJSON(r, "/api/admin/bot-filter-config", token, body); w.Code != http.StatusOK {
+		t.Fatalf("create failed: %d %s", w.Code, w.Body.String())
+	}
+	if blocked, reason := checkBotFilterConfig("flipper", "JPM", time.Now(), 60, 2, 5, 1e11); !blocked || !strings.Contains(reason, "Sektor") {
+		t.Errorf("expected JPM to be blocked, got %v %q", blocked, reason)
+	}
+	if blocked, _ := checkBotFilterConfig("flipper", "AAPL", time.Now(), 60, 2, 5, 1e11); blocked {
+		t.Error("expected AAPL to pass")
+	}
+	// Backfills skip fundamentals in the per-stock check and test each trade instead
+	if blocked, _ := checkBotFilterConfig("flipper", "JPM", time.Time{}, 60, 2, 5, 1e11); blocked {
+		t.Error("a zero time must skip the fundamentals")
+	}
+	if blocked, _ := checkBotFundamentals("flipper", "JPM", time.Now().AddDate(-3, 0, 0), 100); !blocked {
+		t.Error("the sector is static and applies to past trades too")
+	}
+
+	// Updating writes the fundamental columns, including clearing them
+	body = map[string]interface{}{"bot_name": "flipper", "max_pe": 25.5}
+	w := putJSON(r, "/api/admin/bot-filter-config", token, body)
+	var cfg BotFilterConfig
+	json.Unmarshal(w.Body.Bytes(), &cfg)
+	if w.Code != http.StatusOK || cfg.MaxPE == nil || *cfg.MaxPE != 25.5 || cfg.ExcludeSectors != "" || cfg.ProfitableOnly || !cfg.Enabled {
+		t.Fatalf("unexpected config after update: %s", w.Body.String())
+	}
+}
+
+func TestSaveStockFundamentals_CarriesEarningsDates(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&StockFundamentals{}, &StockFundamentalsPeriod{})
+
+	next := day(2025, 1, 30)
+	saveStockFundamentals(StockFundamentals{Symbol: "MSFT", NextEarningsDate: &next, FetchedAt: day(2025, 1, 5)}, nil)
+
+	// After the report: the old "next" becomes "last" and pins the publication date of Q4
+	newNext := day(2025, 4, 29)
+	q4 := testQuarter("MSFT", day(2024, 12, 31), 100, 10, 1)
+	saveStockFundamentals(StockFundamentals{Symbol: "MSFT", NextEarningsDate: &newNext, FetchedAt: day(2025, 2, 5)}, []StockFundamentalsPeriod{q4})
+
+	var snap StockFundamentals
+	db.Where("symbol = ?", "MSFT").First(&snap)
+	if snap.LastEarningsDate == nil || !snap.LastEarningsDate.Equal(next) || !snap.NextEarningsDate.Equal(newNext) {
+		t.Errorf("unexpected earnings dates: %+v", snap)
+	}
+	var stored StockFundamentalsPeriod
+	db.Where("symbol = ? AND period_type = ?", "MSFT", "quarterly").First(&stored)
+	if !stored.AvailableAt.Equal(next) {
+		t.Errorf("expected Q4 to be available on the earnings date, got %v", stored.AvailableAt)
+	}
+
+	// Refetching keeps a single row per period
+	saveStockFundamentals(StockFundamentals{Symbol: "MSFT", NextEarningsDate: &newNext, FetchedAt: day(2025, 2, 6)}, []StockFundamentalsPeriod{q4})
+	var count int64
+	db.Model(&StockFundamentalsPeriod{}).Where("symbol = ?", "MSFT").Count(&count)
+	if count != 1 {
+		t.Errorf("expected 1 period row, got %d", count)
+	}
+}
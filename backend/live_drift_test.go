@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// ============ Live Drift Report Tests ============
+
+func TestDriftSlippagePct(t *testing.T) {
+	// Buying 1% above the backtest price is adverse
+	if got := driftSlippagePct(100, 101, true); math.Abs(got-1) > 1e-9 {
+		t.Fatalf("expected +1%%, got %.4f", got)
+	}
+	// Selling 1% above the backtest price is favorable
+	if got := driftSlippagePct(100, 101, false); math.Abs(got+1) > 1e-9 {
+		t.Fatalf("expected -1%%, got %.4f", got)
+	}
+	if driftSlippagePct(0, 101, true) != 0 {
+		t.Fatalf("expected 0 without backtest price")
+	}
+}
+
+func TestMatchLiveDriftTrades(t *testing.T) {
+	start := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	bt := []ArenaBacktestTrade{
+		{Direction: "LONG", EntryTime: start.Add(-time.Hour).Unix(), EntryPrice: 90, ExitPrice: 95, ReturnPct: 5.5}, // before session start
+		{Direction: "LONG", EntryTime: start.Add(time.Hour).Unix(), EntryPrice: 100, ExitPrice: 110, ReturnPct: 10},
+		{Direction: "LONG", EntryTime: start.Add(5 * time.Hour).Unix(), EntryPrice: 120, ExitPrice: 114, ReturnPct: -5},
+		{Direction: "SHORT", EntryTime: start.Add(6 * time.Hour).Unix(), EntryPrice: 115, ExitPrice: 110, ReturnPct: 4.3},
+		{Direction: "LONG", EntryTime: start.Add(20 * time.Hour).Unix(), EntryPrice: 125, ExitPrice: 130, ReturnPct: 4}, // after the session stopped
+	}
+	closeTime := start.Add(3 * time.Hour)
+	live := []LiveTradingPosition{
+		// Matched one bar late, bought 1% higher, sold 2% lower
+		{Direction: "LONG", EntryTime: start.Add(2 * time.Hour), EntryPrice: 101, IsClosed: true, ClosePrice: 107.8, CloseTime: &closeTime, ProfitLossPct: 6.73, InvestedAmount: 505, ProfitLossAmt: 33.6},
+		// No backtest counterpart
+		{Direction: "LONG", EntryTime: start.Add(10 * time.Hour), EntryPrice: 130, ProfitLossPct: -1, ProfitLossAmt: -5},
+	}
+
+	// Missed trades are sized like the session's live entries: 3 shares
+	sizer := liveDriftSizer(LiveTradingSession{TradeAmount: 500}, LiveTradingConfig{SizingMode: sizingModeFixedShares, FixedShares: 3}, "AAPL", nil)
+	trades := matchLiveDriftTrades("AAPL", bt, live, start, start.Add(12*time.Hour), true, 3600, sizer)
+	kinds := map[string]int{}
+	for _, tr := range trades {
+		kinds[tr.Kind]++
+	}
+	// SHORT is skipped (long only), trades before the start and after the stop ignored
+	if kinds["matched"] != 1 || kinds["missed"] != 1 || kinds["extra"] != 1 {
+		t.Fatalf("expected 1 matched / 1 missed / 1 extra, got %v", kinds)
+	}
+	m := trades[0]
+	if m.Kind != "matched" || math.Abs(m.EntrySlippagePct-1) > 1e-9 || math.Abs(m.ExitSlippagePct-2) > 1e-9 {
+		t.Fatalf("unexpected matched trade: %+v", m)
+	}
+
+	var report LiveDriftReport
+	summarizeLiveDrift(&report, trades)
+	if report.BacktestTrades != 2 || report.LiveTrades != 2 || report.MatchRate != 50 {
+		t.Fatalf("unexpected summary: %+v", report)
+	}
+	// Matched: 10% of the live 505 USD, missed: -5% of 3 × 120 USD
+	if math.Abs(report.BacktestReturnPct-5) > 1e-9 || math.Abs(report.BacktestPnL-(50.5-18)) > 1e-9 {
+		t.Fatalf("expected backtest 5%% / 32.5 P&L, got %.2f / %.2f", report.BacktestReturnPct, report.BacktestPnL)
+	}
+	if math.Abs(report.LivePnL-28.6) > 1e-9 || math.Abs(report.PnLDiff-(28.6-32.5)) > 1e-9 {
+		t.Fatalf("expected live P&L 28.6 / diff -3.9, got %.2f / %.2f", report.LivePnL, report.PnLDiff)
+	}
+}
+
+func TestLiveDriftReportEndpoints(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&LiveSessionStrategy{}, &LiveTradingLog{}, &LiveDriftReport{})
+	r, token := setupLiveRouter(t)
+	r.GET("/api/trading/live/session/:id/drift", authMiddleware(), getLiveDriftReports)
+	r.POST("/api/trading/live/session/:id/drift", authMiddleware(), adminOnly(), runLiveDriftReportHandler)
+
+	var admin User
+	db.Where("username = ?", "admin").First(&admin)
+	config := LiveTradingConfig{UserID: admin.ID, TradeAmount: 500}
+	db.Create(&config)
+	started := time.Now().Add(-48 * time.Hour)
+	session := LiveTradingSession{UserID: admin.ID, ConfigID: config.ID, Name: "Drift", Strategy: "hybrid_ai_trend", Interval: "1h", IsActive: true, StartedAt: started}
+	db.Create(&session)
+	strat := LiveSessionStrategy{SessionID: session.ID, Name: "hybrid_ai_trend", Symbols: `["DRIFTTEST_NO_CACHE"]`, IsEnabled: true, LongOnly: true}
+	db.Create(&strat)
+	db.Create(&LiveTradingPosition{SessionID: session.ID, StrategyID: strat.ID, Symbol: "DRIFTTEST_NO_CACHE", Direction: "LONG", EntryPrice: 10, EntryTime: started.Add(time.Hour), ProfitLossAmt: 12})
+
+	w := getJSON(r, "/api/trading/live/session/999/drift", token)
+	if w.Code != 404 {
+		t.Fatalf("expected 404 for unknown session, got %d", w.Code)
+	}
+
+	w = postJSON(r, "/api/trading/live/session/"+fmt.Sprint(session.ID)+"/drift", token, nil)
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = getJSON(r, "/api/trading/live/session/"+fmt.Sprint(session.ID)+"/drift", token)
+	var resp struct {
+		Latest []struct {
+			Report LiveDriftReport  `json:"report"`
+			Trades []LiveDriftTrade `json:"trades"`
+		} `json:"latest"`
+		History []LiveDriftReport `json:"history"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Latest) != 1 || len(resp.History) != 1 {
+		t.Fatalf("expected 1 latest + 1 history report, got %s", w.Body.String())
+	}
+	rep := resp.Latest[0].Report
+	// No cached bars → the live position cannot be confirmed by the backtest
+	if rep.ExtraTrades != 1 || rep.LivePnL != 12 || rep.Trigger != "manual" || rep.StrategyID != strat.ID {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+	if len(resp.Latest[0].Trades) != 1 || resp.Latest[0].Trades[0].Kind != "extra" {
+		t.Fatalf("expected one extra trade in details, got %+v", resp.Latest[0].Trades)
+	}
+
+	// Not-started session is rejected
+	idle := LiveTradingSession{UserID: admin.ID, Name: "Idle", Interval: "1h"}
+	db.Create(&idle)
+	req, _ := http.NewRequest("POST", "/api/trading/live/session/"+fmt.Sprint(idle.ID)+"/drift", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != 400 {
+		t.Fatalf("expected 400 for session without StartedAt, got %d", rec.Code)
+	}
+}
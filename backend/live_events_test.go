@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ============ Live Event Stream Tests ============
+
+func setupLiveEventsTest(t *testing.T) (uint, uint) {
+	t.Helper()
+	setupLiveTestDB(t)
+	liveEvents = newLiveEventHub()
+	user := User{Email: "events@test.com", Username: "events", Password: "hashed", IsAdmin: true}
+	db.Create(&user)
+	session := LiveTradingSession{UserID: user.ID, Name: "Events", Strategy: "hybrid_ai_trend", Interval: "5m", IsActive: true}
+	db.Create(&session)
+	return user.ID, session.ID
+}
+
+func TestLiveEventTypeForLevel(t *testing.T) {
+	cases := map[string]string{
+		"SIGNAL": liveEventSignal,
+		"OPEN":   liveEventPositionOpened,
+		"CLOSE":  liveEventPositionClosed,
+		"SL":     liveEventSLTPHit,
+		"TP":     liveEventSLTPHit,
+		"ALPACA": liveEventOrderSubmitted,
+		"IBKR":   liveEventOrderSubmitted,
+		"INFO":   liveEventLog,
+	}
+	for level, want := range cases {
+		if got := liveEventTypeForLevel(level); got != want {
+			t.Errorf("%s: expected %s, got %s", level, want, got)
+		}
+	}
+}
+
+func TestLiveEventHubReplaySince(t *testing.T) {
+	uid, sid := setupLiveEventsTest(t)
+
+	logLiveEvent(sid, "SIGNAL", "AAPL", "BUY erkannt")
+	logLiveEvent(sid, "OPEN", "AAPL", "LONG eröffnet")
+	logLiveEvent(sid, "DEBUG", "-", "Scan übersprungen")
+	publishLiveEvent(sid, liveEventScanProgress, "", map[string]interface{}{"progress": 1, "total": 2})
+	logLiveEvent(sid, "TP", "AAPL", "TP ausgelöst")
+
+	replay, ch, gap := liveEvents.Subscribe(uid, 0)
+	defer liveEvents.Unsubscribe(uid, ch)
+	if gap {
+		t.Fatalf("expected no gap")
+	}
+	// DEBUG log and scan progress are transient → not replayed
+	if len(replay) != 3 {
+		t.Fatalf("expected 3 replayable events, got %d: %+v", len(replay), replay)
+	}
+	if replay[0].Type != liveEventSignal || replay[1].Type != liveEventPositionOpened || replay[2].Type != liveEventSLTPHit {
+		t.Fatalf("unexpected event types: %s, %s, %s", replay[0].Type, replay[1].Type, replay[2].Type)
+	}
+
+	replay2, ch2, _ := liveEvents.Subscribe(uid, replay[1].Seq)
+	defer liveEvents.Unsubscribe(uid, ch2)
+	if len(replay2) != 1 || replay2[0].Seq != replay[2].Seq {
+		t.Fatalf("expected only the TP event after seq %d, got %+v", replay[1].Seq, replay2)
+	}
+
+	// Live delivery to the subscriber, including transient events
+	publishLiveEvent(sid, liveEventWSState, "", map[string]interface{}{"connected": true})
+	select {
+	case evt := <-ch:
+		if evt.Type != liveEventWSState || evt.Data["connected"] != true {
+			t.Fatalf("unexpected live event: %+v", evt)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected live ws_state event")
+	}
+}
+
+func TestLiveEventHubOtherUserIsolated(t *testing.T) {
+	_, sid := setupLiveEventsTest(t)
+	other := User{Email: "other@test.com", Username: "other", Password: "hashed"}
+	db.Create(&other)
+
+	logLiveEvent(sid, "OPEN", "AAPL", "LONG eröffnet")
+	replay, ch, _ := liveEvents.Subscribe(other.ID, 0)
+	defer liveEvents.Unsubscribe(other.ID, ch)
+	if len(replay) != 0 {
+		t.Fatalf("expected no events for other user, got %d", len(replay))
+	}
+}
+
+func TestLiveEventHubGapAfterEviction(t *testing.T) {
+	uid, sid := setupLiveEventsTest(t)
+	for i := 0; i < liveEventBufferSize+5; i++ {
+		publishLiveEvent(sid, liveEventOrderFilled, "AAPL", map[string]interface{}{"n": i})
+	}
+	replay, ch, gap := liveEvents.Subscribe(uid, 2)
+	defer liveEvents.Unsubscribe(uid, ch)
+	if !gap {
+		t.Fatalf("expected gap after eviction")
+	}
+	if len(replay) != liveEventBufferSize {
+		t.Fatalf("expected %d buffered events, got %d", liveEventBufferSize, len(replay))
+	}
+	_, ch2, gap := liveEvents.Subscribe(uid, replay[0].Seq-1)
+	defer liveEvents.Unsubscribe(uid, ch2)
+	if gap {
+		t.Fatalf("expected no gap when resuming at the oldest buffered event")
+	}
+}
+
+func TestLiveEventHubDropsSlowSubscriber(t *testing.T) {
+	uid, sid := setupLiveEventsTest(t)
+	_, ch, _ := liveEvents.Subscribe(uid, 0)
+	for i := 0; i < cap(ch)+1; i++ {
+		publishLiveEvent(sid, liveEventScanProgress, "", nil)
+	}
+	n := 0
+	for range ch {
+		n++
+	}
+	if n != cap(ch) {
+		t.Fatalf("expected channel closed after %d buffered events, got %d", cap(ch), n)
+	}
+	liveEvents.Unsubscribe(uid, ch) // must not panic on an already closed channel
+}
+
+func TestStreamLiveEventsEndpoint(t *testing.T) {
+	_, sid := setupLiveEventsTest(t)
+	r, token := setupLiveRouter(t)
+	r.GET("/api/trading/live/events", authMiddleware(), streamLiveEvents)
+	// setupLiveRouter creates its own admin — the stream belongs to that user
+	var admin User
+	db.Where("username = ?", "admin").First(&admin)
+	db.Model(&LiveTradingSession{}).Where("id = ?", sid).Update("user_id", admin.ID)
+
+	logLiveEvent(sid, "SIGNAL", "AAPL", "BUY erkannt")
+	logLiveEvent(sid, "OPEN", "AAPL", "LONG eröffnet")
+	first := liveEvents.LastSeq() - 1
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	read := func(query string, want int) []map[string]interface{} {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/api/trading/live/events"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+			t.Fatalf("expected event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		var msgs []map[string]interface{}
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() && len(msgs) < want {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			var m map[string]interface{}
+			json.Unmarshal([]byte(line[6:]), &m)
+			msgs = append(msgs, m)
+		}
+		return msgs
+	}
+
+	msgs := read("", 3)
+	if len(msgs) != 3 || msgs[0]["type"] != "hello" || msgs[1]["type"] != liveEventSignal || msgs[2]["type"] != liveEventPositionOpened {
+		t.Fatalf("expected hello + 2 replayed events, got %+v", msgs)
+	}
+	epoch := msgs[0]["epoch"].(string)
+
+	msgs = read(fmt.Sprintf("?since=%d&epoch=%s", first, epoch), 2)
+	if len(msgs) != 2 || msgs[1]["type"] != liveEventPositionOpened || msgs[0]["resync"] != false {
+		t.Fatalf("expected only the OPEN event after seq %d, got %+v", first, msgs)
+	}
+
+	// Unknown epoch (server restarted) → full replay with resync flag
+	msgs = read(fmt.Sprintf("?since=%d&epoch=1", first), 1)
+	if len(msgs) != 1 || msgs[0]["resync"] != true {
+		t.Fatalf("expected resync for stale epoch, got %+v", msgs)
+	}
+
+	w := getJSON(r, "/api/trading/live/events?since=abc", token)
+	if w.Code != 400 {
+		t.Fatalf("expected 400 for invalid seq, got %d", w.Code)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// ============ Position Sizing Tests ============
+
+func TestSizingAmountMode(t *testing.T) {
+	size := computeLivePositionSize(LivePositionSizingInput{
+		Mode: sizingModeAmount, TradeAmount: 500, EntryPriceUSD: 200, Fractionable: true,
+	})
+	if size.Skip || size.Qty != 2.5 {
+		t.Fatalf("expected 2.5 shares, got %+v", size)
+	}
+	if size.Rounding != "" {
+		t.Fatalf("fractionable asset must not be rounded: %s", size.Rounding)
+	}
+}
+
+func TestSizingRiskMode(t *testing.T) {
+	// 1% of $10,000 equity = $100 risk, SL 2% below $50 entry = $1/share → 100 shares
+	size := computeLivePositionSize(LivePositionSizingInput{
+		Mode: sizingModeRisk, TradeAmount: 500, RiskPercent: 1,
+		EntryPriceUSD: 50, StopDistPct: 2, EquityUSD: 10000, Fractionable: true,
+	})
+	if size.Mode != sizingModeRisk || math.Abs(size.Qty-100) > 1e-6 {
+		t.Fatalf("expected 100 shares in risk mode, got %+v", size)
+	}
+	if math.Abs(size.Notional-5000) > 1e-6 {
+		t.Fatalf("expected $5000 notional, got %.2f", size.Notional)
+	}
+}
+
+func TestSizingRiskModeWithoutStopFallsBack(t *testing.T) {
+	size := computeLivePositionSize(LivePositionSizingInput{
+		Mode: sizingModeRisk, TradeAmount: 500, RiskPercent: 1,
+		EntryPriceUSD: 100, EquityUSD: 10000, Fractionable: true,
+	})
+	if size.Mode != sizingModeAmount || size.Qty != 5 {
+		t.Fatalf("expected fallback to amount (5 shares), got %+v", size)
+	}
+}
+
+func TestSizingCappedAtEquity(t *testing.T) {
+	// Tight stop would size $50,000 — capped at $2,000 equity
+	size := computeLivePositionSize(LivePositionSizingInput{
+		Mode: sizingModeRisk, RiskPercent: 5, EntryPriceUSD: 100,
+		StopDistPct: 0.2, EquityUSD: 2000, Fractionable: true,
+	})
+	if math.Abs(size.Notional-2000) > 1e-6 {
+		t.Fatalf("expected notional capped at 2000, got %.2f", size.Notional)
+	}
+}
+
+func TestSizingEquityPctMode(t *testing.T) {
+	size := computeLivePositionSize(LivePositionSizingInput{
+		Mode: sizingModeEquityPct, EquityPercent: 10, EntryPriceUSD: 40,
+		EquityUSD: 20000, Fractionable: true,
+	})
+	if size.Qty != 50 {
+		t.Fatalf("expected 50 shares (10%% of 20000 @ 40), got %+v", size)
+	}
+
+	// No equity (Alpaca disabled) → TradeAmount
+	size = computeLivePositionSize(LivePositionSizingInput{
+		Mode: sizingModeEquityPct, EquityPercent: 10, TradeAmount: 400, EntryPriceUSD: 40, Fractionable: true,
+	})
+	if size.Mode != sizingModeAmount || size.Qty != 10 {
+		t.Fatalf("expected fallback to amount (10 shares), got %+v", size)
+	}
+}
+
+func TestSizingVolatilityMode(t *testing.T) {
+	// Target 2%, ATR 4% → half of TradeAmount
+	size := computeLivePositionSize(LivePositionSizingInput{
+		Mode: sizingModeVolatility, TradeAmount: 1000, VolTargetPct: 2,
+		ATRPct: 4, EntryPriceUSD: 10, Fractionable: true,
+	})
+	if size.Qty != 50 {
+		t.Fatalf("expected 50 shares, got %+v", size)
+	}
+	// Very low ATR is capped at 3x
+	size = computeLivePositionSize(LivePositionSizingInput{
+		Mode: sizingModeVolatility, TradeAmount: 1000, VolTargetPct: 2,
+		ATRPct: 0.1, EntryPriceUSD: 10, Fractionable: true,
+	})
+	if size.Qty != 300 {
+		t.Fatalf("expected scale capped at 3x (300 shares), got %+v", size)
+	}
+}
+
+func TestSizingNonFractionableRoundsDown(t *testing.T) {
+	size := computeLivePositionSize(LivePositionSizingInput{
+		Mode: sizingModeAmount, TradeAmount: 500, EntryPriceUSD: 150, Fractionable: false,
+	})
+	if size.Qty != 3 || size.Rounding == "" {
+		t.Fatalf("expected 3 whole shares with rounding note, got %+v", size)
+	}
+
+	size = computeLivePositionSize(LivePositionSizingInput{
+		Mode: sizingModeAmount, TradeAmount: 500, EntryPriceUSD: 900, Fractionable: false,
+	})
+	if !size.Skip {
+		t.Fatalf("expected skip when less than one whole share, got %+v", size)
+	}
+}
+
+func TestSizingFixedShares(t *testing.T) {
+	size := computeLivePositionSize(LivePositionSizingInput{
+		Mode: sizingModeFixedShares, FixedShares: 7.5, EntryPriceUSD: 20, Fractionable: false,
+	})
+	if size.Qty != 7 {
+		t.Fatalf("expected 7 whole shares, got %+v", size)
+	}
+}
+
+func TestLiveATRPct(t *testing.T) {
+	bars := make([]OHLCV, 30)
+	for i := range bars {
+		bars[i] = OHLCV{Time: int64(i * 60), Open: 100, High: 101, Low: 99, Close: 100}
+	}
+	atr := liveATRPct(bars, 14)
+	if math.Abs(atr-2) > 1e-9 {
+		t.Fatalf("expected ATR 2%%, got %.4f", atr)
+	}
+	if liveATRPct(bars[:10], 14) != 0 {
+		t.Fatalf("expected 0 ATR with too few bars")
+	}
+}
+
+func TestSaveLiveTradingConfigSizing(t *testing.T) {
+	setupLiveTestDB(t)
+	r, token := setupLiveRouter(t)
+
+	w := postJSON(r, "/api/trading/live/config", token, map[string]interface{}{
+		"strategy": "hybrid_ai_trend", "interval": "5m", "symbols": []string{"AAPL"},
+		"trade_amount": 500, "sizing_mode": "risk", "risk_percent": 0.5,
+	})
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = getJSON(r, "/api/trading/live/config", token)
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["sizing_mode"] != "risk" || resp["risk_percent"] != 0.5 {
+		t.Fatalf("expected risk/0.5, got %v/%v", resp["sizing_mode"], resp["risk_percent"])
+	}
+
+	w = postJSON(r, "/api/trading/live/config", token, map[string]interface{}{
+		"strategy": "hybrid_ai_trend", "sizing_mode": "kelly",
+	})
+	if w.Code != 400 {
+		t.Fatalf("expected 400 for unknown sizing mode, got %d", w.Code)
+	}
+}
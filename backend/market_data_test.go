@@ -0,0 +1,648 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// ============ Market Data Provider Tests ============
+
+type fakeMarketData struct {
+	name   string
+	fail   bool
+	bars   []OHLCV
+	quote  map[string]QuoteData
+	search []SearchResult
+	calls  *int
+}
+
+func (f fakeMarketData) Name() string { return f.name }
+
+func (f fakeMarketData) Bars(symbol, period, interval string) ([]OHLCV, error) {
+	*f.calls++
+	if f.fail {
+		return nil, providerStatusError(f.name, 503, "%s status 503", f.name)
+	}
+	return f.bars, nil
+}
+
+func (f fakeMarketData) Quotes(symbols []string) (map[string]QuoteData, error) {
+	*f.calls++
+	if f.fail {
+		return nil, providerStatusError(f.name, 503, "%s status 503", f.name)
+	}
+	return f.quote, nil
+}
+
+func (f fakeMarketData) Search(string) ([]SearchResult, error) {
+	if f.search == nil {
+		return nil, errProviderUnsupported
+	}
+	return f.search, nil
+}
+
+func (f fakeMarketData) Fundamentals(string) (*MarketFundamentals, error) {
+	return nil, errProviderUnsupported
+}
+
+// fakeBatchMarketData also serves several symbols per request
+type fakeBatchMarketData struct {
+	fakeMarketData
+	batch map[string][]OHLCV
+}
+
+func (f fakeBatchMarketData) BatchBars(symbols []string, period, interval string) (map[string][]OHLCV, error) {
+	*f.calls++
+	out := map[string][]OHLCV{}
+	for _, sym := range symbols {
+		if b, ok := f.batch[sym]; ok {
+			out[sym] = b
+		}
+	}
+	return out, nil
+}
+
+// setupMarketDataTest registers two fake providers ("primary" fails when failPrimary is set)
+func setupMarketDataTest(t *testing.T, failPrimary bool) (*int, *int) {
+	t.Helper()
+	setupLiveTestDB(t)
+	db.AutoMigrate(&GlobalSetting{})
+	primaryCalls, backupCalls := 0, 0
+	marketDataRegistry["primary"] = func() MarketDataProvider {
+		return fakeMarketData{name: "primary", fail: failPrimary, calls: &primaryCalls,
+			bars: []OHLCV{{Time: 1, Close: 1}}, quote: map[string]QuoteData{"AAPL": {Price: 100}}}
+	}
+	marketDataRegistry["backup"] = func() MarketDataProvider {
+		return fakeMarketData{name: "backup", calls: &backupCalls,
+			bars: []OHLCV{{Time: 2, Close: 2}}, quote: map[string]QuoteData{"AAPL": {Price: 101}, "MSFT": {Price: 300}}}
+	}
+	t.Cleanup(func() {
+		delete(marketDataRegistry, "primary")
+		delete(marketDataRegistry, "backup")
+		marketDataHealthMap = map[string]*MarketDataProviderHealth{}
+		resetMarketDataChain()
+	})
+	marketDataHealthMap = map[string]*MarketDataProviderHealth{}
+	setGlobalSetting(marketDataSettingChain, "primary,backup")
+	resetMarketDataChain()
+	return &primaryCalls, &backupCalls
+}
+
+func TestParseMarketDataChain(t *testing.T) {
+	names, err := parseMarketDataChain(" Alpaca, yahoo,alpaca ,file")
+	if err != nil || len(names) != 3 || names[0] != "alpaca" || names[2] != "file" {
+		t.Fatalf("unexpected chain: %v (%v)", names, err)
+	}
+	if _, err := parseMarketDataChain("yahoo,bloomberg"); err == nil {
+		t.Fatalf("expected error for unknown provider")
+	}
+	if _, err := parseMarketDataChain(" , "); err == nil {
+		t.Fatalf("expected error for empty chain")
+	}
+}
+
+func TestMarketDataFailover(t *testing.T) {
+	primaryCalls, backupCalls := setupMarketDataTest(t, true)
+
+	bars, err := marketDataBars("AAPL", "2y", "1d")
+	if err != nil || len(bars) != 1 || bars[0].Close != 2 {
+		t.Fatalf("expected backup bars, got %v (%v)", bars, err)
+	}
+	quotes := marketDataQuotes([]string{"AAPL", "MSFT"})
+	if quotes["AAPL"].Price != 101 || quotes["MSFT"].Price != 300 {
+		t.Fatalf("expected backup quotes, got %v", quotes)
+	}
+
+	// After marketDataCooldownAfter failures the primary is skipped
+	for i := 0; i < marketDataCooldownAfter; i++ {
+		marketDataBars("AAPL", "2y", "1d")
+	}
+	before := *primaryCalls
+	marketDataBars("AAPL", "2y", "1d")
+	if *primaryCalls != before {
+		t.Fatalf("expected primary to be skipped during cooldown")
+	}
+
+	health := marketDataHealth()
+	if health["primary"].ConsecutiveFailures < marketDataCooldownAfter || health["primary"].CooldownUntil == nil || health["primary"].LastError == "" {
+		t.Fatalf("unexpected primary health: %+v", health["primary"])
+	}
+	if health["backup"].Failures != 0 || health["backup"].Calls != int64(*backupCalls) || health["backup"].LastSuccessAt == nil {
+		t.Fatalf("unexpected backup health: %+v", health["backup"])
+	}
+}
+
+func TestMarketDataUnknownSymbolIsNoOutage(t *testing.T) {
+	setupMarketDataTest(t, false)
+	calls := 0
+	marketDataRegistry["primary"] = func() MarketDataProvider {
+		return fakeMarketData{name: "primary", calls: &calls}
+	}
+	resetMarketDataChain()
+
+	// Empty series fall through to the backup but keep the primary out of cooldown
+	for i := 0; i < marketDataCooldownAfter+1; i++ {
+		if _, err := marketDataBars("NOPE", "2y", "1d"); err != nil {
+			t.Fatalf("expected backup bars, got %v", err)
+		}
+	}
+	if calls != marketDataCooldownAfter+1 {
+		t.Fatalf("primary must be asked every time, got %d calls", calls)
+	}
+	if h := marketDataHealth()["primary"]; h.Failures != 0 || h.CooldownUntil != nil {
+		t.Fatalf("unknown symbols must not count as failures, got %+v", h)
+	}
+}
+
+func TestMarketDataOutage(t *testing.T) {
+	cases := map[error]bool{
+		nil:                                      false,
+		errProviderUnsupported:                   false,
+		providerNoData("yahoo", "no data for X"): false,
+		providerStatusError("yahoo", 404, "yahoo X status 404"):                                                   false,
+		providerStatusError("yahoo", 502, "yahoo X status 502"):                                                   true,
+		providerStatusError("yahoo", 429, "yahoo X status 429"):                                                   true,
+		&providerError{Provider: "twelvedata", RateLimited: true, Err: fmt.Errorf("TWELVE_DATA_RATE_LIMIT: max")}: true,
+		fmt.Errorf("alpaca request failed: %w", &url.Error{Op: "Get", URL: "x", Err: fmt.Errorf("refused")}):      true,
+		// The text alone no longer counts
+		fmt.Errorf("yahoo X status 502"): false,
+	}
+	for err, want := range cases {
+		if got := marketDataOutage(err); got != want {
+			t.Errorf("marketDataOutage(%v) = %v, want %v", err, got, want)
+		}
+	}
+}
+
+func TestMarketDataBarsBatch(t *testing.T) {
+	primaryCalls, _ := setupMarketDataTest(t, false)
+	batchCalls := 0
+	marketDataRegistry["batch"] = func() MarketDataProvider {
+		return fakeBatchMarketData{fakeMarketData{name: "batch", calls: &batchCalls}, map[string][]OHLCV{"AAPL": {{Time: 3, Close: 3}}}}
+	}
+	t.Cleanup(func() { delete(marketDataRegistry, "batch") })
+	setGlobalSetting(marketDataSettingChain, "primary,batch")
+	resetMarketDataChain()
+
+	// Providers without batch support are left out; missing symbols stay for the per-symbol path
+	bars := marketDataBarsBatch([]string{"AAPL", "MSFT"}, "2y", "1d")
+	if len(bars) != 1 || bars["AAPL"][0].Close != 3 || batchCalls != 1 || *primaryCalls != 0 {
+		t.Fatalf("unexpected batch result %v (calls %d/%d)", bars, *primaryCalls, batchCalls)
+	}
+}
+
+func TestMarketDataChainErrorKeepsProviderErrors(t *testing.T) {
+	setupMarketDataTest(t, true)
+	marketDataRegistry["backup"] = func() MarketDataProvider {
+		return fakeMarketData{name: "backup", calls: new(int)}
+	}
+	resetMarketDataChain()
+
+	_, err := marketDataBars("AAPL", "2y", "1d")
+	errs := providerErrors(fmt.Errorf("wrapped: %w", err))
+	if len(errs) != 2 || errs[0].Provider != "primary" || errs[0].Status != 503 || !errs[1].NoData {
+		t.Fatalf("unexpected provider errors %+v", errs)
+	}
+	if err.Error() != "alle Provider fehlgeschlagen (primary: primary status 503; backup: keine Daten)" {
+		t.Errorf("unexpected message %q", err)
+	}
+}
+
+func TestMarketDataQuotesFallThroughForMissingSymbols(t *testing.T) {
+	primaryCalls, backupCalls := setupMarketDataTest(t, false)
+	quotes := marketDataQuotes([]string{"AAPL", "MSFT"})
+	// AAPL from the primary, only MSFT asked from the backup
+	if quotes["AAPL"].Price != 100 || quotes["MSFT"].Price != 300 || *primaryCalls != 1 || *backupCalls != 1 {
+		t.Fatalf("unexpected quotes %v (calls %d/%d)", quotes, *primaryCalls, *backupCalls)
+	}
+}
+
+func TestMarketDataSearchUnsupportedIsNotAFailure(t *testing.T) {
+	setupMarketDataTest(t, false)
+	if r := marketDataSearch("AA"); len(r) != 0 {
+		t.Fatalf("expected no results, got %v", r)
+	}
+	if h := marketDataHealth()["primary"]; h.Calls != 0 {
+		t.Fatalf("unsupported calls must not be recorded, got %+v", h)
+	}
+}
+
+func TestFileMarketDataCSV(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "1d"), 0755)
+	os.WriteFile(filepath.Join(dir, "1d", "SAP.DE.csv"), []byte(
+		"Date;Open;High;Low;Close;Volume\n"+
+			"2024-01-03;101;103;100;102;1000\n"+
+			"2024-01-02;100;102;99;101;900\n"+
+			"2023-01-02;80;81;79;80;500\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "AAPL_60m.csv"), []byte(
+		"timestamp,open,high,low,close,volume\n"+
+			"1704205800,1,2,0.5,1.5,10\n"+
+			"1704209400,1.5,3,1,2.5,20\n"+
+			"1704213000,2.5,2.6,2,2.2,5\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "fundamentals.csv"), []byte("symbol,name,sector,market_cap,currency\nSAP.DE,SAP SE,Technology,2.1e11,EUR\n"), 0644)
+	f := fileMarketData{Dir: dir}
+
+	bars, err := f.Bars("SAP.DE", "max", "1D")
+	if err != nil || len(bars) != 3 || bars[0].Close != 80 || bars[2].Close != 102 {
+		t.Fatalf("expected 3 sorted daily bars, got %v (%v)", bars, err)
+	}
+	// Period is relative to the last bar → the 2023 bar is cut off
+	if bars, _ := f.Bars("SAP.DE", "6mo", "1d"); len(bars) != 2 {
+		t.Fatalf("expected 2 bars within 6mo, got %d", len(bars))
+	}
+	// 2h bars aggregated from the hourly file
+	bars, err = f.Bars("AAPL", "max", "2h")
+	if err != nil || len(bars) != 2 || bars[0].High != 3 || bars[0].Volume != 30 || bars[0].Close != 2.5 {
+		t.Fatalf("unexpected 2h aggregation: %v (%v)", bars, err)
+	}
+	if _, err := f.Bars("MSFT", "max", "1d"); err == nil {
+		t.Fatalf("expected error for missing file")
+	}
+
+	quotes, _ := f.Quotes([]string{"SAP.DE", "MSFT"})
+	if q := quotes["SAP.DE"]; q.Price != 102 || q.PrevClose != 101 || math.Abs(q.ChangePercent-0.990099) > 1e-4 {
+		t.Fatalf("unexpected quote: %+v", q)
+	}
+	if _, ok := quotes["MSFT"]; ok {
+		t.Fatalf("expected no quote for missing file")
+	}
+
+	results, _ := f.Search("sa")
+	if len(results) != 1 || results[0].Symbol != "SAP.DE" {
+		t.Fatalf("unexpected search results: %v", results)
+	}
+	fund, err := f.Fundamentals("sap.de")
+	if err != nil || fund.Name != "SAP SE" || fund.MarketCap != 210000000000 || fund.Currency != "EUR" {
+		t.Fatalf("unexpected fundamentals: %+v (%v)", fund, err)
+	}
+}
+
+func TestParseBarTime(t *testing.T) {
+	want := time.Date(2024, 1, 2, 15, 30, 0, 0, time.UTC).Unix()
+	for _, s := range []string{"1704209400", "1704209400000", "2024-01-02T15:30:00Z", "2024-01-02 15:30:00"} {
+		if got, err := parseBarTime(s); err != nil || got != want {
+			t.Errorf("%s: expected %d, got %d (%v)", s, want, got, err)
+		}
+	}
+	if _, err := parseBarTime("yesterday"); err == nil {
+		t.Errorf("expected error for invalid time")
+	}
+}
+
+func TestSnappyDecode(t *testing.T) {
+	// Literal "abc" followed by a 6-byte copy at offset 3
+	got, err := snappyDecode([]byte{0x09, 0x08, 'a', 'b', 'c', 0x09, 0x03})
+	if err != nil || string(got) != "abcabcabc" {
+		t.Fatalf("expected abcabcabc, got %q (%v)", got, err)
+	}
+	if _, err := snappyDecode([]byte{0x05, 0x09, 0x03}); err == nil {
+		t.Fatalf("expected error for copy before any literal")
+	}
+	// Declared length far beyond what the input can expand to
+	if _, err := snappyDecode([]byte{0xff, 0xff, 0xff, 0xff, 0x0f, 0x00, 'a'}); err == nil {
+		t.Fatalf("expected error for oversized length")
+	}
+}
+
+func TestParquetRejectsOversizedLengths(t *testing.T) {
+	// List of 2^32 elements in a 6 byte buffer
+	if _, err := (&thriftReader{buf: []byte{0xf5, 0xff, 0xff, 0xff, 0xff, 0x0f}}).readValue(9); err == nil {
+		t.Fatalf("expected error for oversized list")
+	}
+	data := make([]byte, 64)
+	for name, cm := range map[string]map[int16]interface{}{
+		"num_values":  {5: int64(1) << 40, 9: int64(4)},
+		"negative":    {5: int64(-1), 9: int64(4)},
+		"data_offset": {5: int64(1), 9: int64(1) << 40},
+	} {
+		if _, _, err := readParquetColumnChunk(data, cm, parquetColumn{Type: parquetTypeDouble}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, err := decodeParquetPlain(data, parquetTypeDouble, 1<<40); err == nil {
+		t.Fatalf("expected error for oversized dictionary")
+	}
+}
+
+func TestDecodeRLEHybrid(t *testing.T) {
+	// Bit-packed group (1,0,1,0,0,0,0,0) followed by an RLE run of three 1s
+	got, err := decodeRLEHybrid([]byte{0x03, 0x05, 0x06, 0x01}, 1, 11)
+	want := []int{1, 0, 1, 0, 0, 0, 0, 0, 1, 1, 1}
+	if err != nil || fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected %v, got %v (%v)", want, got, err)
+	}
+}
+
+// ---- Test-side Parquet writer (flat schema, one row group) ----
+
+type thriftField struct {
+	id  int16
+	typ byte
+	val interface{}
+}
+
+type thriftWriter struct{ bytes.Buffer }
+
+func (w *thriftWriter) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (w *thriftWriter) value(typ byte, v interface{}) {
+	switch typ {
+	case 5, 6:
+		n := v.(int64)
+		w.uvarint(uint64((n << 1) ^ (n >> 63)))
+	case 8:
+		b := v.([]byte)
+		w.uvarint(uint64(len(b)))
+		w.Write(b)
+	case 9:
+		list := v.([]interface{})
+		elem := byte(12)
+		if len(list) > 0 {
+			switch list[0].(type) {
+			case int64:
+				elem = 5
+			case []byte:
+				elem = 8
+			}
+		}
+		w.WriteByte(byte(len(list))<<4 | elem)
+		for _, e := range list {
+			w.value(elem, e)
+		}
+	case 12:
+		w.structure(v.([]thriftField))
+	}
+}
+
+func (w *thriftWriter) structure(fields []thriftField) {
+	var last int16
+	for _, f := range fields {
+		if f.typ == 1 {
+			// bool true is encoded in the field header
+			w.WriteByte(byte(f.id-last)<<4 | 1)
+		} else {
+			w.WriteByte(byte(f.id-last)<<4 | f.typ)
+			w.value(f.typ, f.val)
+		}
+		last = f.id
+	}
+	w.WriteByte(0)
+}
+
+func thriftBytes(fields []thriftField) []byte {
+	var w thriftWriter
+	w.structure(fields)
+	return w.Bytes()
+}
+
+// snappyLiteral wraps data as a single snappy literal (valid for len <= 256)
+func snappyLiteral(data []byte) []byte {
+	var w thriftWriter
+	w.uvarint(uint64(len(data)))
+	if len(data) <= 60 {
+		w.WriteByte(byte(len(data)-1) << 2)
+	} else {
+		w.WriteByte(60 << 2)
+		w.WriteByte(byte(len(data) - 1))
+	}
+	w.Write(data)
+	return w.Bytes()
+}
+
+type testParquetColumn struct {
+	name     string
+	typ      int64
+	optional bool
+	millis   bool
+	dict     bool
+	snappy   bool
+	values   []float64
+	nulls    map[int]bool
+}
+
+func plainBytes(typ int64, values []float64) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		switch typ {
+		case parquetTypeInt64:
+			binary.Write(&buf, binary.LittleEndian, int64(v))
+		case parquetTypeDouble:
+			binary.Write(&buf, binary.LittleEndian, v)
+		}
+	}
+	return buf.Bytes()
+}
+
+func writeTestParquet(rows int, cols []testParquetColumn) []byte {
+	var file bytes.Buffer
+	file.WriteString("PAR1")
+	schema := []interface{}{[]thriftField{{4, 8, []byte("schema")}, {5, 5, int64(len(cols))}}}
+	var chunks []interface{}
+
+	for _, c := range cols {
+		repetition := int64(0)
+		if c.optional {
+			repetition = parquetRepetitionOptional
+		}
+		el := []thriftField{{1, 5, c.typ}, {3, 5, repetition}, {4, 8, []byte(c.name)}}
+		if c.millis {
+			unit := []thriftField{{parquetTimeUnitMillis, 12, []thriftField{}}}
+			el = append(el, thriftField{10, 12, []thriftField{{parquetLogicalTypeTimestamp, 12, []thriftField{{1, 1, true}, {2, 12, unit}}}}})
+		}
+		schema = append(schema, el)
+
+		codec := int64(parquetCodecUncompressed)
+		compress := func(b []byte) []byte { return b }
+		if c.snappy {
+			codec = parquetCodecSnappy
+			compress = snappyLiteral
+		}
+		writePage := func(header []thriftField, body []byte) {
+			comp := compress(body)
+			hdr := append([]thriftField{{1, 5, header[0].val}, {2, 5, int64(len(body))}, {3, 5, int64(len(comp))}}, header[1:]...)
+			file.Write(thriftBytes(hdr))
+			file.Write(comp)
+		}
+
+		var dense []float64
+		var levels []byte
+		for i, v := range c.values {
+			if c.nulls[i] {
+				levels = append(levels, 0)
+			} else {
+				levels = append(levels, 1)
+				dense = append(dense, v)
+			}
+		}
+
+		start := int64(file.Len())
+		dictOffset := int64(0)
+		var body bytes.Buffer
+		if c.optional {
+			// Definition levels as bit-packed groups with a length prefix
+			var rle thriftWriter
+			groups := (len(levels) + 7) / 8
+			rle.uvarint(uint64(groups<<1 | 1))
+			packed := make([]byte, groups)
+			for i, l := range levels {
+				packed[i/8] |= l << (i % 8)
+			}
+			rle.Write(packed)
+			binary.Write(&body, binary.LittleEndian, uint32(rle.Len()))
+			body.Write(rle.Bytes())
+		}
+		encoding := int64(parquetEncodingPlain)
+		if c.dict {
+			var uniq []float64
+			index := map[float64]int{}
+			var idx []int
+			for _, v := range dense {
+				if _, ok := index[v]; !ok {
+					index[v] = len(uniq)
+					uniq = append(uniq, v)
+				}
+				idx = append(idx, index[v])
+			}
+			dictOffset = start
+			writePage([]thriftField{{1, 5, int64(parquetPageDictionary)}, {7, 12, []thriftField{{1, 5, int64(len(uniq))}, {2, 5, int64(parquetEncodingPlain)}}}}, plainBytes(c.typ, uniq))
+			// Indices with bit width 8, bit-packed in groups of 8
+			body.WriteByte(8)
+			var rle thriftWriter
+			groups := (len(idx) + 7) / 8
+			rle.uvarint(uint64(groups<<1 | 1))
+			packed := make([]byte, groups*8)
+			for i, k := range idx {
+				packed[i] = byte(k)
+			}
+			rle.Write(packed)
+			body.Write(rle.Bytes())
+			encoding = parquetEncodingRLEDictionary
+		} else {
+			body.Write(plainBytes(c.typ, dense))
+		}
+		dataOffset := int64(file.Len())
+		writePage([]thriftField{{1, 5, int64(parquetPageData)}, {5, 12, []thriftField{{1, 5, int64(rows)}, {2, 5, encoding}, {3, 5, int64(3)}, {4, 5, int64(3)}}}}, body.Bytes())
+
+		meta := []thriftField{
+			{1, 5, c.typ},
+			{2, 9, []interface{}{encoding}},
+			{3, 9, []interface{}{[]byte(c.name)}},
+			{4, 5, codec},
+			{5, 6, int64(rows)},
+			{6, 6, int64(file.Len()) - start},
+			{7, 6, int64(file.Len()) - start},
+			{9, 6, dataOffset},
+		}
+		if dictOffset > 0 {
+			meta = append(meta, thriftField{11, 6, dictOffset})
+		}
+		chunks = append(chunks, []thriftField{{2, 6, start}, {3, 12, meta}})
+	}
+
+	footer := thriftBytes([]thriftField{
+		{1, 5, int64(1)},
+		{2, 9, schema},
+		{3, 6, int64(rows)},
+		{4, 9, []interface{}{[]thriftField{{1, 9, chunks}, {2, 6, int64(0)}, {3, 6, int64(rows)}}}},
+	})
+	file.Write(footer)
+	binary.Write(&file, binary.LittleEndian, uint32(len(footer)))
+	file.WriteString("PAR1")
+	return file.Bytes()
+}
+
+func TestParseOHLCVParquet(t *testing.T) {
+	base := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	times := []float64{float64(base.UnixMilli()), float64(base.AddDate(0, 0, 1).UnixMilli()), float64(base.AddDate(0, 0, 2).UnixMilli())}
+	raw := writeTestParquet(3, []testParquetColumn{
+		{name: "timestamp", typ: parquetTypeInt64, millis: true, values: times},
+		{name: "Open", typ: parquetTypeDouble, snappy: true, values: []float64{10, 11, 12}},
+		{name: "High", typ: parquetTypeDouble, values: []float64{11, 12, 13}},
+		{name: "Low", typ: parquetTypeDouble, values: []float64{9, 10, 11}},
+		{name: "Close", typ: parquetTypeDouble, dict: true, snappy: true, values: []float64{10.5, 11.5, 10.5}},
+		{name: "Volume", typ: parquetTypeDouble, optional: true, values: []float64{100, 0, 300}, nulls: map[int]bool{1: true}},
+		{name: "symbol_id", typ: parquetTypeInt64, values: []float64{1, 1, 1}},
+	})
+
+	bars, err := parseOHLCVParquet(raw)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(bars) != 3 {
+		t.Fatalf("expected 3 bars, got %d", len(bars))
+	}
+	if bars[1].Time != base.AddDate(0, 0, 1).Unix() || bars[1].Open != 11 || bars[1].Close != 11.5 || bars[1].Volume != 0 {
+		t.Fatalf("unexpected bar: %+v", bars[1])
+	}
+	if bars[2].Close != 10.5 || bars[2].Volume != 300 {
+		t.Fatalf("unexpected dictionary/optional decoding: %+v", bars[2])
+	}
+
+	// Through the file provider
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "ASML.AS_1d.parquet"), raw, 0644)
+	got, err := fileMarketData{Dir: dir}.Bars("ASML.AS", "max", "1d")
+	if err != nil || len(got) != 3 {
+		t.Fatalf("expected 3 bars from parquet file, got %d (%v)", len(got), err)
+	}
+
+	if _, err := parseOHLCVParquet([]byte("PAR1 not a parquet file PAR1")); err == nil {
+		t.Fatalf("expected error for corrupt file")
+	}
+}
+
+func TestMarketDataProvidersEndpoint(t *testing.T) {
+	setupMarketDataTest(t, false)
+	r, token := setupLiveRouter(t)
+	r.GET("/api/admin/market-data/providers", authMiddleware(), adminOnly(), getMarketDataProviders)
+	r.PUT("/api/admin/market-data/providers", authMiddleware(), adminOnly(), saveMarketDataProviders)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", "/api/admin/market-data/providers", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := put(`{"chain":["yahoo","nope"]}`); w.Code != 400 {
+		t.Fatalf("expected 400 for unknown provider, got %d", w.Code)
+	}
+	if w := put(`{"chain":["file"],"file_dir":""}`); w.Code != 400 {
+		t.Fatalf("expected 400 for file provider without directory, got %d", w.Code)
+	}
+	dir := t.TempDir()
+	w := put(fmt.Sprintf(`{"chain":["backup","file","yahoo"],"file_dir":%q}`, dir))
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if getGlobalSetting(marketDataSettingChain) != "backup,file,yahoo" || getGlobalSetting(marketDataSettingFileDir) != dir {
+		t.Fatalf("settings not stored")
+	}
+
+	marketDataBars("AAPL", "2y", "1d")
+	w = getJSON(r, "/api/admin/market-data/providers", token)
+	var resp struct {
+		Chain  []string                            `json:"chain"`
+		Health map[string]MarketDataProviderHealth `json:"health"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if fmt.Sprint(resp.Chain) != "[backup file yahoo]" || resp.Health["backup"].Calls != 1 {
+		t.Fatalf("unexpected response: %s", w.Body.String())
+	}
+}
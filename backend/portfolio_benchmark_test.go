@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBenchmarkStats_TrackingThePortfolio(t *testing.T) {
+	prices := map[time.Time]float64{
+		day(2026, 3, 2): 100, day(2026, 3, 3): 110, day(2026, 3, 4): 105, day(2026, 3, 5): 120, day(2026, 3, 6): 90,
+	}
+	bars := map[string][]OHLCV{"AAA": closes(prices)}
+	txs := []PortfolioTransaction{
+		{ID: 1, Symbol: "AAA", Type: "buy", Date: day(2026, 3, 2), Quantity: 10, Price: 100, Currency: "USD"},
+		{ID: 2, Symbol: "AAA", Type: "buy", Date: day(2026, 3, 4), Quantity: 5, Price: 105, Currency: "USD"},
+	}
+	series := valueSeriesFromBars(txs, day(2026, 3, 2), bars)
+
+	// The portfolio holds the benchmark itself, the deposit must not count as outperformance
+	s, ok := benchmarkStats(series, closes(prices), time.Time{})
+	if !ok {
+		t.Fatal("expected stats")
+	}
+	if !near(s.PortfolioReturn, -10) || !near(s.BenchmarkReturn, -10) || !near(s.ExcessReturn, 0) {
+		t.Errorf("unexpected returns %+v", s)
+	}
+	if !near(s.Beta, 1) || !near(s.Correlation, 1) || math.Abs(s.Alpha) > 1e-6 || math.Abs(s.TrackingError) > 1e-6 {
+		t.Errorf("identical returns must give beta 1 and no tracking error, got %+v", s)
+	}
+	if !near(s.MaxDrawdown, -25) || !near(s.BenchmarkMaxDrawdown, -25) || math.Abs(s.RelativeMaxDrawdown) > 1e-9 {
+		t.Errorf("unexpected drawdowns %+v", s)
+	}
+}
+
+func TestBenchmarkStats_LeveragedPortfolio(t *testing.T) {
+	days := []time.Time{day(2026, 4, 6), day(2026, 4, 7), day(2026, 4, 8), day(2026, 4, 9), day(2026, 4, 10)}
+	// Thursday is a holiday for the benchmark: its close carries forward
+	bench := []float64{100, 101, 99, 99, 100}
+	benchPrices := map[time.Time]float64{days[0]: 100, days[1]: 101, days[2]: 99, days[4]: 100}
+	stockPrices := map[time.Time]float64{days[0]: 50}
+	for i := 1; i < len(days); i++ {
+		stockPrices[days[i]] = stockPrices[days[i-1]] * (1 + 2*(bench[i]/bench[i-1]-1))
+	}
+
+	txs := []PortfolioTransaction{{ID: 1, Symbol: "BBB", Type: "buy", Date: days[0], Quantity: 10, Price: 50, Currency: "USD"}}
+	series := valueSeriesFromBars(txs, days[0], map[string][]OHLCV{"BBB": closes(stockPrices)})
+	s, ok := benchmarkStats(series, closes(benchPrices), time.Time{})
+	if !ok {
+		t.Fatal("expected stats")
+	}
+	if !near(s.Beta, 2) || !near(s.Correlation, 1) || math.Abs(s.Alpha) > 1e-6 {
+		t.Errorf("expected beta 2 without alpha, got %+v", s)
+	}
+	if s.TrackingError <= 0 || s.RelativeMaxDrawdown >= 0 || s.MaxDrawdown >= s.BenchmarkMaxDrawdown {
+		t.Errorf("leverage must show tracking error and deeper drawdowns, got %+v", s)
+	}
+}
+
+func TestNormalizeBenchmark(t *testing.T) {
+	for in, want := range map[string]string{"spy": "SPY", " ^gdaxi ": "^GDAXI", "eunl.de": "EUNL.DE", "EURUSD=X": "EURUSD=X"} {
+		if got, err := normalizeBenchmark(in); err != nil || got != want {
+			t.Errorf("normalizeBenchmark(%q) = %q, %v", in, got, err)
+		}
+	}
+	for _, in := range []string{"", "SPY; DROP", "../etc"} {
+		if _, err := normalizeBenchmark(in); err == nil {
+			t.Errorf("normalizeBenchmark(%q) must fail", in)
+		}
+	}
+}
+
+func TestBenchmarkSelection_Endpoints(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&GlobalSetting{})
+	r, token := setupLiveRouter(t)
+	r.GET("/api/benchmarks", authMiddleware(), getBenchmarks)
+	r.PUT("/api/benchmarks/preference", authMiddleware(), updateBenchmarkPreference)
+	r.PUT("/api/admin/benchmarks/default", authMiddleware(), adminOnly(), updateDefaultBenchmark)
+	r.GET("/api/portfolio/history", authMiddleware(), getPortfolioHistory)
+
+	selected := func() string {
+		var resp struct {
+			Selected string `json:"selected"`
+		}
+		json.Unmarshal(getJSON(r, "/api/benchmarks", token).Body.Bytes(), &resp)
+		return resp.Selected
+	}
+	if got := selected(); got != "SPY" {
+		t.Fatalf("expected SPY as fallback, got %q", got)
+	}
+	if w := putJSON(r, "/api/admin/benchmarks/default", token, map[string]string{"symbol": "urth"}); w.Code != http.StatusOK {
+		t.Fatalf("setting the default failed: %d %s", w.Code, w.Body.String())
+	}
+	if got := selected(); got != "URTH" {
+		t.Errorf("expected the admin default, got %q", got)
+	}
+	if w := putJSON(r, "/api/benchmarks/preference", token, map[string]string{"symbol": "^gdaxi"}); w.Code != http.StatusOK {
+		t.Fatalf("saving the preference failed: %d %s", w.Code, w.Body.String())
+	}
+	if got := selected(); got != "^GDAXI" {
+		t.Errorf("expected the user's choice, got %q", got)
+	}
+	if w := putJSON(r, "/api/benchmarks/preference", token, map[string]string{"symbol": ""}); w.Code != http.StatusOK || selected() != "URTH" {
+		t.Errorf("an empty choice must return to the default")
+	}
+
+	if w := putJSON(r, "/api/benchmarks/preference", token, map[string]string{"symbol": "SPY; DROP"}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid symbol, got %d", w.Code)
+	}
+	if w := getJSON(r, "/api/portfolio/history?benchmark=../x", token); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid benchmark, got %d", w.Code)
+	}
+}
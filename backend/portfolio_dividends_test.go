@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDividendCreditsAndForecast(t *testing.T) {
+	now := day(2026, 6, 15)
+	txs := []PortfolioTransaction{
+		{ID: 1, Symbol: "DIV", Type: "buy", Date: day(2025, 1, 10), Quantity: 10, Price: 50, Currency: "USD"},
+		{ID: 2, Symbol: "DIV", Type: "split", Date: day(2025, 9, 1), Ratio: 2},
+		{ID: 3, Symbol: "DIV", Type: "sell", Date: day(2026, 3, 2), Quantity: 5, Price: 30, Currency: "USD"},
+	}
+	splits := map[string][]CorporateAction{"DIV": {{Symbol: "DIV", Type: "split", ExDate: day(2025, 9, 1), Ratio: 2}}}
+	dividends := map[string][]CorporateAction{"DIV": {
+		{ID: 10, Symbol: "DIV", ExDate: day(2025, 1, 10), Amount: 0.5, Source: "yahoo"},    // bought on the ex-date: not entitled
+		{ID: 11, Symbol: "DIV", ExDate: day(2025, 6, 2), Amount: 0.5, Source: "yahoo"},     // split-adjusted: 1.00 per share then
+		{ID: 12, Symbol: "DIV", ExDate: day(2025, 12, 1), Amount: 0.6, Source: "manual"},   // after the split
+		{ID: 13, Symbol: "DIV", ExDate: day(2026, 6, 1), Amount: 0.6, Source: "manual"},    // after the partial sell
+		{ID: 14, Symbol: "DIV", ExDate: day(2026, 11, 30), Amount: 0.65, Source: "manual"}, // announced
+	}}
+
+	credits := dividendCredits(txs, dividends, splits, now)
+	if len(credits) != 3 {
+		t.Fatalf("expected three credited dividends, got %+v", credits)
+	}
+	for i, want := range []struct{ qty, perShare float64 }{{10, 1}, {20, 0.6}, {15, 0.6}} {
+		if credits[i].Quantity != want.qty || !near(credits[i].PerShare, want.perShare) {
+			t.Errorf("credit %d: expected %v × %v, got %+v", i, want.qty, want.perShare, credits[i])
+		}
+	}
+
+	state, _ := replayLedger(txs)
+	forecast := forecastDividends(state, dividends, splits, now)
+	// The announced November dividend replaces last December's, the June one is projected
+	if len(forecast) != 2 {
+		t.Fatalf("expected two forecast payments, got %+v", forecast)
+	}
+	if f := forecast[0]; !f.ExDate.Equal(day(2026, 11, 30)) || f.Estimated || f.AmountPerShare != 0.65 || f.Quantity != 15 {
+		t.Errorf("unexpected announced payment %+v", f)
+	}
+	if f := forecast[1]; !f.ExDate.Equal(day(2027, 6, 1)) || !f.Estimated || f.AmountPerShare != 0.6 {
+		t.Errorf("unexpected projected payment %+v", f)
+	}
+}
+
+func TestCreditDividendsToUserAndBotPortfolios(t *testing.T) {
+	setupCorporateActionTest(t)
+	r, token := setupLiveRouter(t)
+	r.GET("/api/portfolio/dividends", authMiddleware(), getPortfolioDividends)
+	r.POST("/api/portfolio/dividends/sync", authMiddleware(), syncPortfolioDividends)
+	r.GET("/api/portfolios/dividends/:bot", authMiddleware(), getBotDividends)
+	var admin User
+	db.Where("username = ?", "admin").First(&admin)
+
+	ex := time.Now().UTC().AddDate(0, 0, -10).Truncate(24 * time.Hour)
+	if _, err := addLedgerTransaction(defaultPortfolio(admin.ID), &PortfolioTransaction{Symbol: "DIVX", Type: "buy", Date: ex.AddDate(0, -2, 0), Quantity: 10, Price: 40, Currency: "USD"}); err != nil {
+		t.Fatal(err)
+	}
+	// Bought on the ex-date: not entitled
+	addLedgerTransaction(defaultPortfolio(7), &PortfolioTransaction{Symbol: "DIVX", Type: "buy", Date: ex, Quantity: 3, Price: 40, Currency: "USD"})
+	db.Create(&FlipperBotTrade{Symbol: "DIVX", Action: "BUY", Quantity: 4, Price: 40, SignalDate: ex.AddDate(0, -1, 0), ExecutedAt: ex.AddDate(0, -1, 0)})
+
+	created := recordCorporateActions([]CorporateAction{{Symbol: "DIVX", Type: "dividend", ExDate: ex, Amount: 0.5, Source: "manual"}})
+	applyCorporateAction(created[0])
+	applyCorporateAction(created[0]) // idempotent
+
+	var txs []PortfolioTransaction
+	db.Where("user_id = ? AND type = ?", admin.ID, "dividend").Find(&txs)
+	if len(txs) != 1 || txs[0].Amount != 5 || txs[0].Currency != "USD" || txs[0].ExternalID != dividendExternalID(created[0].ID) {
+		t.Fatalf("expected one dividend of 5 USD, got %+v", txs)
+	}
+	var count int64
+	db.Model(&PortfolioTransaction{}).Where("user_id = ? AND type = ?", 7, "dividend").Count(&count)
+	if count != 0 {
+		t.Error("buying on the ex-date must not earn the dividend")
+	}
+	var botDivs []BotDividend
+	db.Find(&botDivs)
+	if len(botDivs) != 1 || botDivs[0].Bot != "flipper" || botDivs[0].Amount != 2 {
+		t.Fatalf("expected a 2 USD bot dividend, got %+v", botDivs)
+	}
+
+	// A broker-imported payment a few weeks later is the same dividend
+	addLedgerTransaction(defaultPortfolio(7), &PortfolioTransaction{Symbol: "DIVX", Type: "buy", Date: ex.AddDate(0, -1, 0), Quantity: 2, Price: 40, Currency: "USD"})
+	addLedgerTransaction(defaultPortfolio(7), &PortfolioTransaction{Symbol: "DIVX", Type: "dividend", Date: ex.AddDate(0, 0, 5), Amount: 1, Currency: "USD", Source: "import"})
+	if added, _ := creditPortfolioDividends(defaultPortfolio(7), nil); added != 0 {
+		t.Errorf("imported dividend must not be booked twice, added %d", added)
+	}
+
+	if w := postJSON(r, "/api/portfolio/dividends/sync", token, nil); w.Code != 200 || w.Body.String() != `{"added":0}` {
+		t.Errorf("sync: %d %s", w.Code, w.Body.String())
+	}
+
+	// The forecast projects the dividend one year ahead
+	var report struct {
+		Currency    string             `json:"currency"`
+		Forecast    []DividendForecast `json:"forecast"`
+		Forecast12m float64            `json:"forecast_12m"`
+		Received12m float64            `json:"received_12m"`
+	}
+	w := getJSON(r, "/api/portfolio/dividends?currency=USD", token)
+	json.Unmarshal(w.Body.Bytes(), &report)
+	if w.Code != 200 || report.Currency != "USD" || report.Received12m != 5 || report.Forecast12m != 5 || len(report.Forecast) != 1 || !report.Forecast[0].Estimated {
+		t.Fatalf("unexpected report %s", w.Body.String())
+	}
+	w = getJSON(r, "/api/portfolios/dividends/flipper", token)
+	json.Unmarshal(w.Body.Bytes(), &report)
+	if w.Code != 200 || report.Received12m != 2 || report.Forecast12m != 2 {
+		t.Errorf("unexpected bot report %s", w.Body.String())
+	}
+	if w := getJSON(r, "/api/portfolio/dividends?currency=GBP", token); w.Code != 400 {
+		t.Errorf("unsupported currency must be rejected, got %d", w.Code)
+	}
+}
+
+func TestCreditBotDividendsForBackfilledTrades(t *testing.T) {
+	setupCorporateActionTest(t)
+
+	ex := time.Now().UTC().AddDate(0, 0, -10).Truncate(24 * time.Hour)
+	// A backfilled trade is executed now, but its signal came before the ex-date
+	db.Create(&LutzTrade{Symbol: "DIVB", Action: "BUY", Quantity: 4, Price: 40, SignalDate: ex.AddDate(0, -1, 0), ExecutedAt: time.Now()})
+	// Signalled on the ex-date: not entitled, whatever the execution time says
+	db.Create(&QuantTrade{Symbol: "DIVB", Action: "BUY", Quantity: 4, Price: 40, SignalDate: ex, ExecutedAt: ex.AddDate(0, -1, 0)})
+
+	created := recordCorporateActions([]CorporateAction{{Symbol: "DIVB", Type: "dividend", ExDate: ex, Amount: 0.5, Source: "manual"}})
+	applyCorporateAction(created[0])
+
+	var botDivs []BotDividend
+	db.Find(&botDivs)
+	if len(botDivs) != 1 || botDivs[0].Bot != "lutz" || botDivs[0].Quantity != 4 || botDivs[0].Amount != 2 {
+		t.Fatalf("expected the backfilled Lutz position to earn 2 USD, got %+v", botDivs)
+	}
+}
+
+func TestDividendCutoffPlaceholdersAndImportReplace(t *testing.T) {
+	setupCorporateActionTest(t)
+	db.AutoMigrate(&Security{}, &Stock{}, &GlobalSetting{})
+	r, token := setupLiveRouter(t)
+	r.POST("/api/portfolio/import", authMiddleware(), importPortfolioTransactions)
+	var admin User
+	db.Where("username = ?", "admin").First(&admin)
+	saveSecurity(&Security{Symbol: "DIVY", ISIN: "US0378331005"})
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	setGlobalSetting(corporateActionsSinceSetting, today.AddDate(0, 0, -20).Format("2006-01-02"))
+	p := defaultPortfolio(admin.ID)
+	addLedgerTransaction(p, &PortfolioTransaction{Symbol: "DIVY", Type: "buy", Date: today.AddDate(0, 0, -100), Quantity: 10, Price: 40, Currency: "EUR"})
+	// A position entered without quantity holds one placeholder share
+	addLedgerTransaction(defaultPortfolio(7), &PortfolioTransaction{Symbol: "DIVY", Type: "buy", Date: today.AddDate(0, 0, -100), Quantity: 1, Price: 40, Currency: "EUR", QuantityUnknown: true})
+
+	created := recordCorporateActions([]CorporateAction{
+		{Symbol: "DIVY", Type: "dividend", ExDate: today.AddDate(0, 0, -60), Amount: 0.5, Source: "yahoo"},
+		{Symbol: "DIVY", Type: "dividend", ExDate: today.AddDate(0, 0, -10), Amount: 0.5, Source: "yahoo"},
+	})
+	if autoCreditable(created[0]) || !autoCreditable(created[1]) {
+		t.Fatal("only ex-dates from the go-live on are credited automatically")
+	}
+	applyCorporateAction(created[1])
+	var txs []PortfolioTransaction
+	db.Where("type = ?", "dividend").Order("date").Find(&txs)
+	if len(txs) != 1 || txs[0].UserID != admin.ID || txs[0].ExternalID != dividendExternalID(created[1].ID) {
+		t.Fatalf("expected only the new dividend for the known quantity, got %+v", txs)
+	}
+
+	// The history before the go-live is booked by an explicit sync
+	if added, _ := creditPortfolioDividends(p, nil); added != 1 {
+		t.Errorf("sync must book the older dividend, added %d", added)
+	}
+	if added, _ := creditPortfolioDividends(defaultPortfolio(7), nil); added != 0 {
+		t.Errorf("a placeholder share must not earn dividends, added %d", added)
+	}
+
+	// The broker export books the recent payment again: it replaces the credited one
+	export := "Datum;Typ;Wert;Notiz;ISIN;Stück;Gebühren;Steuern\n" + today.AddDate(0, 0, -5).Format("2006-01-02") + ";Dividende;4,25;DIVY;US0378331005;;;0,75\n"
+	w := postRaw(r, "/api/portfolio/import", token, []byte(export))
+	var res struct {
+		Imported int `json:"imported"`
+		Replaced int `json:"replaced"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if w.Code != 200 || res.Imported != 1 || res.Replaced != 1 {
+		t.Fatalf("import: %d %s", w.Code, w.Body.String())
+	}
+	txs = nil
+	db.Where("user_id = ? AND type = ?", admin.ID, "dividend").Order("date").Find(&txs)
+	if len(txs) != 2 || txs[0].Source != "corporate_action" || txs[1].Source != "import:traderepublic" || txs[1].Amount != 5 {
+		t.Fatalf("expected the older credit and the imported payment, got %+v", txs)
+	}
+	if added, _ := creditPortfolioDividends(p, nil); added != 0 {
+		t.Errorf("the imported payment must not be credited again, added %d", added)
+	}
+}
+
+func TestBacktestMetricsWithDividends(t *testing.T) {
+	entry, exit := day(2025, 1, 2).Unix(), day(2025, 12, 30).Unix()
+	dividends := []CorporateAction{
+		{ExDate: day(2025, 3, 3), Amount: 1, Source: "yahoo"},
+		{ExDate: day(2025, 9, 1), Amount: 1, Source: "yahoo"},
+		{ExDate: day(2026, 3, 2), Amount: 1, Source: "yahoo"}, // after the exit
+	}
+	trades := withTradeDividends([]ArenaBacktestTrade{
+		{Direction: "LONG", EntryPrice: 100, EntryTime: entry, ExitTime: exit, ReturnPct: 10},
+		{Direction: "SHORT", EntryPrice: 50, EntryTime: entry, ExitTime: day(2025, 6, 1).Unix(), ReturnPct: 4},
+	}, dividends, nil)
+	if trades[0].DividendPct != 2 || trades[1].DividendPct != -2 {
+		t.Fatalf("unexpected dividend returns %+v", trades)
+	}
+	m := recalcMetrics(trades)
+	if m.TotalReturn != 14 || m.TotalReturnWithDividends != 14 || math.Abs(m.NetProfitWithDividends-(1.12*1.02-1)*100) > 1e-9 {
+		t.Errorf("unexpected metrics %+v", m)
+	}
+
+	exitDate := exit
+	perf := []TradeData{{EntryDate: entry, EntryPrice: 100, ExitDate: &exitDate, ReturnPct: 10}, {EntryDate: exit, EntryPrice: 110, IsOpen: true, ReturnPct: 5}}
+	setupCorporateActionTest(t)
+	recordCorporateActions([]CorporateAction{{Symbol: "TRD", Type: "dividend", ExDate: day(2025, 3, 3), Amount: 1.5, Source: "manual"}})
+	if got := totalReturnWithDividends("TRD", perf); !near(got, 11.5) {
+		t.Errorf("expected 11.5%% with dividends, got %v", got)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func postRaw(r *gin.Engine, path, token string, body []byte) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", path, strings.NewReader(string(body)))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func latin1(s string) []byte {
+	var b []byte
+	for _, r := range s {
+		b = append(b, byte(r))
+	}
+	return b
+}
+
+const trExport = `Datum;Typ;Wert;Notiz;ISIN;Stück;Gebühren;Steuern
+2024-01-10;Kauf;-1001,00;Apple Inc.;US0378331005;10;1,00;
+2024-05-16;Dividende;2,10;Apple Inc.;US0378331005;;;0,40
+2024-06-01;Einlage;500,00;;;;;
+`
+
+func TestParseBrokerExports(t *testing.T) {
+	for _, c := range []struct {
+		in      string
+		decimal rune
+		want    float64
+	}{
+		{"1.234,56 €", ',', 1234.56},
+		{"1.000", ',', 1000},
+		{"1.500", ',', 1500},
+		{"-12,5", ',', -12.5},
+		{"-1,234.5", '.', -1234.5},
+		{"1,234", '.', 1234},
+		{"0.25", '.', 0.25},
+	} {
+		if v := parseBrokerNumber(c.in, c.decimal); v != c.want {
+			t.Errorf("%q parsed as %v, want %v", c.in, v, c.want)
+		}
+	}
+
+	broker, rows, err := parseBrokerExport([]byte(trExport), "auto")
+	if err != nil || broker != "traderepublic" || len(rows) != 3 {
+		t.Fatalf("Trade Republic: %s %d %v", broker, len(rows), err)
+	}
+	if tx := rows[0].Transaction; tx.Type != "buy" || tx.Quantity != 10 || tx.Price != 100 || tx.Fees != 1 {
+		t.Errorf("price must exclude fees: %+v", tx)
+	}
+	if tx := rows[1].Transaction; tx.Type != "dividend" || !near(tx.Amount, 2.5) || !near(tx.Taxes, 0.4) {
+		t.Errorf("dividend must be booked gross: %+v", tx)
+	}
+	if rows[2].Status != "skipped" {
+		t.Errorf("deposit must be skipped, got %+v", rows[2])
+	}
+
+	scalable := "date;time;status;reference;description;assetType;type;isin;shares;price;amount;fee;tax;currency\n" +
+		"2024-02-01;10:00:00;Executed;SCAL-1;SAP SE;Security;Savings plan;DE0007164600;2;150,00;-300,99;0,99;0,00;EUR\n" +
+		"2024-02-02;10:00:00;Cancelled;SCAL-2;SAP SE;Security;Buy;DE0007164600;2;150,00;-300,99;0,99;0,00;EUR\n"
+	broker, rows, _ = parseBrokerExport([]byte(scalable), "auto")
+	if broker != "scalable" || len(rows) != 2 || rows[0].Transaction.ExternalID != "scalable:SCAL-1" || rows[0].Transaction.Type != "buy" || rows[1].Status != "skipped" {
+		t.Errorf("Scalable: %s %+v", broker, rows)
+	}
+
+	// comdirect exports are Latin-1 and start with a title block
+	comdirect := latin1("\"Depotumsätze der letzten 90 Tage\";\n\n" +
+		"\"Buchungstag\";\"Geschäftstag\";\"Stück / Nom.\";\"Bezeichnung\";\"WKN\";\"Währung\";\"Ausführungskurs\";\"Umsatz in EUR\";\n" +
+		"\"05.03.2024\";\"01.03.2024\";\"3\";\"SAP SE\";\"716460\";\"EUR\";\"160,00\";\"-484,90\";\n" +
+		"\"10.04.2024\";\"08.04.2024\";\"1\";\"SAP SE\";\"716460\";\"EUR\";\"170,00\";\"165,10\";\n")
+	broker, rows, _ = parseBrokerExport(comdirect, "auto")
+	if broker != "comdirect" || len(rows) != 2 {
+		t.Fatalf("comdirect: %s %+v", broker, rows)
+	}
+	if buy, sell := rows[0].Transaction, rows[1].Transaction; buy.Type != "buy" || !near(buy.Fees, 4.9) || !buy.Date.Equal(day(2024, 3, 1)) || sell.Type != "sell" || !near(sell.Fees, 4.9) {
+		t.Errorf("comdirect trades: %+v %+v", buy, sell)
+	}
+
+	ibkr := `"ClientAccountID","CurrencyPrimary","AssetClass","Symbol","Description","ISIN","TradeDate","Quantity","TradePrice","IBCommission","Buy/Sell","TransactionID"
+"U123","USD","STK","BRK B","BERKSHIRE HATHAWAY INC-CL B","US0846707026","20240105","10","360.5","-1","BUY","111"
+"U123","USD","CASH","EUR.USD","EUR.USD","","20240105","1000","1.09","-2","BUY","112"
+"ClientAccountID","Currency","Symbol","Description","ISIN","SettleDate","Amount","Type","TransactionID"
+"U123","USD","MSFT","MSFT CASH DIV","US5949181045","20240314","7.5","Dividends","201"
+"U123","USD","MSFT","MSFT WHT","US5949181045","20240314","-1.13","Withholding Tax","202"
+`
+	broker, rows, _ = parseBrokerExport([]byte(ibkr), "auto")
+	if broker != "ibkr" || len(rows) != 3 {
+		t.Fatalf("IBKR: %s %+v", broker, rows)
+	}
+	if tx := rows[0].Transaction; tx.Type != "buy" || tx.Quantity != 10 || tx.Fees != 1 || tx.Currency != "USD" || tx.ExternalID != "ibkr:111" {
+		t.Errorf("IBKR trade: %+v", tx)
+	}
+	if rows[1].Status != "skipped" {
+		t.Errorf("forex trades must be skipped: %+v", rows[1])
+	}
+	if tx := rows[2].Transaction; tx.Type != "dividend" || tx.Amount != 7.5 || !near(tx.Taxes, 1.13) {
+		t.Errorf("withholding tax must be attached to the dividend: %+v", tx)
+	}
+
+	if _, _, err := parseBrokerExport([]byte("a,b,c\n1,2,3\n"), "auto"); err == nil {
+		t.Error("unknown format must be rejected")
+	}
+}
+
+func TestPortfolioImport_DryRunAndIdempotent(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&Security{}, &PortfolioPosition{}, &PortfolioTransaction{}, &PortfolioTradeHistory{}, &Stock{})
+	r, token := setupLiveRouter(t)
+	r.POST("/api/portfolio/import", authMiddleware(), importPortfolioTransactions)
+	saveSecurity(&Security{Symbol: "AAPL", Name: "Apple", ISIN: "US0378331005", Exchange: "NASDAQ"})
+
+	type result struct {
+		Rows     []BrokerImportRow `json:"rows"`
+		Summary  map[string]int    `json:"summary"`
+		Imported int               `json:"imported"`
+		Error    string            `json:"error"`
+	}
+	post := func(body, query string) result {
+		w := postRaw(r, "/api/portfolio/import"+query, token, []byte(body))
+		var res result
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return res
+	}
+
+	res := post(trExport, "?dry_run=true")
+	if res.Summary["new"] != 2 || res.Summary["skipped"] != 1 || res.Imported != 0 || res.Rows[0].Transaction.Symbol != "AAPL" {
+		t.Fatalf("unexpected preview %+v", res)
+	}
+	var count int64
+	if db.Model(&PortfolioTransaction{}).Count(&count); count != 0 {
+		t.Fatalf("dry run must not write, found %d transactions", count)
+	}
+
+	if res = post(trExport, ""); res.Imported != 2 {
+		t.Fatalf("import failed: %+v", res)
+	}
+	if res = post(trExport, ""); res.Imported != 0 || res.Summary["duplicate"] != 2 {
+		t.Errorf("re-import must only find duplicates: %+v", res)
+	}
+
+	// The next monthly export overlaps with the first one and adds a partial sell
+	next := trExport + "2024-07-01;Verkauf;949,00;Apple Inc.;US0378331005;5;1,00;50,00\n"
+	if res = post(next, ""); res.Imported != 1 || res.Summary["duplicate"] != 2 {
+		t.Fatalf("overlapping export: %+v", res)
+	}
+	var pos PortfolioPosition
+	db.Where("symbol = ?", "AAPL").First(&pos)
+	if pos.Quantity == nil || *pos.Quantity != 5 {
+		t.Errorf("expected 5 remaining shares, got %+v", pos)
+	}
+	var trade PortfolioTradeHistory
+	db.First(&trade)
+	if !near(trade.SellPrice, 199.8) || !near(trade.ProfitLoss, 5*199.8-5*100.1) {
+		t.Errorf("unexpected realized trade %+v", trade)
+	}
+
+	// A sell without the matching buys is reported and nothing is written
+	res = post("Datum;Typ;Wert;Notiz;ISIN;Stück;Gebühren;Steuern\n2024-08-01;Verkauf;5000,00;Apple;US0378331005;50;0;0\n", "")
+	if res.Error == "" || res.Imported != 0 {
+		t.Errorf("oversell must be rejected: %+v", res)
+	}
+	if db.Model(&PortfolioTransaction{}).Count(&count); count != 3 {
+		t.Errorf("expected 3 transactions, got %d", count)
+	}
+}
+
+func TestPortfolioImport_WKNAndHistoricalRates(t *testing.T) {
+	setupMarketDataTest(t, false)
+	db.AutoMigrate(&Security{}, &PortfolioPosition{}, &PortfolioTransaction{}, &PortfolioTradeHistory{}, &Stock{})
+	calls := 0
+	marketDataRegistry["primary"] = func() MarketDataProvider {
+		return fakeMarketData{name: "primary", calls: &calls, search: []SearchResult{{Symbol: "ALV.DE", Name: "Allianz SE"}}}
+	}
+	resetMarketDataChain()
+	historicalRatesMu.Lock()
+	historicalRates["2024-01-05"] = map[string]float64{"USD": 1, "EUR": 0.9}
+	historicalRatesMu.Unlock()
+	t.Cleanup(func() {
+		historicalRatesMu.Lock()
+		delete(historicalRates, "2024-01-05")
+		historicalRatesMu.Unlock()
+	})
+	withExchangeRates(t, map[string]float64{"EUR": 0.5})
+
+	// A comdirect WKN nobody has set up before is resolved through the provider search
+	sec, err := resolveSecurity("840400")
+	if err != nil || sec.Symbol != "ALV.DE" || sec.WKN != "840400" {
+		t.Fatalf("WKN must resolve through the search, got %+v (%v)", sec, err)
+	}
+	if s, ok := lookupSecurity("ALV.DE"); !ok || s.WKN != "840400" {
+		t.Errorf("resolved WKN must be stored in the master, got %+v", s)
+	}
+
+	p := Portfolio{UserID: 1, Name: "Depot", Currency: "EUR"}
+	saveSecurity(&Security{Symbol: "BRK-B", ISIN: "US0846707026"})
+	rows := prepareBrokerImport(p, "ibkr", []BrokerImportRow{
+		brokerTradeRow(1, "buy", day(2024, 1, 5), "US0846707026", "", 10, 360, 1, 0, "USD"),
+		brokerTradeRow(2, "buy", day(1990, 1, 1), "US0846707026", "", 1, 100, 0, 0, "USD"),
+	}, "EUR")
+	if tx := rows[0].Transaction; rows[0].Status != "new" || tx.Currency != "EUR" || !near(tx.Price, 324) || !near(tx.Fees, 0.9) || rows[0].OriginalCurrency != "USD" {
+		t.Errorf("USD trade must be converted at the rate of its day: %+v", rows[0])
+	}
+	if row := rows[1]; row.Transaction.Currency != "USD" || row.Transaction.Price != 100 || row.Message == "" {
+		t.Errorf("without a rate of the day the trade must keep its currency: %+v", row)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+
+func deleteReq(r *gin.Engine, path, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("DELETE", path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestReplayLedger_FIFOLotsAndSplit(t *testing.T) {
+	txs := []PortfolioTransaction{
+		{ID: 1, Symbol: "AAPL", Type: "buy", Date: day(2024, 1, 10), Quantity: 10, Price: 100, Fees: 10},
+		{ID: 2, Symbol: "AAPL", Type: "buy", Date: day(2024, 3, 5), Quantity: 10, Price: 150},
+		{ID: 3, Symbol: "AAPL", Type: "sell", Date: day(2024, 6, 1), Quantity: 15, Price: 200, Fees: 15},
+		{ID: 4, Symbol: "AAPL", Type: "split", Date: day(2024, 7, 1), Ratio: 4},
+		{ID: 5, Symbol: "AAPL", Type: "dividend", Date: day(2024, 8, 1), Amount: 5, Taxes: 1.3},
+	}
+	state, err := replayLedger(txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The sell consumes the whole first lot and half of the second one
+	if len(state.Realized) != 2 {
+		t.Fatalf("expected two lot matches, got %+v", state.Realized)
+	}
+	first, second := state.Realized[0], state.Realized[1]
+	if first.BuyTransactionID != 1 || first.Quantity != 10 || !near(first.Cost, 1010) || !near(first.Proceeds, 1990) {
+		t.Errorf("unexpected first match %+v", first)
+	}
+	if second.BuyTransactionID != 2 || second.Quantity != 5 || !near(second.Gain, 5*199-5*150) {
+		t.Errorf("unexpected second match %+v", second)
+	}
+
+	// 5 remaining shares become 20 after the 4:1 split, at a quarter of the cost
+	lots := state.Lots["AAPL"]
+	if len(lots) != 1 || !near(lots[0].Quantity, 20) || !near(lots[0].CostPerShare, 37.5) || !lots[0].Date.Equal(day(2024, 3, 5)) {
+		t.Errorf("unexpected open lots %+v", lots)
+	}
+	if len(state.Income) != 1 || state.Income[0].Amount != 5 {
+		t.Errorf("dividend not recorded: %+v", state.Income)
+	}
+
+	if _, err := replayLedger(append(txs, PortfolioTransaction{ID: 6, Symbol: "AAPL", Type: "sell", Date: day(2024, 9, 1), Quantity: 21, Price: 50})); err == nil {
+		t.Error("selling more than the open quantity must fail")
+	}
+
+	// Transfers move lots with their acquisition date and never realize a gain
+	state, _ = replayLedger([]PortfolioTransaction{
+		{ID: 1, Symbol: "SAP.DE", Type: "transfer_in", Date: day(2019, 5, 2), Quantity: 8, Price: 90},
+		{ID: 2, Symbol: "SAP.DE", Type: "transfer_out", Date: day(2024, 1, 2), Quantity: 3},
+	})
+	if len(state.Realized) != 0 || !near(state.Lots["SAP.DE"][0].Quantity, 5) || !state.Lots["SAP.DE"][0].Date.Equal(day(2019, 5, 2)) {
+		t.Errorf("unexpected transfer result %+v", state)
+	}
+}
+
+func TestPortfolioLedger_Endpoints(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&Security{}, &PortfolioPosition{}, &PortfolioTransaction{}, &PortfolioTradeHistory{}, &Stock{})
+	r, token := setupLiveRouter(t)
+	r.POST("/api/portfolio", authMiddleware(), createPortfolioPosition)
+	r.PUT("/api/portfolio/:id", authMiddleware(), updatePortfolioPosition)
+	r.POST("/api/portfolio/:id/sell", authMiddleware(), sellPortfolioPosition)
+	r.GET("/api/portfolio/transactions", authMiddleware(), getPortfolioTransactions)
+	r.POST("/api/portfolio/transactions", authMiddleware(), createPortfolioTransaction)
+	r.PUT("/api/portfolio/transactions/:id", authMiddleware(), updatePortfolioTransaction)
+	r.DELETE("/api/portfolio/transactions/:id", authMiddleware(), deletePortfolioTransaction)
+	r.GET("/api/portfolio/lots", authMiddleware(), getPortfolioLots)
+
+	var user User
+	db.Where("username = ?", "admin").First(&user)
+
+	// A legacy position without ledger is migrated on the first change
+	qty := 4.0
+	db.Create(&PortfolioPosition{UserID: user.ID, Symbol: "MSFT", Name: "Microsoft", AvgPrice: 300, Currency: "USD", Quantity: &qty, PurchaseDate: tptr(day(2023, 2, 1))})
+
+	w := postJSON(r, "/api/portfolio", token, map[string]interface{}{"symbol": "MSFT", "avg_price": 400.0, "quantity": 4.0, "purchase_date": "2024-02-01", "currency": "USD"})
+	var pos PortfolioPosition
+	json.Unmarshal(w.Body.Bytes(), &pos)
+	if w.Code != http.StatusCreated || pos.Quantity == nil || *pos.Quantity != 8 || !near(pos.AvgPrice, 350) {
+		t.Fatalf("second buy must be merged into the position: %d %s", w.Code, w.Body.String())
+	}
+	if w := postJSON(r, "/api/portfolio", token, map[string]interface{}{"symbol": "MSFT", "avg_price": 1.0}); w.Code != http.StatusCreated {
+		t.Fatalf("third buy failed: %d", w.Code)
+	}
+	if w := putJSON(r, fmt.Sprintf("/api/portfolio/%d", pos.ID), token, map[string]interface{}{"avg_price": 1.0}); w.Code != http.StatusConflict {
+		t.Errorf("positions with several buys must be edited per transaction, got %d", w.Code)
+	}
+
+	var txs []PortfolioTransaction
+	json.Unmarshal(getJSON(r, "/api/portfolio/transactions?symbol=MSFT", token).Body.Bytes(), &txs)
+	if len(txs) != 3 || txs[0].Source != "migration" {
+		t.Fatalf("expected migrated buy plus two new buys, got %+v", txs)
+	}
+	if w := deleteReq(r, fmt.Sprintf("/api/portfolio/transactions/%d", txs[2].ID), token); w.Code != http.StatusOK {
+		t.Fatalf("delete failed: %d %s", w.Code, w.Body.String())
+	}
+
+	// Partial sell: 5 shares, FIFO takes the 4 old ones at 300 and 1 at 400
+	w = postJSON(r, fmt.Sprintf("/api/portfolio/%d/sell", pos.ID), token, map[string]interface{}{"sell_price": 450.0, "quantity": 5.0, "date": "2024-06-03"})
+	var sell struct {
+		ProfitLoss float64               `json:"profit_loss"`
+		Trade      PortfolioTradeHistory `json:"trade"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &sell)
+	if w.Code != http.StatusOK || !near(sell.ProfitLoss, 4*150+50) || sell.Trade.Quantity != 5 || !near(sell.Trade.BuyPrice, 320) {
+		t.Fatalf("unexpected sell result: %d %s", w.Code, w.Body.String())
+	}
+	db.First(&pos, pos.ID)
+	if pos.Quantity == nil || *pos.Quantity != 3 || !near(pos.AvgPrice, 400) || !pos.PurchaseDate.Equal(day(2024, 2, 1)) {
+		t.Errorf("remaining position must consist of the newer lot, got %+v", pos)
+	}
+
+	// Correcting the old buy price recomputes the realized gain
+	if w := putJSON(r, fmt.Sprintf("/api/portfolio/transactions/%d", txs[0].ID), token, map[string]interface{}{"symbol": "MSFT", "type": "buy", "date": "2023-02-01", "quantity": 4.0, "price": 350.0, "currency": "USD"}); w.Code != http.StatusOK {
+		t.Fatalf("correction failed: %d %s", w.Code, w.Body.String())
+	}
+	var trade PortfolioTradeHistory
+	db.Where("user_id = ?", user.ID).First(&trade)
+	if !near(trade.ProfitLoss, 4*100+50) {
+		t.Errorf("trade history not recomputed after correction: %+v", trade)
+	}
+
+	// A correction that would oversell is rejected and leaves the ledger untouched
+	if w := putJSON(r, fmt.Sprintf("/api/portfolio/transactions/%d", txs[0].ID), token, map[string]interface{}{"symbol": "MSFT", "type": "buy", "date": "2023-02-01", "quantity": 0.5, "price": 350.0}); w.Code != http.StatusBadRequest {
+		t.Errorf("oversell by correction must fail, got %d", w.Code)
+	}
+	if w := deleteReq(r, fmt.Sprintf("/api/portfolio/transactions/%d", txs[1].ID), token); w.Code != http.StatusBadRequest {
+		t.Errorf("deleting a sold lot must fail, got %d", w.Code)
+	}
+
+	var lots struct {
+		Lots         []TaxLot `json:"lots"`
+		RealizedGain float64  `json:"realized_gain"`
+	}
+	json.Unmarshal(getJSON(r, "/api/portfolio/lots", token).Body.Bytes(), &lots)
+	if len(lots.Lots) != 1 || lots.Lots[0].Quantity != 3 || !near(lots.RealizedGain, 450) {
+		t.Errorf("unexpected lots %+v", lots)
+	}
+}
+
+// withExchangeRates pins the USD rates for a test and keeps the live fetch from replacing them
+func withExchangeRates(t *testing.T, rates map[string]float64) {
+	t.Helper()
+	exchangeRatesMutex.Lock()
+	prevRates, prevFetched := exchangeRatesFromUSD, exchangeRatesLastFetched
+	exchangeRatesFromUSD, exchangeRatesLastFetched = rates, time.Now()
+	exchangeRatesMutex.Unlock()
+	t.Cleanup(func() {
+		exchangeRatesMutex.Lock()
+		exchangeRatesFromUSD, exchangeRatesLastFetched = prevRates, prevFetched
+		exchangeRatesMutex.Unlock()
+	})
+}
+
+func TestPortfolioLedger_LegacyReadsAndLocking(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&Security{}, &PortfolioPosition{}, &PortfolioTransaction{}, &PortfolioTradeHistory{}, &Stock{})
+	migrateLedgerIndexes()
+	withExchangeRates(t, map[string]float64{"USD": 1, "EUR": 0.5})
+	r, token := setupLiveRouter(t)
+	r.GET("/api/portfolio/transactions", authMiddleware(), getPortfolioTransactions)
+	var user User
+	db.Where("username = ?", "admin").First(&user)
+	p := Portfolio{UserID: user.ID}
+
+	// Legacy positions: one without quantity (equal weight), one with
+	qty := 3.0
+	db.Create(&PortfolioPosition{UserID: user.ID, Symbol: "SAP.DE", Name: "SAP", AvgPrice: 100, Currency: "EUR"})
+	db.Create(&PortfolioPosition{UserID: user.ID, Symbol: "AAPL", Name: "Apple", AvgPrice: 100, Currency: "EUR", Quantity: &qty})
+
+	var txs []PortfolioTransaction
+	json.Unmarshal(getJSON(r, "/api/portfolio/transactions", token).Body.Bytes(), &txs)
+	var stored int64
+	db.Model(&PortfolioTransaction{}).Count(&stored)
+	if len(txs) != 2 || stored != 0 {
+		t.Fatalf("reads must show the legacy positions without storing them, got %d listed, %d stored", len(txs), stored)
+	}
+
+	// The first change stores the migration; the position without quantity stays equal-weight
+	if _, err := addLedgerTransaction(p, &PortfolioTransaction{Symbol: "AAPL", Type: "buy", Quantity: 1, Price: 100, Currency: "USD"}); err != nil {
+		t.Fatal(err)
+	}
+	var sap, aapl PortfolioPosition
+	db.Where("symbol = ?", "SAP.DE").First(&sap)
+	db.Where("symbol = ?", "AAPL").First(&aapl)
+	if sap.Quantity != nil {
+		t.Errorf("a position entered without quantity must keep a nil quantity, got %v", *sap.Quantity)
+	}
+	// 3 × 100 EUR plus 1 × 100 USD (= 50 EUR) in the currency of the oldest lot
+	if aapl.Quantity == nil || *aapl.Quantity != 4 || aapl.Currency != "EUR" || !near(aapl.AvgPrice, 87.5) {
+		t.Errorf("mixed-currency lots must be converted before averaging, got %+v", aapl)
+	}
+
+	// Concurrent sells of the whole position: only one may pass
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sold := 0
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := addLedgerTransaction(p, &PortfolioTransaction{Symbol: "AAPL", Type: "sell", Quantity: 4, Price: 120, Currency: "EUR"}); err == nil {
+				mu.Lock()
+				sold++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if sold != 1 {
+		t.Errorf("expected exactly one sell of the position, got %d", sold)
+	}
+
+	// External IDs are unique per portfolio
+	ext := PortfolioTransaction{Symbol: "AAPL", Type: "dividend", Amount: 1, ExternalID: "dividend:1"}
+	if _, err := addLedgerTransaction(p, &ext); err != nil {
+		t.Fatal(err)
+	}
+	dup := PortfolioTransaction{Symbol: "AAPL", Type: "dividend", Amount: 1, ExternalID: "dividend:1"}
+	if _, err := addLedgerTransaction(p, &dup); err == nil {
+		t.Error("a second transaction with the same external ID must be rejected")
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupPortfolioTest(t *testing.T) (*gin.Engine, string) {
+	t.Helper()
+	setupLiveTestDB(t)
+	db.AutoMigrate(&Security{}, &PortfolioPosition{}, &PortfolioTransaction{}, &PortfolioTradeHistory{}, &Stock{},
+		&SavingsPlan{}, &RebalancePlan{}, &AllocationTarget{}, &AllocationSettings{}, &Portfolio{}, &PortfolioShare{})
+	r, token := setupLiveRouter(t)
+	r.GET("/api/portfolios/mine", authMiddleware(), getPortfolios)
+	r.POST("/api/portfolios/mine", authMiddleware(), createPortfolio)
+	r.PUT("/api/portfolios/mine/:id", authMiddleware(), updatePortfolio)
+	r.DELETE("/api/portfolios/mine/:id", authMiddleware(), deletePortfolio)
+	r.GET("/api/portfolio/transactions", authMiddleware(), getPortfolioTransactions)
+	r.POST("/api/portfolio/transactions", authMiddleware(), createPortfolioTransaction)
+	r.GET("/api/portfolios/history/:userId", authMiddleware(), getUserPortfolioHistory)
+	r.GET("/api/ranked", authMiddleware(), func(c *gin.Context) { c.JSON(http.StatusOK, rankingPortfolios(c)) })
+	return r, token
+}
+
+// portfolioTestUser creates a regular user with a session
+func portfolioTestUser(t *testing.T, name string) (User, string) {
+	t.Helper()
+	user := User{Email: name + "@test.com", Username: name, Password: "hashed"}
+	db.Create(&user)
+	token := "test-token-" + name
+	session := DBSession{Token: token, UserID: user.ID, Expiry: time.Now().Add(time.Hour)}
+	db.Create(&session)
+	sessions[token] = Session{UserID: user.ID, Expiry: session.Expiry}
+	return user, token
+}
+
+func TestPortfolios_LedgerPerPortfolio(t *testing.T) {
+	r, token := setupPortfolioTest(t)
+	var admin User
+	db.Where("username = ?", "admin").First(&admin)
+
+	// Rows from before portfolios existed end up in the default portfolio
+	db.Create(&PortfolioTransaction{UserID: admin.ID, Symbol: "OLD", Type: "buy", Date: day(2025, 1, 2), Quantity: 1, Price: 10, Currency: "EUR"})
+	migratePortfolios()
+	def := defaultPortfolio(admin.ID)
+	var old PortfolioTransaction
+	db.Where("symbol = ?", "OLD").First(&old)
+	if !def.IsDefault || def.Name != "Depot" || old.PortfolioID != def.ID {
+		t.Fatalf("expected the legacy transaction in the default portfolio, got %+v / %+v", def, old)
+	}
+
+	if w := postJSON(r, "/api/portfolios/mine", token, map[string]interface{}{"name": "Rente", "currency": "XYZ"}); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown currency must be rejected, got %d", w.Code)
+	}
+	w := postJSON(r, "/api/portfolios/mine", token, map[string]interface{}{"name": "Rente", "currency": "usd"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	var rente Portfolio
+	json.Unmarshal(w.Body.Bytes(), &rente)
+	if rente.Currency != "USD" || rente.Visibility != "private" || rente.IsDefault {
+		t.Fatalf("unexpected portfolio %+v", rente)
+	}
+	path := "/api/portfolio/transactions?portfolio=" + itoa(rente.ID)
+
+	// New transactions take the portfolio currency
+	if w := postJSON(r, path, token, map[string]interface{}{"symbol": "NEW", "type": "buy", "date": "2025-03-03", "quantity": 2, "price": 20}); w.Code != http.StatusOK && w.Code != http.StatusCreated {
+		t.Fatalf("create transaction: %d %s", w.Code, w.Body.String())
+	}
+	var tx PortfolioTransaction
+	db.Where("symbol = ?", "NEW").First(&tx)
+	if tx.PortfolioID != rente.ID || tx.Currency != "USD" {
+		t.Errorf("expected a USD transaction in the new portfolio, got %+v", tx)
+	}
+
+	symbols := func(path string) []string {
+		var txs []PortfolioTransaction
+		json.Unmarshal(getJSON(r, path, token).Body.Bytes(), &txs)
+		var out []string
+		for _, tx := range txs {
+			out = append(out, tx.Symbol)
+		}
+		return out
+	}
+	if got := symbols(path); len(got) != 1 || got[0] != "NEW" {
+		t.Errorf("new portfolio should only hold NEW, got %v", got)
+	}
+	if got := symbols("/api/portfolio/transactions"); len(got) != 1 || got[0] != "OLD" {
+		t.Errorf("default portfolio should only hold OLD, got %v", got)
+	}
+
+	if w := deleteReq(r, "/api/portfolios/mine/"+itoa(def.ID), token); w.Code != http.StatusBadRequest {
+		t.Errorf("the default portfolio must not be deletable, got %d", w.Code)
+	}
+	if w := deleteReq(r, "/api/portfolios/mine/"+itoa(rente.ID), token); w.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", w.Code, w.Body.String())
+	}
+	var count int64
+	db.Model(&PortfolioTransaction{}).Where("portfolio_id = ?", rente.ID).Count(&count)
+	if count != 0 {
+		t.Error("deleting a portfolio must delete its ledger")
+	}
+}
+
+func TestPortfolios_SharingAndVisibility(t *testing.T) {
+	r, adminToken := setupPortfolioTest(t)
+	owner, ownerToken := portfolioTestUser(t, "owner")
+	_, friendToken := portfolioTestUser(t, "friend")
+	_, strangerToken := portfolioTestUser(t, "stranger")
+
+	w := postJSON(r, "/api/portfolios/mine", ownerToken, map[string]interface{}{"name": "Ideen", "shared_with": []string{"friend"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	var ideas Portfolio
+	json.Unmarshal(w.Body.Bytes(), &ideas)
+	if len(ideas.SharedWith) != 1 || ideas.SharedWith[0] != "friend" || ideas.Owner != "owner" {
+		t.Fatalf("unexpected shares %+v", ideas)
+	}
+	path := "/api/portfolio/transactions?portfolio=" + itoa(ideas.ID)
+	postJSON(r, path, ownerToken, map[string]interface{}{"symbol": "IDEA", "type": "buy", "date": "2025-03-03", "quantity": 1, "price": 5})
+
+	// Shared read-only: the friend reads but cannot write, strangers see nothing
+	if w := getJSON(r, path, friendToken); w.Code != http.StatusOK {
+		t.Errorf("shared user must read the portfolio, got %d", w.Code)
+	}
+	if w := postJSON(r, path, friendToken, map[string]interface{}{"symbol": "X", "type": "buy", "date": "2025-03-03", "quantity": 1, "price": 5}); w.Code != http.StatusNotFound {
+		t.Errorf("shared user must not write, got %d", w.Code)
+	}
+	if w := getJSON(r, path, strangerToken); w.Code != http.StatusNotFound {
+		t.Errorf("stranger must not read a private portfolio, got %d", w.Code)
+	}
+	if w := getJSON(r, path, adminToken); w.Code != http.StatusOK {
+		t.Errorf("admin must read every portfolio, got %d", w.Code)
+	}
+
+	var shared struct {
+		Shared []Portfolio `json:"shared"`
+	}
+	json.Unmarshal(getJSON(r, "/api/portfolios/mine", friendToken).Body.Bytes(), &shared)
+	if len(shared.Shared) != 1 || shared.Shared[0].ID != ideas.ID || shared.Shared[0].SharedWith != nil {
+		t.Errorf("expected the shared portfolio without its share list, got %+v", shared.Shared)
+	}
+
+	ranked := func(token string) map[uint]bool {
+		var portfolios []Portfolio
+		json.Unmarshal(getJSON(r, "/api/ranked", token).Body.Bytes(), &portfolios)
+		ids := map[uint]bool{}
+		for _, p := range portfolios {
+			ids[p.ID] = true
+		}
+		return ids
+	}
+	if !ranked(friendToken)[ideas.ID] || ranked(strangerToken)[ideas.ID] {
+		t.Error("the comparison must list shared but not foreign private portfolios")
+	}
+	if w := putJSON(r, "/api/portfolios/mine/"+itoa(ideas.ID), ownerToken, map[string]interface{}{"visibility": "public", "shared_with": []string{}}); w.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", w.Code, w.Body.String())
+	}
+	if !ranked(strangerToken)[ideas.ID] {
+		t.Error("a public portfolio must be listed in the comparison")
+	}
+	if w := getJSON(r, path, friendToken); w.Code != http.StatusOK {
+		t.Errorf("public portfolios are readable by everyone, got %d", w.Code)
+	}
+
+	// The ranking flag of the user follows the default portfolio
+	def := defaultPortfolio(owner.ID)
+	putJSON(r, "/api/portfolios/mine/"+itoa(def.ID), ownerToken, map[string]interface{}{"visibility": "private"})
+	db.First(&owner, owner.ID)
+	if owner.VisibleInRanking {
+		t.Error("a private default portfolio must hide the user from the ranking")
+	}
+	if w := getJSON(r, "/api/portfolios/history/"+itoa(owner.ID), strangerToken); w.Code != http.StatusNotFound {
+		t.Errorf("history of a private portfolio must be hidden, got %d", w.Code)
+	}
+	if w := getJSON(r, "/api/portfolios/history/"+itoa(owner.ID), ownerToken); w.Code != http.StatusOK {
+		t.Errorf("owner must see the history, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPortfolios_TargetsAndTaxReportPerOwner(t *testing.T) {
+	r, ownerToken := setupPortfolioTest(t)
+	db.AutoMigrate(&Category{})
+	r.GET("/api/portfolio/targets", authMiddleware(), getAllocationTargets)
+	r.PUT("/api/portfolio/targets", authMiddleware(), updateAllocationTargets)
+	r.GET("/api/portfolio/tax-report", authMiddleware(), getPortfolioTaxReport)
+	_, friendToken := portfolioTestUser(t, "friend")
+	var owner User
+	db.Where("username = ?", "admin").First(&owner)
+	def := defaultPortfolio(owner.ID)
+
+	create := func(body map[string]interface{}) Portfolio {
+		w := postJSON(r, "/api/portfolios/mine", ownerToken, body)
+		var p Portfolio
+		json.Unmarshal(w.Body.Bytes(), &p)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create: %d %s", w.Code, w.Body.String())
+		}
+		return p
+	}
+	rente := create(map[string]interface{}{"name": "Rente", "shared_with": []string{"friend"}})
+	paper := create(map[string]interface{}{"name": "Muster", "paper": true})
+	if !paper.Paper || rente.Paper {
+		t.Fatalf("unexpected paper flags %+v / %+v", rente, paper)
+	}
+
+	// Every portfolio keeps its own targets and settings
+	if w := putJSON(r, "/api/portfolio/targets", ownerToken, map[string]interface{}{
+		"targets": []map[string]interface{}{{"symbol": "AAA", "weight": 100}},
+	}); w.Code != http.StatusOK {
+		t.Fatalf("saving targets failed: %d %s", w.Code, w.Body.String())
+	}
+	putJSON(r, "/api/portfolio/targets?portfolio="+itoa(rente.ID), ownerToken, map[string]interface{}{
+		"targets":  []map[string]interface{}{{"symbol": "BBB", "weight": 100}},
+		"settings": map[string]interface{}{"threshold": 2},
+	})
+	if w := putJSON(r, "/api/portfolio/targets?portfolio="+itoa(rente.ID), friendToken, map[string]interface{}{}); w.Code != http.StatusNotFound {
+		t.Errorf("a shared user must not change the targets, got %d", w.Code)
+	}
+	allocation := func(id uint) ([]AllocationTarget, AllocationSettings) {
+		var resp struct {
+			Targets  []AllocationTarget `json:"targets"`
+			Settings AllocationSettings `json:"settings"`
+		}
+		json.Unmarshal(getJSON(r, "/api/portfolio/targets?portfolio="+itoa(id), ownerToken).Body.Bytes(), &resp)
+		return resp.Targets, resp.Settings
+	}
+	if targets, settings := allocation(def.ID); len(targets) != 1 || targets[0].Symbol != "AAA" || settings.Threshold != 0 {
+		t.Errorf("unexpected default targets %+v %+v", targets, settings)
+	}
+	if targets, settings := allocation(rente.ID); len(targets) != 1 || targets[0].Symbol != "BBB" || settings.Threshold != 2 {
+		t.Errorf("unexpected retirement targets %+v %+v", targets, settings)
+	}
+	if targets, settings := allocation(paper.ID); len(targets) != 0 || settings.Threshold != 5 {
+		t.Errorf("a portfolio without targets must get the defaults, got %+v %+v", targets, settings)
+	}
+
+	// One allowance and common pots over the owner's portfolios, paper trading stays out
+	trade := func(p Portfolio, symbol string, sell float64) {
+		db.Create(&PortfolioTransaction{UserID: owner.ID, PortfolioID: p.ID, Symbol: symbol, Type: "buy", Date: day(2024, 1, 2), Quantity: 10, Price: 100, Currency: "EUR"})
+		db.Create(&PortfolioTransaction{UserID: owner.ID, PortfolioID: p.ID, Symbol: symbol, Type: "sell", Date: day(2024, 6, 3), Quantity: 10, Price: sell, Currency: "EUR"})
+	}
+	trade(def, "XXX", 200)
+	trade(rente, "YYY", 160)
+	trade(paper, "ZZZ", 300)
+	report := func(id uint, token string) TaxReport {
+		var rep TaxReport
+		w := getJSON(r, "/api/portfolio/tax-report?year=2024&allowance=1000&portfolio="+itoa(id), token)
+		json.Unmarshal(w.Body.Bytes(), &rep)
+		if w.Code != http.StatusOK {
+			t.Fatalf("tax report: %d %s", w.Code, w.Body.String())
+		}
+		return rep
+	}
+	for _, id := range []uint{def.ID, rente.ID} {
+		if rep := report(id, ownerToken); !near(rep.Taxable, 1600) || !near(rep.AllowanceUsed, 1000) || !near(rep.TaxBase, 600) {
+			t.Errorf("portfolio %d: expected one allowance over 1600 EUR, got %+v", id, rep)
+		}
+	}
+	if rep := report(paper.ID, ownerToken); !near(rep.Taxable, 2000) {
+		t.Errorf("a paper portfolio gets a report of its own, got taxable %v", rep.Taxable)
+	}
+	putJSON(r, "/api/portfolios/mine/"+itoa(def.ID), ownerToken, map[string]interface{}{"visibility": "private"})
+	if rep := report(rente.ID, friendToken); !near(rep.Taxable, 600) {
+		t.Errorf("a shared user must only see the shared portfolio, got taxable %v", rep.Taxable)
+	}
+}
+
+func itoa(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
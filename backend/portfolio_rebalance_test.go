@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func uintPtr(v uint) *uint { return &v }
+
+func TestPlanRebalance_SymbolTargets(t *testing.T) {
+	in := rebalanceInput{
+		Holdings: []rebalanceHolding{
+			{Symbol: "AAA", Quantity: 30, Price: 10},
+			{Symbol: "BBB", Quantity: 10, Price: 10},
+			{Symbol: "CCC", Quantity: 10, Price: 10},
+		},
+		Targets: []AllocationTarget{{Symbol: "AAA", Weight: 50}, {Symbol: "BBB", Weight: 50}},
+		Options: RebalanceOptions{Threshold: 5},
+	}
+	p, err := planRebalance(in)
+	if err != nil || !p.NeedsRebalance || !near(p.TotalValue, 500) {
+		t.Fatalf("unexpected proposal %+v, %v", p, err)
+	}
+	trades := map[string]RebalanceTrade{}
+	for _, tr := range p.Trades {
+		trades[tr.Symbol] = tr
+	}
+	if tr := trades["AAA"]; tr.Side != "sell" || tr.Quantity != 5 {
+		t.Errorf("AAA must be sold down to 50%%: %+v", tr)
+	}
+	if tr := trades["CCC"]; tr.Side != "sell" || tr.Quantity != 10 {
+		t.Errorf("CCC has no target and must be sold: %+v", tr)
+	}
+	if tr := trades["BBB"]; tr.Side != "buy" || tr.Quantity != 15 {
+		t.Errorf("BBB must be bought up to 50%%: %+v", tr)
+	}
+	if !near(p.CashRemaining, 0) || !near(p.BuyTotal, p.SellTotal) {
+		t.Errorf("sells must fund the buys: %+v", p)
+	}
+
+	// Within the band nothing happens
+	in.Holdings = []rebalanceHolding{{Symbol: "AAA", Quantity: 26, Price: 10}, {Symbol: "BBB", Quantity: 24, Price: 10}}
+	if p, _ := planRebalance(in); p.NeedsRebalance || len(p.Trades) != 0 {
+		t.Errorf("2%% drift is below the threshold: %+v", p)
+	}
+
+	// A deposit is invested in whole shares, the rest stays as cash
+	in.Options.Cash = 105
+	p, _ = planRebalance(in)
+	if len(p.Trades) != 2 || !near(p.BuyTotal, 100) || !near(p.CashRemaining, 5) {
+		t.Errorf("unexpected deposit plan %+v", p)
+	}
+
+	in.Options.Cash = -1000
+	if _, err := planRebalance(in); err == nil {
+		t.Error("a withdrawal above the portfolio value must fail")
+	}
+}
+
+func TestPlanRebalance_TaxAwareCategory(t *testing.T) {
+	tech := uintPtr(1)
+	in := rebalanceInput{
+		Holdings: []rebalanceHolding{
+			{Symbol: "GAIN", Quantity: 10, Price: 10, CategoryID: tech, Lots: []TaxLot{{Quantity: 10, CostPerShare: 5}}},
+			{Symbol: "LOSS", Quantity: 10, Price: 10, CategoryID: tech, Lots: []TaxLot{{Quantity: 10, CostPerShare: 20}}},
+		},
+		Targets:    []AllocationTarget{{CategoryID: tech, Weight: 50}, {Symbol: "NEW", Weight: 50}},
+		Prices:     map[string]float64{"NEW": 20},
+		Categories: map[uint]string{1: "Tech"},
+		Options:    RebalanceOptions{Threshold: 5, TaxAware: true},
+	}
+	p, err := planRebalance(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Trades) != 2 || p.Trades[0].Symbol != "LOSS" || p.Trades[0].Quantity != 10 || p.Trades[1].Symbol != "NEW" || p.Trades[1].Quantity != 5 {
+		t.Fatalf("tax-aware plan must sell the loss position and buy NEW: %+v", p.Trades)
+	}
+	if !near(p.RealizedGain, -100) || p.TaxEstimate != 0 || p.Buckets[0].Label != "Tech" {
+		t.Errorf("unexpected gain/tax %+v", p)
+	}
+
+	in.Options.TaxAware = false
+	p, _ = planRebalance(in)
+	if len(p.Trades) != 3 || !near(p.RealizedGain, -25) {
+		t.Errorf("pro rata plan must sell both: %+v", p.Trades)
+	}
+}
+
+func TestRebalance_Endpoints(t *testing.T) {
+	setupMarketDataTest(t, false)
+	calls := 0
+	marketDataRegistry["primary"] = func() MarketDataProvider {
+		return fakeMarketData{name: "primary", calls: &calls, quote: map[string]QuoteData{"AAA": {Price: 118}, "BBB": {Price: 51}}}
+	}
+	resetMarketDataChain()
+	db.AutoMigrate(&Security{}, &PortfolioPosition{}, &PortfolioTransaction{}, &PortfolioTradeHistory{}, &Stock{}, &Category{}, &AllocationTarget{}, &AllocationSettings{}, &RebalancePlan{})
+	r, token := setupLiveRouter(t)
+	r.GET("/api/portfolio/targets", authMiddleware(), getAllocationTargets)
+	r.PUT("/api/portfolio/targets", authMiddleware(), updateAllocationTargets)
+	r.GET("/api/portfolio/rebalance/plans", authMiddleware(), getRebalancePlans)
+	r.POST("/api/portfolio/rebalance/plans/:id/execute", authMiddleware(), executeRebalancePlan)
+
+	var user User
+	db.Where("username = ?", "admin").First(&user)
+
+	if w := putJSON(r, "/api/portfolio/targets", token, map[string]interface{}{
+		"targets": []map[string]interface{}{{"symbol": "AAA", "weight": 60}, {"symbol": "BBB", "weight": 30}},
+	}); w.Code != http.StatusBadRequest {
+		t.Errorf("weights below 100%% must be rejected, got %d", w.Code)
+	}
+	w := putJSON(r, "/api/portfolio/targets", token, map[string]interface{}{
+		"targets":  []map[string]interface{}{{"symbol": "aaa", "weight": 60}, {"symbol": "BBB", "weight": 40}},
+		"settings": map[string]interface{}{"threshold": 0, "min_trade": 50, "tax_aware": false},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("saving targets failed: %d %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Targets  []AllocationTarget `json:"targets"`
+		Settings AllocationSettings `json:"settings"`
+	}
+	json.Unmarshal(getJSON(r, "/api/portfolio/targets", token).Body.Bytes(), &resp)
+	if len(resp.Targets) != 2 || resp.Targets[0].Symbol != "AAA" || resp.Settings.Threshold != 0 || resp.Settings.TaxAware || resp.Settings.MinTrade != 50 {
+		t.Errorf("unexpected targets %+v", resp)
+	}
+
+	// A plan whose prices moved too far must be computed again
+	db.Create(&PortfolioTransaction{UserID: user.ID, Symbol: "AAA", Type: "buy", Date: day(2025, 1, 2), Quantity: 10, Price: 100, Currency: "USD"})
+	stale, _ := json.Marshal([]RebalanceTrade{{Symbol: "AAA", Side: "sell", Quantity: 4, Price: 150}})
+	stalePlan := RebalancePlan{UserID: user.ID, Status: "draft", TradesJSON: string(stale)}
+	db.Create(&stalePlan)
+	if w := postJSON(r, "/api/portfolio/rebalance/plans/"+fmt.Sprint(stalePlan.ID)+"/execute", token, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("a stale plan must be rejected, got %d", w.Code)
+	}
+	if db.First(&stalePlan, stalePlan.ID); stalePlan.Status != "draft" {
+		t.Errorf("a rejected plan must stay a draft, got %s", stalePlan.Status)
+	}
+
+	// A saved plan is booked into the ledger at the current prices, in the currency the symbol is kept in
+	trades, _ := json.Marshal([]RebalanceTrade{
+		{Symbol: "BBB", Side: "buy", Quantity: 3, Price: 50},
+		{Symbol: "AAA", Side: "sell", Quantity: 4, Price: 120},
+	})
+	plan := RebalancePlan{UserID: user.ID, Status: "draft", TradesJSON: string(trades)}
+	db.Create(&plan)
+	if w := postJSON(r, "/api/portfolio/rebalance/plans/"+fmt.Sprint(plan.ID)+"/execute", token, nil); w.Code != http.StatusOK {
+		t.Fatalf("execute failed: %d %s", w.Code, w.Body.String())
+	}
+	var txs []PortfolioTransaction
+	db.Where("user_id = ? AND source = ?", user.ID, "rebalance").Order("id").Find(&txs)
+	if len(txs) != 2 || txs[0].Type != "sell" || txs[0].Quantity != 4 || txs[0].Price != 118 || txs[1].Symbol != "BBB" || txs[1].Price != 51 {
+		t.Fatalf("unexpected ledger transactions %+v", txs)
+	}
+	var pos PortfolioPosition
+	if db.Where("user_id = ? AND symbol = ?", user.ID, "AAA").First(&pos).Error != nil || pos.Quantity == nil || *pos.Quantity != 6 {
+		t.Errorf("positions must be rebuilt from the ledger: %+v", pos)
+	}
+	if w := postJSON(r, "/api/portfolio/rebalance/plans/"+fmt.Sprint(plan.ID)+"/execute", token, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("a plan runs only once, got %d", w.Code)
+	}
+
+	// Concurrent submits of one plan book its trades once
+	again, _ := json.Marshal([]RebalanceTrade{{Symbol: "BBB", Side: "buy", Quantity: 1, Price: 50}})
+	plan = RebalancePlan{UserID: user.ID, Status: "draft", TradesJSON: string(again)}
+	db.Create(&plan)
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = postJSON(r, "/api/portfolio/rebalance/plans/"+fmt.Sprint(plan.ID)+"/execute", token, nil).Code
+		}(i)
+	}
+	wg.Wait()
+	var count int64
+	db.Model(&PortfolioTransaction{}).Where("user_id = ? AND source = ?", user.ID, "rebalance").Count(&count)
+	if count != 3 || codes[0]+codes[1] != http.StatusOK+http.StatusBadRequest {
+		t.Errorf("expected one booking for two submits, got %d transactions and codes %v", count, codes)
+	}
+}
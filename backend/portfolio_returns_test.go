@@ -0,0 +1,135 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func closes(points map[time.Time]float64) []OHLCV {
+	bars := make([]OHLCV, 0, len(points))
+	for d, c := range points {
+		bars = append(bars, OHLCV{Time: d.Add(14 * time.Hour).Unix(), Close: c})
+	}
+	return bars
+}
+
+func TestValueSeries_TWRIgnoresDeposits(t *testing.T) {
+	bars := map[string][]OHLCV{"AAA": closes(map[time.Time]float64{
+		day(2026, 1, 5): 100, day(2026, 1, 6): 110, day(2026, 1, 7): 110, day(2026, 1, 8): 99,
+	})}
+	txs := []PortfolioTransaction{
+		{ID: 1, Symbol: "AAA", Type: "buy", Date: day(2026, 1, 5), Quantity: 10, Price: 100, Currency: "USD"},
+		{ID: 2, Symbol: "AAA", Type: "buy", Date: day(2026, 1, 7), Quantity: 10, Price: 110, Currency: "USD"},
+	}
+
+	series := valueSeriesFromBars(txs, day(2026, 1, 5), bars)
+	if len(series) != 5 || series[0].Value != 0 || series[1].Flow != 1000 || series[3].Flow != 1100 || series[4].Value != 1980 {
+		t.Fatalf("unexpected series %+v", series)
+	}
+
+	// The stock went 100 -> 99, the second deposit came right before the drop
+	r, ok := windowReturn(series, day(2026, 1, 5))
+	if !ok || math.Abs(r.TWR-(-1)) > 1e-9 {
+		t.Fatalf("expected TWR -1%%, got %+v", r)
+	}
+	if r.MWR > -7 || r.MWR < -10 || r.Flows != 1100 {
+		t.Errorf("money-weighted return must reflect the bad timing, got %+v", r)
+	}
+
+	// Holdings from before the window are the starting capital, not a deposit
+	r, _ = windowReturn(valueSeriesFromBars(txs, day(2026, 1, 6), bars), day(2026, 1, 6))
+	if math.Abs(r.TWR-(-10)) > 1e-9 {
+		t.Errorf("expected -10%% from the 2026-01-06 close, got %v", r.TWR)
+	}
+	if r2, _ := windowReturn(series, day(2026, 1, 6)); math.Abs(r2.TWR-r.TWR) > 1e-9 {
+		t.Errorf("window of the full series must match, got %v and %v", r2.TWR, r.TWR)
+	}
+}
+
+func TestValueSeries_SellsAndDividendsAreWithdrawals(t *testing.T) {
+	bars := map[string][]OHLCV{"AAA": closes(map[time.Time]float64{
+		day(2026, 2, 2): 50, day(2026, 2, 3): 60, day(2026, 2, 4): 60,
+	})}
+	txs := []PortfolioTransaction{
+		{ID: 1, Symbol: "AAA", Type: "buy", Date: day(2026, 2, 2), Quantity: 20, Price: 50, Fees: 5, Currency: "USD"},
+		{ID: 2, Symbol: "AAA", Type: "sell", Date: day(2026, 2, 3), Quantity: 10, Price: 60, Fees: 5, Currency: "USD"},
+		{ID: 3, Symbol: "AAA", Type: "dividend", Date: day(2026, 2, 4), Amount: 12, Taxes: 2, Currency: "USD"},
+	}
+	series := valueSeriesFromBars(txs, day(2026, 2, 2), bars)
+	if series[2].Flow != -595 || series[2].Value != 600 || series[3].Flow != -10 {
+		t.Fatalf("unexpected flows %+v", series)
+	}
+	twr := cumulativeTWR(series)
+	// Day 1 loses the buy fee, day 2 gains 20%, day 3 earns the dividend
+	want := (1000.0/1005)*(1+(600-1000+595)/1000.0)*(1+10/600.0) - 1
+	if math.Abs(twr[3]-want) > 1e-9 {
+		t.Errorf("expected TWR %v, got %v", want, twr[3])
+	}
+}
+
+func TestXIRR(t *testing.T) {
+	rate, ok := xirr([]CashFlow{
+		{Date: day(2025, 1, 1), Amount: -1000},
+		{Date: day(2026, 1, 1), Amount: 1100},
+	})
+	if !ok || math.Abs(rate-0.1) > 1e-6 {
+		t.Errorf("expected 10%%, got %v %v", rate, ok)
+	}
+
+	// Half a year later money is added, the total doubles after a year
+	rate, ok = xirr([]CashFlow{
+		{Date: day(2025, 1, 1), Amount: -1000},
+		{Date: day(2025, 7, 2), Amount: -1000},
+		{Date: day(2026, 1, 1), Amount: 2500},
+	})
+	if !ok || rate < 0.25 || rate > 0.40 {
+		t.Errorf("unexpected rate %v", rate)
+	}
+
+	if _, ok := xirr([]CashFlow{{Date: day(2025, 1, 1), Amount: -1000}, {Date: day(2026, 1, 1), Amount: -5}}); ok {
+		t.Error("flows without a payout have no rate")
+	}
+}
+
+func TestCachedValueSeries_ReusedUntilLedgerChanges(t *testing.T) {
+	from := earningsDay(time.Now()).AddDate(0, 0, -10)
+	key := "CACHE:" + historyRangeFor(from)
+	setBars := func(close float64) {
+		points := map[time.Time]float64{}
+		for d := from; !d.After(from.AddDate(0, 0, 5)); d = d.AddDate(0, 0, 1) {
+			points[d] = close
+		}
+		histCacheMu.Lock()
+		histCache[key] = histCacheEntry{Data: closes(points), FetchedAt: time.Now()}
+		histCacheMu.Unlock()
+	}
+	t.Cleanup(func() {
+		histCacheMu.Lock()
+		delete(histCache, key)
+		histCacheMu.Unlock()
+		portfolioSeriesMu.Lock()
+		portfolioSeriesCache = map[string]portfolioSeriesEntry{}
+		portfolioSeriesMu.Unlock()
+	})
+
+	p := Portfolio{ID: 7, UserID: 3}
+	txs := []PortfolioTransaction{{ID: 1, Symbol: "CACHE", Type: "buy", Date: from, Quantity: 2, Price: 10, Currency: "USD"}}
+	setBars(10)
+	first := cachedValueSeries(p, txs, from)
+	if len(first) == 0 || first[len(first)-1].Value != 20 {
+		t.Fatalf("unexpected series %+v", first)
+	}
+
+	// A second request within the TTL does not fetch the history again
+	setBars(30)
+	if again := cachedValueSeries(p, txs, from); again[len(again)-1].Value != 20 {
+		t.Errorf("series must come from the cache, got %+v", again[len(again)-1])
+	}
+
+	// A new transaction changes the fingerprint
+	txs = append(txs, PortfolioTransaction{ID: 2, Symbol: "CACHE", Type: "buy", Date: from.AddDate(0, 0, 1), Quantity: 1, Price: 30, Currency: "USD"})
+	if changed := cachedValueSeries(p, txs, from); changed[len(changed)-1].Value != 90 {
+		t.Errorf("changed ledger must be replayed, got %+v", changed[len(changed)-1])
+	}
+}
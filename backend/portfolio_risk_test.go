@@ -0,0 +1,86 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeRisk(t *testing.T) {
+	a, b, c := map[time.Time]float64{}, map[time.Time]float64{}, map[time.Time]float64{}
+	pa, pc := 100.0, 50.0
+	for i := 0; i < 40; i++ {
+		d := day(2026, 1, 1).AddDate(0, 0, i)
+		if i > 0 {
+			r := 0.02 * math.Sin(float64(i))
+			pa *= 1 + r
+			pc *= 1 - r
+		}
+		a[d], b[d], c[d] = pa, 2*pa, pc
+	}
+	positions := []RiskPosition{
+		{Symbol: "AAA", Quantity: 10, Sector: "Technology", Country: "United States"},
+		{Symbol: "BBB", Quantity: 5, Sector: "Technology", Country: "United States"},
+		{Symbol: "CCC", Quantity: 10, Country: "Germany"},
+		{Symbol: "DDD", Quantity: 3},
+	}
+	report := analyzeRisk(positions, map[string][]OHLCV{"AAA": closes(a), "BBB": closes(b), "CCC": closes(c)})
+
+	if len(report.Positions) != 3 || len(report.MissingData) != 1 || report.MissingData[0] != "DDD" {
+		t.Fatalf("DDD has no closes and must be reported as missing: %+v", report)
+	}
+	if !near(report.TotalValue, 10*pa+10*pa+10*pc) || report.Days != 39 {
+		t.Errorf("unexpected value %v / days %d", report.TotalValue, report.Days)
+	}
+	sum := 0.0
+	for _, p := range report.Positions {
+		sum += p.Weight
+	}
+	if !near(sum, 100) || report.Sectors[0].Name != "Technology" || report.Sectors[1].Name != riskUnknownBucket {
+		t.Errorf("unexpected weights %+v / sectors %+v", report.Positions, report.Sectors)
+	}
+
+	index := map[string]int{}
+	for i, s := range report.Symbols {
+		index[s] = i
+	}
+	if !near(report.Correlation[index["AAA"]][index["BBB"]], 1) || !near(report.Correlation[index["AAA"]][index["CCC"]], -1) {
+		t.Errorf("unexpected correlation matrix %v", report.Correlation)
+	}
+	if len(report.CorrelatedPairs) != 1 || report.CorrelatedPairs[0].A == "CCC" || report.CorrelatedPairs[0].B == "CCC" {
+		t.Errorf("only AAA/BBB move together: %+v", report.CorrelatedPairs)
+	}
+	if report.DiversificationRatio <= 1 {
+		t.Errorf("the hedge must diversify, got ratio %v", report.DiversificationRatio)
+	}
+	if report.MaxDrawdown >= 0 || report.HHI <= 0 || report.EffectivePositions < 1 {
+		t.Errorf("unexpected drawdown/concentration %+v", report)
+	}
+
+	if len(report.VaR) != 4 {
+		t.Fatalf("expected historical and parametric VaR at 95 and 99%%, got %+v", report.VaR)
+	}
+	for _, v := range report.VaR {
+		if v.CVaRPct < v.VaRPct-1e-9 || !near(v.VaR, v.VaRPct/100*report.TotalValue) {
+			t.Errorf("CVaR must not be below VaR: %+v", v)
+		}
+	}
+	if report.VaR[2].VaRPct < report.VaR[0].VaRPct {
+		t.Errorf("99%% VaR must not be below 95%%: %+v", report.VaR)
+	}
+}
+
+func TestValueAtRisk_Historical(t *testing.T) {
+	returns := make([]float64, 100)
+	for i := range returns {
+		returns[i] = float64(i-50) / 1000 // -5% .. +4.9%
+	}
+	v := valueAtRisk(returns, 10000)
+	// 5 worst days: -5%, -4.9%, -4.8%, -4.7%, -4.6%
+	if v[0].Method != "historical" || !near(v[0].VaRPct, 4.6) || !near(v[0].CVaRPct, 4.8) || !near(v[0].VaR, 460) {
+		t.Errorf("unexpected 95%% VaR %+v", v[0])
+	}
+	if !near(v[2].VaRPct, 5) || !near(v[2].CVaRPct, 5) {
+		t.Errorf("unexpected 99%% VaR %+v", v[2])
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTaxReport_PotsCarryForwardAndChurchTax(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&Security{})
+	saveSecurity(&Security{Symbol: "EUNL.DE", Name: "iShares Core MSCI World UCITS ETF", AssetType: "etf"})
+	saveSecurity(&Security{Symbol: "SAP.DE", AssetType: "stock"})
+	saveSecurity(&Security{Symbol: "ALV.DE", AssetType: "stock"})
+
+	txs := []PortfolioTransaction{
+		// 2023: stock loss of 2000 is carried forward
+		{ID: 1, Symbol: "SAP.DE", Type: "buy", Date: day(2023, 1, 2), Quantity: 10, Price: 300, Currency: "EUR"},
+		{ID: 2, Symbol: "SAP.DE", Type: "sell", Date: day(2023, 6, 1), Quantity: 10, Price: 100, Currency: "EUR"},
+		// 2024: stock gain 5000, ETF gain 1000 (30 % Teilfreistellung), dividend with 15 % withholding
+		{ID: 3, Symbol: "ALV.DE", Type: "buy", Date: day(2024, 1, 2), Quantity: 10, Price: 200, Currency: "EUR"},
+		{ID: 4, Symbol: "ALV.DE", Type: "sell", Date: day(2024, 5, 2), Quantity: 10, Price: 700, Currency: "EUR"},
+		{ID: 5, Symbol: "EUNL.DE", Type: "buy", Date: day(2024, 1, 2), Quantity: 10, Price: 80, Currency: "EUR"},
+		{ID: 6, Symbol: "EUNL.DE", Type: "sell", Date: day(2024, 7, 1), Quantity: 10, Price: 180, Currency: "EUR"},
+		{ID: 7, Symbol: "ALV.DE", Type: "dividend", Date: day(2024, 5, 1), Amount: 200, Taxes: 40, Currency: "EUR"},
+	}
+	state, err := replayLedger(txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := TaxSettings{Allowance: 1000, PartialExemption: 0.3}
+
+	r2023 := buildTaxReport(state, 2023, settings)
+	if r2023.EquityLosses != 2000 || r2023.CarryOutEquity != 2000 || r2023.TotalTax != 0 {
+		t.Errorf("2023: %+v", r2023)
+	}
+
+	r := buildTaxReport(state, 2024, settings)
+	if r.CarryInEquity != 2000 || r.EquityGains != 5000 || !near(r.OtherGains, 700) || r.Dividends != 200 {
+		t.Fatalf("2024 pots: %+v", r)
+	}
+	// 3000 + 900 - 1000 allowance; 30 of 40 withholding creditable (15 % of 200)
+	if !near(r.TaxBase, 2900) || !near(r.CreditableWithholding, 30) || !near(r.Kapitalertragsteuer, 2900.0/4-30) {
+		t.Errorf("2024 tax: base %v credit %v KapESt %v", r.TaxBase, r.CreditableWithholding, r.Kapitalertragsteuer)
+	}
+	if !near(r.Soli, r.Kapitalertragsteuer*0.055) || len(r.Items) != 3 {
+		t.Errorf("2024 soli/items: %+v", r)
+	}
+
+	// Church tax lowers the KapESt: (e - 4q) / (4 + k)
+	settings.ChurchTaxRate = 0.09
+	r = buildTaxReport(state, 2024, settings)
+	want := (2900.0 - 4*30) / 4.09
+	if !near(r.Kapitalertragsteuer, want) || !near(r.Kirchensteuer, want*0.09) {
+		t.Errorf("church tax: KapESt %v church %v", r.Kapitalertragsteuer, r.Kirchensteuer)
+	}
+
+	// Stock losses never offset dividends, general losses offset stock gains
+	r = settleTaxYear(2025, []TaxReportItem{
+		{Type: "sale", Pot: "equity", Gain: -500, Taxable: -500},
+		{Type: "dividend", Pot: "other", Gain: 1500, Taxable: 1500, Proceeds: 1500},
+	}, 0, 0, TaxSettings{})
+	if r.Taxable != 1500 || r.CarryOutEquity != 500 {
+		t.Errorf("stock loss must be carried forward: %+v", r)
+	}
+	r = settleTaxYear(2025, []TaxReportItem{
+		{Type: "sale", Pot: "equity", Gain: 1000, Taxable: 1000},
+		{Type: "sale", Pot: "other", Gain: -400, Taxable: -400},
+	}, 0, 0, TaxSettings{})
+	if r.Taxable != 600 || r.CarryOutOther != 0 {
+		t.Errorf("general loss must offset stock gains: %+v", r)
+	}
+
+	if got := formatEuro(-1234567.891); got != "-1.234.567,89" {
+		t.Errorf("formatEuro: %s", got)
+	}
+}
+
+func TestTaxReport_AssetTypesAndWithholdingCap(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&Security{})
+	// Invesco Ltd. is a stock despite the fund company name, the property fund has no fund word
+	saveSecurity(&Security{Symbol: "IVZ", Name: "Invesco Ltd.", AssetType: "stock"})
+	saveSecurity(&Security{Symbol: "PROP.DE", Name: "Grundbesitz Europa", AssetType: "fund"})
+
+	txs := []PortfolioTransaction{
+		{ID: 1, Symbol: "IVZ", Type: "buy", Date: day(2024, 1, 2), Quantity: 10, Price: 10, Currency: "EUR"},
+		{ID: 2, Symbol: "IVZ", Type: "sell", Date: day(2024, 3, 1), Quantity: 10, Price: 20, Currency: "EUR"},
+		{ID: 3, Symbol: "PROP.DE", Type: "buy", Date: day(2024, 1, 2), Quantity: 10, Price: 50, Currency: "EUR"},
+		{ID: 4, Symbol: "PROP.DE", Type: "dividend", Date: day(2024, 6, 1), Amount: 100, Taxes: 30, Currency: "EUR"},
+		{ID: 5, Symbol: "XYZ1", Type: "buy", Date: day(2024, 1, 2), Quantity: 1, Price: 10, Currency: "EUR"},
+		{ID: 6, Symbol: "XYZ1", Type: "sell", Date: day(2024, 2, 1), Quantity: 1, Price: 20, Currency: "EUR"},
+	}
+	state, err := replayLedger(txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := buildTaxReport(state, 2024, TaxSettings{PartialExemption: 0.6})
+	if r.EquityGains != 110 || r.OtherGains != 0 || !near(r.Dividends, 40) {
+		t.Fatalf("pots by asset type: %+v", r)
+	}
+	// 15 % of 100 would be 15, but the German tax on the 40 taxable is only 10
+	if !near(r.CreditableWithholding, 10) || r.WithholdingTax != 30 {
+		t.Errorf("withholding credit must be capped per dividend: %v of %v", r.CreditableWithholding, r.WithholdingTax)
+	}
+	if !strings.Contains(strings.Join(r.Notes, "\n"), "Wertpapierart unbekannt, als Aktie angesetzt: XYZ1") {
+		t.Errorf("unknown asset type must be noted: %v", r.Notes)
+	}
+}
+
+func TestBotTaxReport_Formats(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&Security{}, &FlipperBotTrade{})
+	r, token := setupLiveRouter(t)
+	r.GET("/api/portfolios/tax-report/:bot", authMiddleware(), getBotTaxReport)
+
+	now := time.Now()
+	bought := now.AddDate(0, 0, -10)
+	// Dollar fell from 0.9 to 0.8 EUR between buy and sale
+	historicalRatesMu.Lock()
+	for _, d := range []time.Time{bought, bought.UTC(), now, now.UTC()} {
+		rate := 0.8
+		if d.Before(now.AddDate(0, 0, -1)) {
+			rate = 0.9
+		}
+		historicalRates[d.Format("2006-01-02")] = map[string]float64{"USD": 1, "EUR": rate}
+	}
+	historicalRatesMu.Unlock()
+	t.Cleanup(func() {
+		historicalRatesMu.Lock()
+		for _, d := range []time.Time{bought, bought.UTC(), now, now.UTC()} {
+			delete(historicalRates, d.Format("2006-01-02"))
+		}
+		historicalRatesMu.Unlock()
+	})
+	// Backfilled buy: executed just now, signalled ten days ago
+	db.Create(&FlipperBotTrade{Symbol: "AAPL", Name: "Apple", Action: "BUY", Quantity: 2, Price: 100, SignalDate: bought, ExecutedAt: now})
+	db.Create(&FlipperBotTrade{Symbol: "AAPL", Name: "Apple", Action: "SELL", Quantity: 2, Price: 150, SignalDate: now, ExecutedAt: now})
+	db.Create(&FlipperBotTrade{Symbol: "MSFT", Name: "Microsoft", Action: "SELL", Quantity: 1, Price: 400, SignalDate: now, ExecutedAt: now})
+	db.Create(&FlipperBotTrade{Symbol: "NVDA", Name: "Nvidia", Action: "BUY", Quantity: 1, Price: 1, SignalDate: now, ExecutedAt: now, IsDeleted: true})
+
+	var report TaxReport
+	w := getJSON(r, "/api/portfolios/tax-report/flipper", token)
+	json.Unmarshal(w.Body.Bytes(), &report)
+	if w.Code != http.StatusOK || report.Owner != "FlipperBot" || len(report.Items) != 1 {
+		t.Fatalf("bot report: %d %s", w.Code, w.Body.String())
+	}
+	// 300 × 0.8 − 200 × 0.9: the currency loss reduces the gain
+	if it := report.Items[0]; !near(it.Cost, 180) || !near(it.Proceeds, 240) || !near(it.Gain, 60) || !near(report.EquityGains, 60) {
+		t.Errorf("USD amounts must be converted at the rates of their days: %+v", it)
+	}
+	if it := report.Items[0]; it.BuyDate == nil || it.BuyDate.After(now.AddDate(0, 0, -9)) {
+		t.Errorf("the backfilled buy must be dated by its signal: %+v", it)
+	}
+	for _, n := range report.Notes {
+		if strings.Contains(n, "aktuellen Kurs") {
+			t.Errorf("no fallback note expected with known rates: %v", report.Notes)
+		}
+	}
+
+	w = getJSON(r, "/api/portfolios/tax-report/flipper?format=csv", token)
+	if body := w.Body.String(); !strings.Contains(body, "Kapitalertragsteuer;") || !strings.Contains(body, "AAPL;Apple;Aktien") {
+		t.Errorf("unexpected CSV:\n%s", body)
+	}
+	w = getJSON(r, "/api/portfolios/tax-report/flipper?format=pdf", token)
+	if body := w.Body.String(); w.Header().Get("Content-Type") != "application/pdf" || !strings.HasPrefix(body, "%PDF-1.4") || !strings.HasSuffix(body, "%%EOF\n") {
+		t.Errorf("invalid PDF response")
+	}
+	if w := getJSON(r, "/api/portfolios/tax-report/unknown", token); w.Code != http.StatusNotFound {
+		t.Errorf("unknown bot must return 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+func TestSavingsPlanDates(t *testing.T) {
+	end := day(2026, 6, 30)
+	plan := SavingsPlan{Interval: "monthly", ExecutionDay: 15, StartDate: day(2026, 1, 20), EndDate: &end}
+	dates := savingsPlanDates(plan, day(2026, 12, 31))
+	if len(dates) != 5 || !dates[0].Equal(day(2026, 2, 15)) || !dates[4].Equal(day(2026, 6, 15)) {
+		t.Errorf("monthly plan must start with the first execution day after the start: %v", dates)
+	}
+
+	plan.Interval, plan.ExecutionDay = "quarterly", 1
+	if dates := savingsPlanDates(plan, day(2026, 12, 31)); len(dates) != 2 || !dates[1].Equal(day(2026, 5, 1)) {
+		t.Errorf("unexpected quarterly dates %v", dates)
+	}
+
+	plan.Interval, plan.EndDate = "biweekly", nil
+	if dates := savingsPlanDates(plan, day(2026, 2, 17)); len(dates) != 3 || !dates[2].Equal(day(2026, 2, 17)) {
+		t.Errorf("unexpected biweekly dates %v", dates)
+	}
+}
+
+func TestSavingsPlanFill(t *testing.T) {
+	bars := closes(map[time.Time]float64{day(2026, 10, 14): 9, day(2026, 10, 16): 10, day(2026, 10, 19): 12})
+	sortBarsByTime(bars)
+	ny := calendarNYSE.location()
+	at := func(d, hour int) time.Time { return time.Date(2026, 10, d, hour, 0, 0, 0, ny) }
+	cases := []struct {
+		date  time.Time
+		now   time.Time
+		price float64
+		day   time.Time
+	}{
+		{day(2026, 10, 16), at(16, 12), 0, time.Time{}},        // session still running
+		{day(2026, 10, 16), at(16, 17), 10, day(2026, 10, 16)}, // after the close
+		{day(2026, 10, 17), at(19, 12), 0, time.Time{}},        // weekend waits for Monday's close
+		{day(2026, 10, 17), at(19, 17), 12, day(2026, 10, 19)}, // not Friday's close
+		{day(2026, 10, 15), at(19, 17), 10, day(2026, 10, 16)}, // gap in the data: next close
+		{day(2026, 10, 20), at(20, 17), 0, time.Time{}},        // no bar yet
+		{day(2026, 10, 1), at(19, 17), 0, time.Time{}},         // next close too far away
+	}
+	for _, c := range cases {
+		price, d, ok := savingsPlanFill(bars, c.date, c.now, calendarNYSE)
+		if price != c.price || !d.Equal(c.day) || ok != (c.price > 0) {
+			t.Errorf("fill(%s at %s) = %v %s %v, want %v %s", c.date.Format("2006-01-02"), c.now.Format(time.RFC3339), price, d.Format("2006-01-02"), ok, c.price, c.day.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestBacktestSavingsPlan(t *testing.T) {
+	bars := closes(map[time.Time]float64{
+		day(2026, 1, 2): 10, day(2026, 2, 2): 20, day(2026, 3, 2): 5, day(2026, 3, 31): 10,
+	})
+	sortBarsByTime(bars)
+	dates := []time.Time{day(2026, 1, 1), day(2026, 2, 1), day(2026, 3, 1)}
+
+	// Weekend dates buy at the next close
+	plain := backtestSavingsPlan("plain", bars, dates, 100, 0, func(time.Time) bool { return true })
+	if plain.Executions != 3 || !near(plain.Shares, 10+5+20) || !near(plain.FinalValue, 350) || !near(plain.ReturnPct, 350.0/300*100-100) {
+		t.Fatalf("unexpected plain run %+v", plain)
+	}
+	if plain.XIRR <= 0 || len(plain.Series) != 3 || !near(plain.Series[1].Value, 300) {
+		t.Errorf("unexpected rate or series %+v", plain)
+	}
+
+	// Money waits while the signal is not BUY and is invested in one go afterwards
+	gated := backtestSavingsPlan("bxtrender", bars, dates, 100, 1, tradesInPosition([]ServerTrade{
+		{Type: "BUY", Time: day(2025, 12, 1).Unix()},
+		{Type: "SELL", Time: day(2026, 2, 1).Unix()},
+		{Type: "BUY", Time: day(2026, 3, 1).Unix()},
+	}))
+	if gated.Executions != 2 || gated.Skipped != 1 || !near(gated.Shares, 9.9+199.0/5) || gated.Cash != 0 {
+		t.Fatalf("unexpected gated run %+v", gated)
+	}
+	if !near(gated.Invested, 300) || !near(gated.Series[1].Value, 9.9*20+100) {
+		t.Errorf("skipped money must be kept as cash %+v", gated.Series)
+	}
+}
+
+func sortBarsByTime(bars []OHLCV) {
+	sort.Slice(bars, func(i, j int) bool { return bars[i].Time < bars[j].Time })
+}
+
+func TestSavingsPlan_Endpoints(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&Security{}, &PortfolioPosition{}, &PortfolioTransaction{}, &PortfolioTradeHistory{}, &Stock{}, &SavingsPlan{})
+	r, token := setupLiveRouter(t)
+	r.GET("/api/portfolio/savings-plans", authMiddleware(), getSavingsPlans)
+	r.POST("/api/portfolio/savings-plans", authMiddleware(), createSavingsPlan)
+	r.POST("/api/portfolio/savings-plans/:id/run", authMiddleware(), runSavingsPlan)
+
+	// Daily closes of 40 USD for the last year so no price has to be fetched; today's session is
+	// still running, so executions due today wait
+	points := map[time.Time]float64{}
+	today := earningsDay(time.Now())
+	for d := today.AddDate(-1, 0, 0); d.Before(today); d = d.AddDate(0, 0, 1) {
+		points[d] = 40
+	}
+	bars := closes(points)
+	sortBarsByTime(bars)
+	histCacheMu.Lock()
+	for _, period := range []string{"5d", "1mo", "3mo", "6mo", "1y"} {
+		histCache["SPAR:"+period] = histCacheEntry{Data: bars, FetchedAt: time.Now()}
+	}
+	histCacheMu.Unlock()
+	defer func() {
+		histCacheMu.Lock()
+		for _, period := range []string{"5d", "1mo", "3mo", "6mo", "1y"} {
+			delete(histCache, "SPAR:"+period)
+		}
+		histCacheMu.Unlock()
+	}()
+
+	if w := postJSON(r, "/api/portfolio/savings-plans", token, map[string]interface{}{
+		"symbol": "SPAR", "amount": 10, "fee": 10, "start_date": "2026-01-01",
+	}); w.Code != http.StatusBadRequest {
+		t.Errorf("a fee eating the whole rate must be rejected, got %d", w.Code)
+	}
+
+	start := today.AddDate(0, -3, 0)
+	w := postJSON(r, "/api/portfolio/savings-plans", token, map[string]interface{}{
+		"symbol": "spar", "amount": 101, "fee": 1, "currency": "USD", "interval": "monthly",
+		"execution_day": 1, "start_date": start.Format("2006-01-02"),
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create failed: %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Plan     SavingsPlan `json:"plan"`
+		Executed int         `json:"executed"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	want := len(savingsPlanDates(created.Plan, today.AddDate(0, 0, -1)))
+	if want < 3 || created.Executed != want || created.Plan.LastExecutedAt == nil {
+		t.Fatalf("past executions must be booked right away, want %d: %+v", want, created)
+	}
+
+	var txs []PortfolioTransaction
+	db.Where("source = ?", "savings_plan").Order("date").Find(&txs)
+	if len(txs) != want || txs[0].Type != "buy" || !near(txs[0].Quantity, 2.5) || txs[0].Fees != 1 || txs[0].Currency != "USD" {
+		t.Fatalf("unexpected ledger transactions %+v", txs)
+	}
+	var pos PortfolioPosition
+	db.Where("symbol = ?", "SPAR").First(&pos)
+	if pos.Quantity == nil || math.Abs(*pos.Quantity-2.5*float64(want)) > 1e-6 {
+		t.Errorf("position must hold all executions, got %+v", pos)
+	}
+
+	// Running again books nothing twice
+	var rerun struct {
+		Executed int `json:"executed"`
+	}
+	w = postJSON(r, "/api/portfolio/savings-plans/"+fmt.Sprint(created.Plan.ID)+"/run", token, nil)
+	json.Unmarshal(w.Body.Bytes(), &rerun)
+	if w.Code != http.StatusOK || rerun.Executed != 0 {
+		t.Errorf("executions must be idempotent: %d %s", w.Code, w.Body.String())
+	}
+
+	// An execution booked by another instance meanwhile is skipped by the unique index
+	migrateLedgerIndexes()
+	dup := txs[0]
+	dup.ID = 0
+	if res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&[]PortfolioTransaction{dup}); res.Error != nil || res.RowsAffected != 0 {
+		t.Errorf("a booked execution must not be inserted twice: %v %d", res.Error, res.RowsAffected)
+	}
+
+	// The position is kept in USD, a EUR plan cannot add to it
+	if w := postJSON(r, "/api/portfolio/savings-plans", token, map[string]interface{}{
+		"symbol": "SPAR", "amount": 50, "currency": "EUR", "start_date": start.Format("2006-01-02"),
+	}); w.Code != http.StatusBadRequest {
+		t.Errorf("a currency mismatch must be rejected, got %d", w.Code)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestSecurityIdentifiers(t *testing.T) {
+	for isin, want := range map[string]bool{
+		"US0378331005": true,  // Apple
+		"DE0007164600": true,  // SAP
+		"US0846707026": true,  // Berkshire Hathaway B
+		"US0378331006": false, // wrong check digit
+		"0378331005US": false,
+		"DE000716460":  false,
+	} {
+		if got := validISIN(isin); got != want {
+			t.Errorf("validISIN(%s) = %v, want %v", isin, got, want)
+		}
+	}
+	if got := wknFromISIN("DE0007164600"); got != "716460" {
+		t.Errorf("expected WKN 716460, got %q", got)
+	}
+	if got := wknFromISIN("US0378331005"); got != "" {
+		t.Errorf("non-German ISIN must not yield a WKN, got %q", got)
+	}
+	if !looksLikeWKN("A1EWWW") || looksLikeWKN("ABCDEF") || looksLikeWKN("AAPL") {
+		t.Error("unexpected WKN format check")
+	}
+}
+
+func TestSecurityProviderSymbols(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&Security{})
+
+	if got := providerSymbol("BRK-B", "alpaca"); got != "BRK.B" {
+		t.Errorf("Alpaca share class expected BRK.B, got %s", got)
+	}
+	if got := providerSymbol("BRK-B", "yahoo"); got != "BRK-B" {
+		t.Errorf("Yahoo symbol must stay canonical, got %s", got)
+	}
+	if got := providerSymbol("SAP.DE", "alpaca"); got != "SAP.DE" {
+		t.Errorf("suffix symbols must not be rewritten, got %s", got)
+	}
+	if got := canonicalSymbol("BRK.B", "alpaca"); got != "BRK-B" {
+		t.Errorf("expected BRK-B from Alpaca ticker, got %s", got)
+	}
+
+	// Explicit mappings override the naming rules in both directions
+	saveSecurity(&Security{Symbol: "RDS-A", AlpacaSymbol: "SHEL", Exchange: "NYSE"})
+	if got := providerSymbol("RDS-A", "alpaca"); got != "SHEL" {
+		t.Errorf("expected mapped ticker SHEL, got %s", got)
+	}
+	if got := canonicalSymbol("SHEL", "alpaca"); got != "RDS-A" {
+		t.Errorf("expected RDS-A for SHEL, got %s", got)
+	}
+
+	// Exchange from the master wins over the suffix heuristic
+	saveSecurity(&Security{Symbol: "SHOP.TO"})
+	saveSecurity(&Security{Symbol: "ADR.X", Exchange: "NYSE"})
+	if !isNonUSStock("SHOP.TO") || isNonUSStock("ADR.X") || isNonUSStock("AAPL") || !isNonUSStock("VOW3.DE") {
+		t.Error("unexpected US classification")
+	}
+	if sec, _ := lookupSecurity("SHOP.TO"); sec.Exchange != "TSX" || sec.Currency != "CAD" {
+		t.Errorf("suffix defaults not applied: %+v", sec)
+	}
+}
+
+func TestSecurityIndexConcurrentAccess(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&Security{})
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(4)
+
+	// Readers run while saveSecurity updates the index in place (go test -race)
+	var wg sync.WaitGroup
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				saveSecurity(&Security{Symbol: fmt.Sprintf("T%d%02d", w, i), AlpacaSymbol: fmt.Sprintf("A%d%02d", w, i), ISIN: "US0378331005"})
+			}
+		}(w)
+	}
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				lookupSecurity("T000")
+				providerSymbol("T101", "alpaca")
+				canonicalSymbol("A001", "alpaca")
+				resolveSecurity("US0378331005")
+			}
+		}()
+	}
+	wg.Wait()
+	if got := canonicalSymbol("A149", "alpaca"); got != "T149" {
+		t.Errorf("expected T149 for A149, got %s", got)
+	}
+}
+
+func TestCreatePortfolioPositionByISINAndWKN(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&Security{}, &PortfolioPosition{}, &PortfolioTransaction{}, &PortfolioTradeHistory{}, &Stock{})
+	r, token := setupLiveRouter(t)
+	r.POST("/api/portfolio", authMiddleware(), createPortfolioPosition)
+	r.GET("/api/securities/resolve", authMiddleware(), resolveSecurityHandler)
+	r.POST("/api/admin/securities", authMiddleware(), adminOnly(), saveSecurityHandler)
+
+	w := postJSON(r, "/api/admin/securities", token, map[string]string{"symbol": "sap.de", "name": "SAP SE", "isin": "DE0007164600"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("save failed: %d %s", w.Code, w.Body.String())
+	}
+	if w := postJSON(r, "/api/admin/securities", token, map[string]string{"symbol": "SAP", "isin": "DE0007164600"}); w.Code != http.StatusBadRequest {
+		t.Errorf("ISIN assigned to another symbol must be rejected, got %d", w.Code)
+	}
+	if w := postJSON(r, "/api/admin/securities", token, map[string]string{"symbol": "X", "isin": "DE0007164601"}); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid ISIN must be rejected, got %d", w.Code)
+	}
+
+	var sec Security
+	json.Unmarshal(getJSON(r, "/api/securities/resolve?q=716460", token).Body.Bytes(), &sec)
+	if sec.Symbol != "SAP.DE" || sec.WKN != "716460" || sec.Currency != "EUR" {
+		t.Fatalf("WKN must resolve to SAP.DE, got %+v", sec)
+	}
+
+	for _, body := range []map[string]interface{}{
+		{"isin": "DE0007164600", "avg_price": 120.0},
+		{"symbol": "716460", "avg_price": 125.0},
+	} {
+		w := postJSON(r, "/api/portfolio", token, body)
+		var pos PortfolioPosition
+		json.Unmarshal(w.Body.Bytes(), &pos)
+		if w.Code != http.StatusCreated || pos.Symbol != "SAP.DE" || pos.Name != "SAP SE" {
+			t.Errorf("position by %v: %d %s", body, w.Code, w.Body.String())
+		}
+	}
+
+	if w := postJSON(r, "/api/portfolio", token, map[string]interface{}{"wkn": "A0B1C2", "avg_price": 10.0}); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown WKN must be rejected, got %d", w.Code)
+	}
+
+	w = postJSON(r, "/api/portfolio", token, map[string]interface{}{"symbol": "brk-b", "name": "Berkshire", "avg_price": 400.0})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("plain symbol failed: %d %s", w.Code, w.Body.String())
+	}
+	if _, ok := lookupSecurity("BRK-B"); !ok {
+		t.Error("new position symbol must be added to the security master")
+	}
+}
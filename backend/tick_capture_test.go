@@ -0,0 +1,162 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTickStore_FlushRangeAndPrune(t *testing.T) {
+	s := newTickStore(t.TempDir())
+	base := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	s.Add("BRK-B", Tick{Time: base.UnixNano(), Kind: "t", Price: 412.5, Size: 100})
+	s.Add("BRK-B", Tick{Time: base.Add(time.Second).UnixNano(), Kind: "q", Bid: 412.4, Ask: 412.6, BidSize: 3, AskSize: 5})
+	s.Add("BRK-B", Tick{Time: base.Add(24 * time.Hour).UnixNano(), Kind: "t", Price: 415, Size: 10})
+	s.Flush()
+
+	ticks, err := s.Range("BRK-B", base.Add(-time.Minute), base.Add(48*time.Hour), 0)
+	if err != nil || len(ticks) != 3 {
+		t.Fatalf("expected 3 ticks across two day files, got %d (%v)", len(ticks), err)
+	}
+	if q := ticks[1]; q.Kind != "q" || q.Bid != 412.4 || q.Ask != 412.6 || q.AskSize != 5 {
+		t.Errorf("quote not restored: %+v", q)
+	}
+	if tr := ticks[0]; tr.Kind != "t" || tr.Price != 412.5 || tr.Size != 100 {
+		t.Errorf("trade not restored: %+v", tr)
+	}
+	if ticks, _ := s.Range("BRK-B", base.Add(-time.Minute), base.Add(48*time.Hour), 1); len(ticks) != 1 || ticks[0].Price != 415 {
+		t.Errorf("limit must keep the newest ticks, got %+v", ticks)
+	}
+
+	// A torn record from an interrupted write is cut before appending
+	path := s.dayPath("BRK-B", base)
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.Write([]byte{1, 2, 3})
+	f.Close()
+	s.Add("BRK-B", Tick{Time: base.Add(2 * time.Second).UnixNano(), Kind: "t", Price: 413})
+	s.Flush()
+	if ticks, _ := s.Range("BRK-B", base, base.Add(time.Hour), 0); len(ticks) != 3 || ticks[2].Price != 413 {
+		t.Errorf("expected clean append after torn record, got %+v", ticks)
+	}
+
+	// Retention by age, then by size (oldest day first)
+	removed, _ := s.Prune(base.AddDate(0, 0, 7).Add(time.Hour), 7, 1<<30)
+	if removed != 1 {
+		t.Errorf("expected the 2026-03-02 file to expire, removed %d", removed)
+	}
+	if _, err := os.Stat(filepath.Join(s.dir, "BRK-B", "2026-03-03.ticks")); err != nil {
+		t.Errorf("newer day file must be kept: %v", err)
+	}
+	if removed, total := s.Prune(base, 30, 0); removed != 1 || total != 0 || s.Size() != 0 {
+		t.Errorf("size limit must remove all files, removed %d, %d bytes left", removed, total)
+	}
+}
+
+func TestTickStore_ExitPrice(t *testing.T) {
+	s := newTickStore("")
+	now := time.Now()
+	s.Add("AAPL", Tick{Time: now.Add(-5 * time.Second).UnixNano(), Kind: "t", Price: 190.1})
+	if p, ok := s.ExitPrice("AAPL", "LONG", now, tickFreshness); !ok || p != 190.1 {
+		t.Errorf("trade price expected without quote, got %v %v", p, ok)
+	}
+	s.Add("AAPL", Tick{Time: now.Add(-time.Second).UnixNano(), Kind: "q", Bid: 189.9, Ask: 190.2})
+	if p, _ := s.ExitPrice("AAPL", "LONG", now, tickFreshness); p != 189.9 {
+		t.Errorf("long exits at the bid, got %v", p)
+	}
+	if p, _ := s.ExitPrice("AAPL", "SHORT", now, tickFreshness); p != 190.2 {
+		t.Errorf("short exits at the ask, got %v", p)
+	}
+	if _, ok := s.ExitPrice("AAPL", "LONG", now.Add(time.Minute), tickFreshness); ok {
+		t.Error("stale ticks must not be used")
+	}
+
+	if sltpTrigger("LONG", 189.95, 0, 189.9) != "SL" || sltpTrigger("SHORT", 190.1, 0, 190.2) != "SL" ||
+		sltpTrigger("LONG", 0, 189.5, 189.9) != "TP" || sltpTrigger("LONG", 180, 200, 189.9) != "" {
+		t.Error("unexpected SL/TP trigger")
+	}
+}
+
+func TestSharedWSManager_TickRefCounting(t *testing.T) {
+	m := &SharedWSManager{
+		client:          &AlpacaWSClient{subscriptions: map[string]bool{}, barHandlers: map[string][]func(AlpacaWSBar){}, tickSubs: map[string]bool{}, tickHandlers: map[string][]func(Tick){}, stopChan: make(chan struct{})},
+		refCount:        map[string]int{},
+		sessionHandlers: map[uint]map[string]func(AlpacaWSBar){},
+		tickRefCount:    map[string]int{},
+		tickSessions:    map[uint]map[string]bool{},
+	}
+	m.SyncTickSessions(map[uint][]string{1: {"AAPL", "BRK-B"}, 2: {"AAPL"}})
+	if got := m.TickSymbols(); got["AAPL"] != 2 || got["BRK-B"] != 1 {
+		t.Fatalf("unexpected ref counts %v", got)
+	}
+	if len(m.client.tickHandlers["BRK.B"]) != 1 {
+		t.Error("tick handler must be registered once under the Alpaca ticker")
+	}
+	if !m.client.tickSubs["AAPL"] || !m.client.tickSubs["BRK.B"] {
+		t.Error("symbols subscribed while disconnected must be kept for the reconnect")
+	}
+
+	m.SyncTickSessions(map[uint][]string{2: {"AAPL", "MSFT"}})
+	if got := m.TickSymbols(); got["AAPL"] != 1 || got["MSFT"] != 1 || len(got) != 2 {
+		t.Errorf("session 1 must release its symbols, got %v", got)
+	}
+	if _, ok := m.client.tickHandlers["BRK.B"]; ok {
+		t.Error("handler of an unsubscribed symbol must be removed")
+	}
+
+	m.RemoveSession(2)
+	if got := m.TickSymbols(); len(got) != 0 {
+		t.Errorf("removing the session must release its ticks, got %v", got)
+	}
+}
+
+func TestCloseSLTPFromTick_RechecksStoredStop(t *testing.T) {
+	setupLiveTestDB(t)
+	go livePositionWriter()
+	config := LiveTradingConfig{UserID: 1}
+	db.Create(&config)
+	session := LiveTradingSession{UserID: 1, ConfigID: config.ID, Strategy: "regression_scalping", Interval: "5m", IsActive: true, StartedAt: time.Now()}
+	db.Create(&session)
+	pos := LiveTradingPosition{SessionID: session.ID, Symbol: "TICK", Direction: "LONG", EntryPrice: 100, EntryPriceUSD: 100,
+		EntryTime: time.Now(), StopLoss: 90, Quantity: 1, InvestedAmount: 100, NativeCurrency: "USD"}
+	db.Create(&pos)
+	key := openPosGuardKey(session.ID, 0, "TICK")
+	liveOpenPosGuard.Store(key, true)
+	t.Cleanup(func() {
+		liveOpenPosGuard.Delete(key)
+		tickClosing.Delete(pos.ID)
+	})
+
+	// The watch list still has the old stop at 95, the position was moved to 90 meanwhile
+	w := tickWatchPos{PositionID: pos.ID, SessionID: session.ID, ConfigID: config.ID, Direction: "LONG", StopLoss: 95}
+	tickClosing.Store(pos.ID, struct{}{})
+	closeSLTPFromTick(w, 93, "SL")
+	time.Sleep(20 * time.Millisecond)
+	db.First(&pos, pos.ID)
+	if pos.IsClosed {
+		t.Fatal("a tick above the stored stop must not close the position")
+	}
+	if _, ok := liveOpenPosGuard.Load(key); !ok {
+		t.Error("the open position guard must be restored")
+	}
+	if _, busy := tickClosing.Load(pos.ID); busy {
+		t.Error("an aborted close must let later ticks try again")
+	}
+
+	// Inactive session: nothing closes, later ticks are not blocked either
+	db.Model(&session).Update("is_active", false)
+	tickClosing.Store(pos.ID, struct{}{})
+	closeSLTPFromTick(w, 89, "SL")
+	if _, busy := tickClosing.Load(pos.ID); busy {
+		t.Error("an inactive session must release the tick close")
+	}
+
+	db.Model(&session).Update("is_active", true)
+	tickClosing.Store(pos.ID, struct{}{})
+	closeSLTPFromTick(w, 89, "SL")
+	time.Sleep(20 * time.Millisecond)
+	db.First(&pos, pos.ID)
+	if !pos.IsClosed || pos.CloseReason != "SL" {
+		t.Errorf("expected SL close below the stored stop, got closed=%v reason=%q", pos.IsClosed, pos.CloseReason)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func tptr(t time.Time) *time.Time { return &t }
+
+func TestUniverseMembership_EffectiveDates(t *testing.T) {
+	m := universeMembership{
+		"AAPL": {{Symbol: "AAPL"}},
+		"GE":   {{Symbol: "GE", ValidTo: tptr(day(2020, 6, 1))}},
+		"TSLA": {{Symbol: "TSLA", ValidFrom: tptr(day(2020, 12, 21))}},
+		"XOM": {
+			{Symbol: "XOM", ValidTo: tptr(day(2019, 1, 1))},
+			{Symbol: "XOM", ValidFrom: tptr(day(2021, 1, 1))},
+		},
+	}
+	cases := []struct {
+		symbol string
+		at     time.Time
+		want   bool
+	}{
+		{"AAPL", day(2010, 1, 1), true},
+		{"GE", time.Date(2020, 5, 31, 22, 0, 0, 0, time.UTC), true},
+		{"GE", day(2020, 6, 1), false},
+		{"TSLA", day(2020, 12, 20), false},
+		{"TSLA", day(2020, 12, 21), true},
+		{"XOM", day(2020, 1, 1), false},
+		{"XOM", day(2022, 1, 1), true},
+		{"MSFT", day(2022, 1, 1), false},
+	}
+	for _, c := range cases {
+		if got := m.contains(c.symbol, c.at); got != c.want {
+			t.Errorf("contains(%s, %s) = %v, want %v", c.symbol, c.at.Format("2006-01-02"), got, c.want)
+		}
+	}
+	if got := strings.Join(m.symbolsAt(day(2020, 1, 1)), ","); got != "AAPL,GE" {
+		t.Errorf("symbolsAt = %s", got)
+	}
+	if got := strings.Join(m.symbols(), ","); got != "AAPL,GE,TSLA,XOM" {
+		t.Errorf("symbols must include removed members, got %s", got)
+	}
+
+	trades := []ArenaBacktestTrade{
+		{EntryTime: day(2019, 3, 1).Unix()},
+		{EntryTime: day(2020, 7, 1).Unix()},
+	}
+	kept, dropped := m.filterTrades("GE", trades)
+	if len(kept) != 1 || dropped != 1 || kept[0].EntryTime != trades[0].EntryTime {
+		t.Errorf("expected only the entry before the removal, got %+v", kept)
+	}
+	if kept, dropped := universeMembership(nil).filterTrades("GE", trades); len(kept) != 2 || dropped != 0 {
+		t.Error("nil membership must keep all trades")
+	}
+}
+
+func TestParseUniverseMembers(t *testing.T) {
+	members, errs, err := parseUniverseMembers([]byte("Symbol;Name;From;To\naapl;Apple;;\nGE;General Electric;01.01.2000;2020-06-01\nX;Bad;2020-01-01;2019-01-01\n"))
+	if err != nil || len(members) != 2 || len(errs) != 1 {
+		t.Fatalf("unexpected CSV result: %+v %v %v", members, errs, err)
+	}
+	if members[0].Symbol != "AAPL" || members[0].ValidFrom != nil || members[0].ValidTo != nil {
+		t.Errorf("open-ended member expected, got %+v", members[0])
+	}
+	if !members[1].ValidFrom.Equal(day(2000, 1, 1)) || !members[1].ValidTo.Equal(day(2020, 6, 1)) {
+		t.Errorf("unexpected span %+v", members[1])
+	}
+
+	// Watchlist export files carry extra fields and no dates
+	members, errs, err = parseUniverseMembers([]byte(`[{"symbol":"DIS","name":"Walt Disney Company","category":"Communication Services","market_cap":0}]`))
+	if err != nil || len(errs) != 0 || len(members) != 1 || members[0].Name != "Walt Disney Company" {
+		t.Fatalf("unexpected JSON result: %+v %v %v", members, errs, err)
+	}
+
+	if _, _, err := parseUniverseMembers([]byte("name\nApple\n")); err == nil {
+		t.Error("missing symbol column must be rejected")
+	}
+}
+
+func TestUniverseImportExport(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&Universe{}, &UniverseMember{}, &BotFilterConfig{})
+	r, token := setupLiveRouter(t)
+	r.GET("/api/universes/:id", authMiddleware(), getUniverse)
+	r.GET("/api/universes/:id/export", authMiddleware(), exportUniverse)
+	r.POST("/api/admin/universes", authMiddleware(), adminOnly(), createUniverse)
+	r.POST("/api/admin/universes/:id/import", authMiddleware(), adminOnly(), importUniverse)
+
+	w := postJSON(r, "/api/admin/universes", token, map[string]string{"name": "S&P 500"})
+	var u Universe
+	json.Unmarshal(w.Body.Bytes(), &u)
+	if w.Code != http.StatusCreated || u.ID == 0 {
+		t.Fatalf("create failed: %d %s", w.Code, w.Body.String())
+	}
+	if w := postJSON(r, "/api/admin/universes", token, map[string]string{"name": "S&P 500"}); w.Code != http.StatusBadRequest {
+		t.Errorf("duplicate name must be rejected, got %d", w.Code)
+	}
+
+	importBody := func(path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	base := "/api/admin/universes/" + fmt.Sprint(u.ID)
+	w = importBody(base+"/import", "symbol,name,valid_from,valid_to\nAAPL,Apple,,\nGE,General Electric,,2020-06-01\n")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"imported":2`) {
+		t.Fatalf("import failed: %d %s", w.Code, w.Body.String())
+	}
+	// Same symbol and start date updates the span instead of adding a second one
+	importBody(base+"/import", `[{"symbol":"GE","valid_to":"2021-01-01"}]`)
+
+	w = getJSON(r, "/api/universes/"+fmt.Sprint(u.ID)+"?at=2020-12-01", token)
+	var got Universe
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if len(got.Members) != 2 || got.MemberCount != 1 {
+		t.Fatalf("expected AAPL and GE on 2020-12-01, got %s", w.Body.String())
+	}
+	for _, m := range got.Members {
+		if m.Symbol == "GE" && (m.Name != "General Electric" || !m.ValidTo.Equal(day(2021, 1, 1))) {
+			t.Errorf("GE span not updated: %+v", m)
+		}
+	}
+
+	w = getJSON(r, "/api/universes/"+fmt.Sprint(u.ID)+"/export?format=csv", token)
+	if want := "symbol,name,valid_from,valid_to\nAAPL,Apple,,\nGE,General Electric,,2021-01-01\n"; w.Body.String() != want {
+		t.Errorf("unexpected CSV export:\n%s", w.Body.String())
+	}
+
+	w = importBody(base+"/import?replace=true", `[{"symbol":"MSFT"}]`)
+	var count int64
+	db.Model(&UniverseMember{}).Where("universe_id = ?", u.ID).Count(&count)
+	if w.Code != http.StatusOK || count != 1 {
+		t.Errorf("replace must drop the old members, got %d members", count)
+	}
+}
+
+func TestBotUniverseFilter(t *testing.T) {
+	setupLiveTestDB(t)
+	db.AutoMigrate(&Universe{}, &UniverseMember{}, &BotFilterConfig{}, &EarningsEvent{})
+	u := Universe{Name: "Nasdaq 100"}
+	db.Create(&u)
+	importUniverseMembers(u.ID, []UniverseMember{
+		{Symbol: "AAPL"},
+		{Symbol: "BBBY", ValidTo: tptr(day(2021, 1, 1))},
+	}, false)
+	db.Create(&BotFilterConfig{BotName: "quant", Enabled: true, UniverseID: &u.ID})
+
+	if blocked, _ := checkBotFilterConfig("quant", "AAPL", time.Now(), 50, 2, 5, 0); blocked {
+		t.Error("member must pass")
+	}
+	if blocked, reason := checkBotFilterConfig("quant", "BBBY", time.Now(), 50, 2, 5, 0); !blocked || !strings.Contains(reason, "Nicht in Nasdaq 100") {
+		t.Errorf("removed member must be blocked, got %v %q", blocked, reason)
+	}
+	if blocked, _ := checkBotUniverse("quant", "BBBY", day(2020, 3, 1)); blocked {
+		t.Error("historical entry during membership must pass")
+	}
+	if blocked, _ := checkBotUniverse("flipper", "BBBY", time.Now()); blocked {
+		t.Error("bot without universe must not be restricted")
+	}
+}
+
+func TestBotUniverseCandidates(t *testing.T) {
+	setupBackfillTestDB(t)
+	db.AutoMigrate(&Universe{}, &UniverseMember{}, &BotFilterConfig{}, &Stock{})
+	u := Universe{Name: "Dow"}
+	db.Create(&u)
+	importUniverseMembers(u.ID, []UniverseMember{
+		{Symbol: "AAPL", Name: "Apple"},
+		{Symbol: "WBA", Name: "Walgreens", ValidTo: tptr(day(2024, 2, 26))},
+	}, false)
+	db.Create(&BotFilterConfig{BotName: "quant", Enabled: true, UniverseID: &u.ID})
+	db.Create(&Stock{Symbol: "MSFT", Name: "Microsoft"})
+	db.Create(&Stock{Symbol: "AAPL", Name: "Apple"})
+
+	universe := botUniverse("quant")
+	if !universe.admits("AAPL", time.Now()) || universe.admits("WBA", time.Now()) || universe.admits("MSFT", time.Now()) {
+		t.Error("only current members may be candidates of the quant bot")
+	}
+	if !botUniverse("flipper").admits("MSFT", time.Now()) {
+		t.Error("a bot without universe takes the whole watchlist")
+	}
+
+	// The full update tracks universe members that are not on the watchlist
+	job, err := runFullStockUpdate("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var symbols []string
+	db.Model(&BackfillJobItem{}).Where("job_id = ?", job.ID).Order("id").Pluck("symbol", &symbols)
+	if strings.Join(symbols, ",") != "MSFT,AAPL,WBA" && strings.Join(symbols, ",") != "AAPL,MSFT,WBA" {
+		t.Errorf("expected watchlist plus former member WBA, got %v", symbols)
+	}
+}
@@ -5,6 +5,9 @@ import { useCurrency } from '../context/CurrencyContext'
 import { processStock, processStockWithConfigs, fetchBXtrenderConfig, fetchBXtrenderQuantConfig, fetchBXtrenderDitzConfig, fetchBXtrenderTraderConfig } from '../utils/bxtrender'
 import PortfolioChart from './PortfolioChart'
 import StockDetailOverlay from './StockDetailOverlay'
+import FundamentalFilterFields, { fundamentalFilterBody } from './FundamentalFilterFields'
+import EarningsGuardFields, { earningsGuardBody } from './EarningsGuardFields'
+import UniverseSelect from './UniverseSelect'
 
 function AdminPanel() {
   const token = localStorage.getItem('authToken')
@@ -188,6 +191,41 @@ function AdminPanel() {
   const [validatingAccount, setValidatingAccount] = useState(null)
   const [validationResult, setValidationResult] = useState({})
 
+  // Market data provider state
+  const [marketData, setMarketData] = useState(null)
+  const [marketDataChain, setMarketDataChain] = useState('')
+  const [marketDataDir, setMarketDataDir] = useState('')
+  const [savingMarketData, setSavingMarketData] = useState(false)
+
+  // Corporate actions state
+  const [corporateActions, setCorporateActions] = useState([])
+  const [corporateActionLog, setCorporateActionLog] = useState([])
+  const [corporateActionSymbol, setCorporateActionSymbol] = useState('')
+  const [corporateActionForm, setCorporateActionForm] = useState({ symbol: '', type: 'split', ex_date: '', numerator: '', denominator: '1', amount: '' })
+  const [savingCorporateAction, setSavingCorporateAction] = useState(false)
+  const [earningsEvents, setEarningsEvents] = useState([])
+  const [earningsSymbol, setEarningsSymbol] = useState('')
+  const [earningsCsv, setEarningsCsv] = useState('')
+  const [earningsImportResult, setEarningsImportResult] = useState(null)
+  const [importingEarnings, setImportingEarnings] = useState(false)
+  const [universes, setUniverses] = useState([])
+  const [selectedUniverse, setSelectedUniverse] = useState(null)
+  const [universeAt, setUniverseAt] = useState('')
+  const [newUniverseName, setNewUniverseName] = useState('')
+  const [universeImport, setUniverseImport] = useState('')
+  const [universeReplace, setUniverseReplace] = useState(false)
+  const [universeImportResult, setUniverseImportResult] = useState(null)
+  const [securities, setSecurities] = useState([])
+  const [securityQuery, setSecurityQuery] = useState('')
+  const [editingSecurity, setEditingSecurity] = useState(null)
+  const [securityMessage, setSecurityMessage] = useState('')
+  const [tickStatus, setTickStatus] = useState(null)
+
+  // Data quality state
+  const [dataQuality, setDataQuality] = useState({ reports: [], total: 0, quarantined: 0, sigma: 6 })
+  const [dataQualityOnlyQuarantined, setDataQualityOnlyQuarantined] = useState(true)
+  const [dataQualityDetail, setDataQualityDetail] = useState(null)
+
   const fetchAllowlist = async () => {
     setAllowlistLoading(true)
     try {
@@ -305,9 +343,311 @@ function AdminPanel() {
     }
     if (activeTab === 'alpaca') {
       fetchAlpacaAccounts()
+      fetchTickStatus()
+    }
+    if (activeTab === 'marketdata') {
+      fetchMarketDataProviders()
+    }
+    if (activeTab === 'corporateactions') {
+      fetchCorporateActions()
+    }
+    if (activeTab === 'earnings') {
+      fetchEarningsEvents()
+    }
+    if (activeTab === 'universes') {
+      fetchUniverses()
+    }
+    if (activeTab === 'securities') {
+      fetchSecurities()
+    }
+    if (activeTab === 'dataquality') {
+      fetchDataQuality()
     }
   }, [activeTab])
 
+  const fetchDataQuality = async (onlyQuarantined = dataQualityOnlyQuarantined) => {
+    try {
+      const res = await fetch(`/api/admin/data-quality${onlyQuarantined ? '?quarantined=true' : ''}`, { headers: { 'Authorization': `Bearer ${token}` } })
+      if (res.ok) setDataQuality(await res.json())
+    } catch (err) {
+      console.error('Failed to fetch data quality:', err)
+    }
+  }
+
+  const openDataQualityReport = async (id) => {
+    if (dataQualityDetail?.id === id) {
+      setDataQualityDetail(null)
+      return
+    }
+    try {
+      const res = await fetch(`/api/admin/data-quality/${id}`, { headers: { 'Authorization': `Bearer ${token}` } })
+      if (res.ok) setDataQualityDetail(await res.json())
+    } catch (err) {
+      console.error('Failed to fetch data quality report:', err)
+    }
+  }
+
+  const dataQualityAction = async (id, action) => {
+    if (action === 'delete' && !confirm('Kursdaten verwerfen und beim nächsten Abruf neu laden?')) return
+    try {
+      const res = await fetch(`/api/admin/data-quality/${id}${action === 'delete' ? '' : `/${action}`}`, {
+        method: action === 'delete' ? 'DELETE' : 'POST',
+        headers: { 'Authorization': `Bearer ${token}` }
+      })
+      const data = await res.json()
+      if (!res.ok) {
+        alert(data.error || 'Fehler')
+        return
+      }
+      setDataQualityDetail(action === 'delete' ? null : data)
+      fetchDataQuality()
+    } catch { alert('Verbindungsfehler') }
+  }
+
+  const fetchCorporateActions = async (symbol = corporateActionSymbol) => {
+    const query = symbol.trim() ? `?symbol=${encodeURIComponent(symbol.trim())}` : ''
+    try {
+      const [actionsRes, logRes] = await Promise.all([
+        fetch(`/api/admin/corporate-actions${query}`, { headers: { 'Authorization': `Bearer ${token}` } }),
+        fetch(`/api/admin/corporate-actions/log${query}`, { headers: { 'Authorization': `Bearer ${token}` } })
+      ])
+      if (actionsRes.ok) setCorporateActions(await actionsRes.json())
+      if (logRes.ok) setCorporateActionLog(await logRes.json())
+    } catch (err) {
+      console.error('Failed to fetch corporate actions:', err)
+    }
+  }
+
+  const createCorporateAction = async () => {
+    setSavingCorporateAction(true)
+    try {
+      const f = corporateActionForm
+      const res = await fetch('/api/admin/corporate-actions', {
+        method: 'POST',
+        headers: { 'Authorization': `Bearer ${token}`, 'Content-Type': 'application/json' },
+        body: JSON.stringify({
+          symbol: f.symbol,
+          type: f.type,
+          ex_date: f.ex_date,
+          numerator: parseFloat(f.numerator) || 0,
+          denominator: parseFloat(f.denominator) || 0,
+          amount: parseFloat(f.amount) || 0
+        })
+      })
+      const data = await res.json()
+      if (!res.ok) {
+        alert(data.error || 'Fehler beim Speichern')
+      } else {
+        setCorporateActionForm({ ...f, symbol: '', ex_date: '', numerator: '', amount: '' })
+        setTimeout(() => fetchCorporateActions(), 1500)
+      }
+    } catch { alert('Verbindungsfehler') }
+    setSavingCorporateAction(false)
+  }
+
+  const fetchEarningsEvents = async (symbol = earningsSymbol) => {
+    const params = new URLSearchParams()
+    if (symbol.trim()) params.set('symbol', symbol.trim())
+    else params.set('from', new Date().toISOString().slice(0, 10))
+    try {
+      const res = await fetch(`/api/earnings?${params}`, { headers: { 'Authorization': `Bearer ${token}` } })
+      if (res.ok) setEarningsEvents(await res.json())
+    } catch (err) {
+      console.error('Failed to fetch earnings events:', err)
+    }
+  }
+
+  const importEarningsCsv = async () => {
+    setImportingEarnings(true)
+    setEarningsImportResult(null)
+    try {
+      const res = await fetch('/api/admin/earnings/import', {
+        method: 'POST',
+        headers: { 'Authorization': `Bearer ${token}`, 'Content-Type': 'text/csv' },
+        body: earningsCsv
+      })
+      const data = await res.json()
+      if (!res.ok) {
+        alert(data.error || 'Import fehlgeschlagen')
+      } else {
+        setEarningsImportResult(data)
+        if (data.imported > 0) setEarningsCsv('')
+        fetchEarningsEvents()
+      }
+    } catch { alert('Verbindungsfehler') }
+    setImportingEarnings(false)
+  }
+
+  const deleteEarningsEvent = async (id) => {
+    if (!confirm('Earnings-Termin löschen?')) return
+    try {
+      const res = await fetch(`/api/admin/earnings/${id}`, { method: 'DELETE', headers: { 'Authorization': `Bearer ${token}` } })
+      if (res.ok) setEarningsEvents(prev => prev.filter(e => e.id !== id))
+    } catch { alert('Verbindungsfehler') }
+  }
+
+  const fetchUniverses = async () => {
+    try {
+      const res = await fetch('/api/universes', { headers: { 'Authorization': `Bearer ${token}` } })
+      if (res.ok) setUniverses(await res.json())
+    } catch (err) {
+      console.error('Failed to fetch universes:', err)
+    }
+  }
+
+  const openUniverse = async (id, at = universeAt) => {
+    try {
+      const res = await fetch(`/api/universes/${id}${at ? `?at=${at}` : ''}`, { headers: { 'Authorization': `Bearer ${token}` } })
+      if (res.ok) setSelectedUniverse(await res.json())
+    } catch (err) {
+      console.error('Failed to fetch universe:', err)
+    }
+  }
+
+  const createUniverse = async () => {
+    try {
+      const res = await fetch('/api/admin/universes', {
+        method: 'POST',
+        headers: { 'Authorization': `Bearer ${token}`, 'Content-Type': 'application/json' },
+        body: JSON.stringify({ name: newUniverseName })
+      })
+      const data = await res.json()
+      if (!res.ok) {
+        alert(data.error || 'Fehler beim Anlegen')
+        return
+      }
+      setNewUniverseName('')
+      fetchUniverses()
+      openUniverse(data.id)
+    } catch { alert('Verbindungsfehler') }
+  }
+
+  const deleteUniverse = async (id) => {
+    if (!confirm('Universe mit allen Mitgliedern löschen?')) return
+    try {
+      const res = await fetch(`/api/admin/universes/${id}`, { method: 'DELETE', headers: { 'Authorization': `Bearer ${token}` } })
+      if (res.ok) {
+        if (selectedUniverse?.id === id) setSelectedUniverse(null)
+        fetchUniverses()
+      }
+    } catch { alert('Verbindungsfehler') }
+  }
+
+  const importUniverseMembers = async () => {
+    if (!selectedUniverse) return
+    setUniverseImportResult(null)
+    try {
+      const res = await fetch(`/api/admin/universes/${selectedUniverse.id}/import${universeReplace ? '?replace=true' : ''}`, {
+        method: 'POST',
+        headers: { 'Authorization': `Bearer ${token}`, 'Content-Type': universeImport.trim().startsWith('[') ? 'application/json' : 'text/csv' },
+        body: universeImport
+      })
+      const data = await res.json()
+      if (!res.ok) {
+        alert(data.error || 'Import fehlgeschlagen')
+        return
+      }
+      setUniverseImportResult(data)
+      if (data.imported > 0) setUniverseImport('')
+      fetchUniverses()
+      openUniverse(selectedUniverse.id)
+    } catch { alert('Verbindungsfehler') }
+  }
+
+  const fetchSecurities = async (q = securityQuery) => {
+    try {
+      const res = await fetch(`/api/securities${q ? `?q=${encodeURIComponent(q)}` : ''}`, { headers: { 'Authorization': `Bearer ${token}` } })
+      if (res.ok) setSecurities(await res.json())
+    } catch (err) {
+      console.error('Failed to fetch securities:', err)
+    }
+  }
+
+  const saveSecurity = async () => {
+    if (!editingSecurity?.symbol) return
+    setSecurityMessage('')
+    try {
+      const res = await fetch('/api/admin/securities', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json', 'Authorization': `Bearer ${token}` },
+        body: JSON.stringify(editingSecurity)
+      })
+      const data = await res.json()
+      if (!res.ok) {
+        setSecurityMessage(data.error || 'Speichern fehlgeschlagen')
+        return
+      }
+      setEditingSecurity(null)
+      fetchSecurities()
+    } catch (err) {
+      console.error('Failed to save security:', err)
+    }
+  }
+
+  const syncSecurities = async () => {
+    setSecurityMessage('')
+    try {
+      const res = await fetch('/api/admin/securities/sync', { method: 'POST', headers: { 'Authorization': `Bearer ${token}` } })
+      const data = await res.json()
+      if (res.ok) setSecurityMessage(`${data.created} neue Einträge, ${data.total} gesamt`)
+      fetchSecurities()
+    } catch (err) {
+      console.error('Failed to sync securities:', err)
+    }
+  }
+
+  const exportUniverse = async (format) => {
+    if (!selectedUniverse) return
+    try {
+      const res = await fetch(`/api/universes/${selectedUniverse.id}/export?format=${format}`, { headers: { 'Authorization': `Bearer ${token}` } })
+      if (!res.ok) return
+      const blob = await res.blob()
+      const link = document.createElement('a')
+      link.href = URL.createObjectURL(blob)
+      link.download = `${selectedUniverse.name.toLowerCase().replace(/ /g, '_')}.${format}`
+      link.click()
+      URL.revokeObjectURL(link.href)
+    } catch { alert('Export fehlgeschlagen') }
+  }
+
+  const fetchMarketDataProviders = async () => {
+    try {
+      const res = await fetch('/api/admin/market-data/providers', {
+        headers: { 'Authorization': `Bearer ${token}` }
+      })
+      if (res.ok) {
+        const data = await res.json()
+        setMarketData(data)
+        setMarketDataChain(data.chain.join(','))
+        setMarketDataDir(data.file_dir || '')
+      }
+    } catch (err) {
+      console.error('Failed to fetch market data providers:', err)
+    }
+  }
+
+  const saveMarketDataProviders = async () => {
+    setSavingMarketData(true)
+    try {
+      const res = await fetch('/api/admin/market-data/providers', {
+        method: 'PUT',
+        headers: { 'Authorization': `Bearer ${token}`, 'Content-Type': 'application/json' },
+        body: JSON.stringify({
+          chain: marketDataChain.split(',').map(s => s.trim()).filter(Boolean),
+          file_dir: marketDataDir
+        })
+      })
+      const data = await res.json()
+      if (!res.ok) {
+        alert(data.error || 'Fehler beim Speichern')
+      } else {
+        setMarketData(data)
+        setMarketDataChain(data.chain.join(','))
+      }
+    } catch { alert('Verbindungsfehler') }
+    setSavingMarketData(false)
+  }
+
   const fetchBotFilterConfigs = async () => {
     try {
       const res = await fetch('/api/admin/bot-filter-config', {
@@ -331,6 +671,26 @@ function AdminPanel() {
     } catch (err) { console.error('Failed to fetch alpaca accounts:', err) }
   }
 
+  const fetchTickStatus = async () => {
+    try {
+      const res = await fetch('/api/admin/ticks', { headers: { 'Authorization': `Bearer ${token}` } })
+      if (res.ok) setTickStatus(await res.json())
+    } catch (err) { console.error('Failed to fetch tick status:', err) }
+  }
+
+  const saveTickSettings = async () => {
+    try {
+      const res = await fetch('/api/admin/ticks', {
+        method: 'PUT',
+        headers: { 'Authorization': `Bearer ${token}`, 'Content-Type': 'application/json' },
+        body: JSON.stringify({ retention_days: parseInt(tickStatus.retention_days, 10), max_mb: parseInt(tickStatus.max_mb, 10) })
+      })
+      const data = await res.json()
+      if (res.ok) setTickStatus(data)
+      else alert(data.error || 'Fehler beim Speichern')
+    } catch { alert('Verbindungsfehler') }
+  }
+
   const saveAlpacaAccount = async () => {
     setSavingAccount(true)
     try {
@@ -413,6 +773,9 @@ function AdminPanel() {
         min_avg_return: config.min_avg_return !== '' && config.min_avg_return != null ? parseFloat(config.min_avg_return) : null,
         max_avg_return: config.max_avg_return !== '' && config.max_avg_return != null ? parseFloat(config.max_avg_return) : null,
         min_market_cap: config.min_market_cap !== '' && config.min_market_cap != null ? parseFloat(config.min_market_cap) : null,
+        ...fundamentalFilterBody(config),
+        ...earningsGuardBody(config),
+        universe_id: config.universe_id || null,
       }
       const res = await fetch('/api/admin/bot-filter-config', {
         method: 'PUT',
@@ -464,6 +827,9 @@ function AdminPanel() {
           min_avg_return: merged.min_avg_return !== '' && merged.min_avg_return != null ? parseFloat(merged.min_avg_return) : null,
           max_avg_return: merged.max_avg_return !== '' && merged.max_avg_return != null ? parseFloat(merged.max_avg_return) : null,
           min_market_cap: merged.min_market_cap !== '' && merged.min_market_cap != null ? parseFloat(merged.min_market_cap) : null,
+          ...fundamentalFilterBody(merged),
+          ...earningsGuardBody(merged),
+          universe_id: merged.universe_id || null,
         }
         const res = await fetch('/api/admin/bot-filter-config', {
           method: 'PUT',
@@ -2298,6 +2664,12 @@ function AdminPanel() {
             { key: 'botfilter', label: 'Bot Filter' },
             { key: 'allowlist', label: 'Aktien Listen' },
             { key: 'alpaca', label: 'Alpaca' },
+            { key: 'marketdata', label: 'Marktdaten' },
+            { key: 'corporateactions', label: 'Kapitalmaßnahmen' },
+            { key: 'earnings', label: 'Earnings' },
+            { key: 'universes', label: 'Universes' },
+            { key: 'securities', label: 'Wertpapiere' },
+            { key: 'dataquality', label: 'Datenqualität' },
             { key: 'settings', label: 'Einstellungen' }
           ].map(tab => (
             <button
@@ -6941,6 +7313,33 @@ function AdminPanel() {
                         </div>
                       </div>
 
+                      <div className="mb-4">
+                        <h5 className="text-sm font-medium text-gray-300 mb-2">Fundamentaldaten</h5>
+                        <FundamentalFilterFields
+                          value={config}
+                          onChange={v => setBotFilterConfigs(prev => ({ ...prev, [bot.name]: { ...v, bot_name: bot.name } }))}
+                        />
+                      </div>
+
+                      <div className="mb-4">
+                        <h5 className="text-sm font-medium text-gray-300 mb-2">Universe</h5>
+                        <UniverseSelect
+                          value={config.universe_id ?? ''}
+                          emptyLabel="Alle Aktien"
+                          onChange={v => updateBotFilterValue(bot.name, 'universe_id', v === '' ? null : v)}
+                          className="w-full md:w-64 bg-dark-700 border border-dark-500 rounded px-2 py-1.5 text-white text-sm"
+                        />
+                        <p className="text-xs text-gray-500 mt-1">Nur Aktien kaufen, die zum Signalzeitpunkt Mitglied sind.</p>
+                      </div>
+
+                      <div className="mb-4">
+                        <h5 className="text-sm font-medium text-gray-300 mb-2">Earnings</h5>
+                        <EarningsGuardFields
+                          value={config}
+                          onChange={v => setBotFilterConfigs(prev => ({ ...prev, [bot.name]: { ...v, bot_name: bot.name } }))}
+                        />
+                      </div>
+
                       <div className="flex justify-end">
                         <button
                           onClick={() => saveBotFilterConfig(bot.name)}
@@ -7302,6 +7701,590 @@ function AdminPanel() {
                     </tbody>
                   </table>
                 </div>
+
+                {tickStatus && (
+                  <div className="bg-dark-800 rounded-lg border border-dark-600 p-4 space-y-3">
+                    <div className="flex items-center justify-between">
+                      <h3 className="text-sm font-semibold text-white">Tick-Erfassung</h3>
+                      <span className={`text-xs ${tickStatus.connected ? 'text-green-400' : 'text-gray-500'}`}>
+                        {tickStatus.connected ? 'WebSocket verbunden' : 'WebSocket getrennt'}
+                      </span>
+                    </div>
+                    <p className="text-xs text-gray-500">Trades und Quotes für offene SL/TP-Positionen von Sessions mit aktivierten Tick-Daten. SL/TP wird bei jedem Tick gegen Bid (Long) bzw. Ask (Short) geprüft.</p>
+                    <div className="flex flex-wrap items-end gap-4 text-sm">
+                      <div>
+                        <div className="text-xs text-gray-500">Symbole</div>
+                        <div className="text-white">{Object.keys(tickStatus.subscribed || {}).length}</div>
+                      </div>
+                      <div>
+                        <div className="text-xs text-gray-500">Überwachte Positionen</div>
+                        <div className="text-white">{tickStatus.watched_positions}</div>
+                      </div>
+                      <div>
+                        <div className="text-xs text-gray-500">Speicher</div>
+                        <div className="text-white">{(tickStatus.store_bytes / 1048576).toFixed(1)} MB</div>
+                      </div>
+                      <div className="w-28">
+                        <label className="text-xs text-gray-500 block mb-1">Aufbewahrung (Tage)</label>
+                        <input type="number" min="1" value={tickStatus.retention_days} onChange={e => setTickStatus(t => ({ ...t, retention_days: e.target.value }))}
+                          className="w-full bg-dark-700 border border-dark-500 rounded px-3 py-1.5 text-sm text-white focus:border-accent-500 focus:outline-none" />
+                      </div>
+                      <div className="w-28">
+                        <label className="text-xs text-gray-500 block mb-1">Max. Größe (MB)</label>
+                        <input type="number" min="1" value={tickStatus.max_mb} onChange={e => setTickStatus(t => ({ ...t, max_mb: e.target.value }))}
+                          className="w-full bg-dark-700 border border-dark-500 rounded px-3 py-1.5 text-sm text-white focus:border-accent-500 focus:outline-none" />
+                      </div>
+                      <button onClick={saveTickSettings}
+                        className="px-3 py-1.5 text-xs bg-accent-600 hover:bg-accent-500 text-white rounded transition-colors">Speichern</button>
+                    </div>
+                    {Object.keys(tickStatus.subscribed || {}).length > 0 && (
+                      <div className="text-xs text-gray-400">
+                        {Object.entries(tickStatus.subscribed).map(([sym, n]) => `${sym}${n > 1 ? ` ×${n}` : ''}`).join(', ')}
+                      </div>
+                    )}
+                  </div>
+                )}
+              </div>
+            )}
+
+            {activeTab === 'marketdata' && (
+              <div className="space-y-4">
+                <h2 className="text-lg font-bold text-white">Marktdaten-Provider</h2>
+                <div className="bg-dark-800 rounded-lg border border-dark-600 p-4 space-y-3">
+                  <div className="grid grid-cols-1 md:grid-cols-2 gap-3">
+                    <div>
+                      <label className="text-xs text-gray-500 block mb-1">Reihenfolge (kommagetrennt)</label>
+                      <input type="text" value={marketDataChain} onChange={e => setMarketDataChain(e.target.value)}
+                        placeholder={marketData?.default || 'yahoo,twelvedata'} className="w-full bg-dark-700 border border-dark-500 rounded px-3 py-2 text-sm text-white placeholder-gray-600 focus:border-accent-500 focus:outline-none" />
+                      <p className="text-[11px] text-gray-500 mt-1">Verfügbar: {marketData?.available?.join(', ')}</p>
+                    </div>
+                    <div>
+                      <label className="text-xs text-gray-500 block mb-1">Verzeichnis für CSV/Parquet-Dateien</label>
+                      <input type="text" value={marketDataDir} onChange={e => setMarketDataDir(e.target.value)}
+                        placeholder="/data/ohlcv" className="w-full bg-dark-700 border border-dark-500 rounded px-3 py-2 text-sm text-white placeholder-gray-600 focus:border-accent-500 focus:outline-none" />
+                      <p className="text-[11px] text-gray-500 mt-1">Dateien: &lt;Intervall&gt;/&lt;SYMBOL&gt;.csv oder &lt;SYMBOL&gt;_&lt;Intervall&gt;.parquet</p>
+                    </div>
+                  </div>
+                  <div className="flex justify-end">
+                    <button onClick={saveMarketDataProviders} disabled={savingMarketData || !marketDataChain.trim()}
+                      className="px-3 py-1.5 text-xs bg-accent-600 hover:bg-accent-500 disabled:bg-dark-600 disabled:text-gray-600 text-white rounded transition-colors">
+                      {savingMarketData ? 'Speichern...' : 'Speichern'}
+                    </button>
+                  </div>
+                </div>
+
+                <div className="bg-dark-800 rounded-lg border border-dark-600 overflow-hidden">
+                  <table className="w-full text-sm">
+                    <thead>
+                      <tr className="border-b border-dark-600 text-left text-gray-400">
+                        <th className="px-4 py-3 font-medium">Provider</th>
+                        <th className="px-4 py-3 font-medium text-right">Aufrufe</th>
+                        <th className="px-4 py-3 font-medium text-right">Fehler</th>
+                        <th className="px-4 py-3 font-medium text-right">Ø Latenz</th>
+                        <th className="px-4 py-3 font-medium">Letzter Erfolg</th>
+                        <th className="px-4 py-3 font-medium">Letzter Fehler</th>
+                      </tr>
+                    </thead>
+                    <tbody>
+                      {marketData && marketData.available.map(name => {
+                        const h = marketData.health[name] || {}
+                        const active = marketData.chain.includes(name)
+                        const cooling = h.cooldown_until && new Date(h.cooldown_until) > new Date()
+                        return (
+                          <tr key={name} className="border-b border-dark-700 hover:bg-dark-700/50">
+                            <td className="px-4 py-3 text-white font-medium">
+                              {name}
+                              {active && <span className="ml-2 px-1.5 py-0.5 rounded text-[10px] bg-accent-500/20 text-accent-400 border border-accent-500/30">#{marketData.chain.indexOf(name) + 1}</span>}
+                              {cooling && <span className="ml-2 px-1.5 py-0.5 rounded text-[10px] bg-red-500/20 text-red-400 border border-red-500/30">pausiert</span>}
+                            </td>
+                            <td className="px-4 py-3 text-right text-gray-300">{h.calls || 0}</td>
+                            <td className={`px-4 py-3 text-right ${h.failures ? 'text-red-400' : 'text-gray-500'}`}>{h.failures || 0}</td>
+                            <td className="px-4 py-3 text-right text-gray-300">{h.calls ? `${Math.round(h.avg_latency_ms)} ms` : '-'}</td>
+                            <td className="px-4 py-3 text-gray-400 text-xs">{h.last_success_at ? new Date(h.last_success_at).toLocaleString('de-DE') : '-'}</td>
+                            <td className="px-4 py-3 text-red-400 text-xs truncate max-w-xs" title={h.last_error}>{h.last_error_at ? `${new Date(h.last_error_at).toLocaleTimeString('de-DE')} ${h.last_error}` : '-'}</td>
+                          </tr>
+                        )
+                      })}
+                    </tbody>
+                  </table>
+                </div>
+              </div>
+            )}
+
+            {activeTab === 'corporateactions' && (
+              <div className="space-y-4">
+                <div className="flex items-center justify-between gap-3">
+                  <h2 className="text-lg font-bold text-white">Kapitalmaßnahmen</h2>
+                  <div className="flex gap-2">
+                    <input type="text" value={corporateActionSymbol} onChange={e => setCorporateActionSymbol(e.target.value.toUpperCase())}
+                      onKeyDown={e => e.key === 'Enter' && fetchCorporateActions()}
+                      placeholder="Symbol filtern" className="w-36 bg-dark-700 border border-dark-500 rounded px-3 py-1.5 text-sm text-white placeholder-gray-600 focus:border-accent-500 focus:outline-none" />
+                    <button onClick={() => fetchCorporateActions()} className="px-3 py-1.5 text-xs bg-dark-700 hover:bg-dark-600 text-gray-300 rounded transition-colors">Laden</button>
+                  </div>
+                </div>
+
+                <div className="bg-dark-800 rounded-lg border border-dark-600 p-4 space-y-3">
+                  <p className="text-xs text-gray-500">Splits werden beim Laden von Yahoo-Kursdaten automatisch erfasst. Ein neuer Split passt gecachte Kerzen, die Performance-Berechnung und offene Positionen an.</p>
+                  <div className="grid grid-cols-2 md:grid-cols-6 gap-3">
+                    <input type="text" value={corporateActionForm.symbol} onChange={e => setCorporateActionForm({ ...corporateActionForm, symbol: e.target.value.toUpperCase() })}
+                      placeholder="Symbol" className="bg-dark-700 border border-dark-500 rounded px-3 py-2 text-sm text-white placeholder-gray-600 focus:border-accent-500 focus:outline-none" />
+                    <select value={corporateActionForm.type} onChange={e => setCorporateActionForm({ ...corporateActionForm, type: e.target.value })}
+                      className="bg-dark-700 border border-dark-500 rounded px-3 py-2 text-sm text-white focus:border-accent-500 focus:outline-none">
+                      <option value="split">Split</option>
+                      <option value="dividend">Dividende</option>
+                    </select>
+                    <input type="date" value={corporateActionForm.ex_date} onChange={e => setCorporateActionForm({ ...corporateActionForm, ex_date: e.target.value })}
+                      className="bg-dark-700 border border-dark-500 rounded px-3 py-2 text-sm text-white focus:border-accent-500 focus:outline-none" />
+                    {corporateActionForm.type === 'split' ? (
+                      <>
+                        <input type="number" min="0" step="any" value={corporateActionForm.numerator} onChange={e => setCorporateActionForm({ ...corporateActionForm, numerator: e.target.value })}
+                          placeholder="Neu (z.B. 4)" className="bg-dark-700 border border-dark-500 rounded px-3 py-2 text-sm text-white placeholder-gray-600 focus:border-accent-500 focus:outline-none" />
+                        <input type="number" min="0" step="any" value={corporateActionForm.denominator} onChange={e => setCorporateActionForm({ ...corporateActionForm, denominator: e.target.value })}
+                          placeholder="Alt (z.B. 1)" className="bg-dark-700 border border-dark-500 rounded px-3 py-2 text-sm text-white placeholder-gray-600 focus:border-accent-500 focus:outline-none" />
+                      </>
+                    ) : (
+                      <input type="number" min="0" step="any" value={corporateActionForm.amount} onChange={e => setCorporateActionForm({ ...corporateActionForm, amount: e.target.value })}
+                        placeholder="Betrag je Aktie" className="md:col-span-2 bg-dark-700 border border-dark-500 rounded px-3 py-2 text-sm text-white placeholder-gray-600 focus:border-accent-500 focus:outline-none" />
+                    )}
+                    <button onClick={createCorporateAction} disabled={savingCorporateAction || !corporateActionForm.symbol.trim() || !corporateActionForm.ex_date}
+                      className="px-3 py-2 text-xs bg-accent-600 hover:bg-accent-500 disabled:bg-dark-600 disabled:text-gray-600 text-white rounded transition-colors">
+                      {savingCorporateAction ? 'Speichern...' : 'Hinzufügen'}
+                    </button>
+                  </div>
+                </div>
+
+                <div className="bg-dark-800 rounded-lg border border-dark-600 overflow-hidden">
+                  <table className="w-full text-sm">
+                    <thead>
+                      <tr className="border-b border-dark-600 text-left text-gray-400">
+                        <th className="px-4 py-3 font-medium">Symbol</th>
+                        <th className="px-4 py-3 font-medium">Typ</th>
+                        <th className="px-4 py-3 font-medium">Ex-Datum</th>
+                        <th className="px-4 py-3 font-medium text-right">Verhältnis / Betrag</th>
+                        <th className="px-4 py-3 font-medium">Quelle</th>
+                        <th className="px-4 py-3 font-medium">Angewendet</th>
+                      </tr>
+                    </thead>
+                    <tbody>
+                      {corporateActions.length === 0 && (
+                        <tr><td colSpan={6} className="px-4 py-6 text-center text-gray-500">Keine Kapitalmaßnahmen erfasst</td></tr>
+                      )}
+                      {corporateActions.map(a => (
+                        <tr key={a.id} className="border-b border-dark-700 hover:bg-dark-700/50">
+                          <td className="px-4 py-3 text-white font-medium">{a.symbol}</td>
+                          <td className="px-4 py-3 text-gray-300">{a.type === 'split' ? 'Split' : 'Dividende'}</td>
+                          <td className="px-4 py-3 text-gray-300">{new Date(a.ex_date).toLocaleDateString('de-DE')}</td>
+                          <td className="px-4 py-3 text-right text-gray-300">{a.type === 'split' ? `${a.ratio >= 1 ? `${+a.ratio.toFixed(4)}:1` : `1:${+(1 / a.ratio).toFixed(4)}`}` : a.amount.toFixed(4)}</td>
+                          <td className="px-4 py-3 text-gray-400 text-xs">{a.source}</td>
+                          <td className="px-4 py-3 text-gray-400 text-xs">{a.applied_at ? new Date(a.applied_at).toLocaleString('de-DE') : '-'}</td>
+                        </tr>
+                      ))}
+                    </tbody>
+                  </table>
+                </div>
+
+                <h3 className="text-sm font-bold text-white">Anpassungsprotokoll</h3>
+                <div className="bg-dark-800 rounded-lg border border-dark-600 overflow-hidden">
+                  <table className="w-full text-sm">
+                    <thead>
+                      <tr className="border-b border-dark-600 text-left text-gray-400">
+                        <th className="px-4 py-3 font-medium">Zeit</th>
+                        <th className="px-4 py-3 font-medium">Symbol</th>
+                        <th className="px-4 py-3 font-medium">Ziel</th>
+                        <th className="px-4 py-3 font-medium text-right">Menge</th>
+                        <th className="px-4 py-3 font-medium text-right">Preis</th>
+                        <th className="px-4 py-3 font-medium">Details</th>
+                      </tr>
+                    </thead>
+                    <tbody>
+                      {corporateActionLog.length === 0 && (
+                        <tr><td colSpan={6} className="px-4 py-6 text-center text-gray-500">Noch keine Anpassungen</td></tr>
+                      )}
+                      {corporateActionLog.map(e => (
+                        <tr key={e.id} className="border-b border-dark-700 hover:bg-dark-700/50">
+                          <td className="px-4 py-3 text-gray-400 text-xs">{new Date(e.created_at).toLocaleString('de-DE')}</td>
+                          <td className="px-4 py-3 text-white font-medium">{e.symbol}</td>
+                          <td className="px-4 py-3 text-gray-300">{e.target}{e.target_id ? ` #${e.target_id}` : ''}</td>
+                          <td className="px-4 py-3 text-right text-gray-300">{e.old_quantity || e.new_quantity ? `${+e.old_quantity.toFixed(4)} → ${+e.new_quantity.toFixed(4)}` : '-'}</td>
+                          <td className="px-4 py-3 text-right text-gray-300">{e.old_price || e.new_price ? `${e.old_price.toFixed(2)} → ${e.new_price.toFixed(2)}` : '-'}</td>
+                          <td className="px-4 py-3 text-gray-400 text-xs">{e.details}</td>
+                        </tr>
+                      ))}
+                    </tbody>
+                  </table>
+                </div>
+              </div>
+            )}
+
+            {activeTab === 'earnings' && (
+              <div className="space-y-4">
+                <div className="flex items-center justify-between gap-3">
+                  <h2 className="text-lg font-bold text-white">Earnings-Kalender</h2>
+                  <div className="flex gap-2">
+                    <input type="text" value={earningsSymbol} onChange={e => setEarningsSymbol(e.target.value.toUpperCase())}
+                      onKeyDown={e => e.key === 'Enter' && fetchEarningsEvents()}
+                      placeholder="Symbol filtern" className="w-36 bg-dark-700 border border-dark-500 rounded px-3 py-1.5 text-sm text-white placeholder-gray-600 focus:border-accent-500 focus:outline-none" />
+                    <button onClick={() => fetchEarningsEvents()} className="px-3 py-1.5 text-xs bg-dark-700 hover:bg-dark-600 text-gray-300 rounded transition-colors">Laden</button>
+                  </div>
+                </div>
+
+                <div className="bg-dark-800 rounded-lg border border-dark-600 p-4 space-y-3">
+                  <p className="text-xs text-gray-500">Kommende Termine werden mit den Fundamentaldaten von Yahoo erfasst. Historische Termine für Backtests per CSV importieren: Spalten <span className="font-mono">symbol,date[,timing]</span>, timing = bmo / amc. Importierte Termine haben Vorrang.</p>
+                  <textarea value={earningsCsv} onChange={e => setEarningsCsv(e.target.value)} rows={5}
+                    placeholder={'symbol,date,timing\nAAPL,2024-05-02,amc'}
+                    className="w-full bg-dark-700 border border-dark-500 rounded px-3 py-2 text-sm text-white font-mono placeholder-gray-600 focus:border-accent-500 focus:outline-none" />
+                  <div className="flex items-center justify-between gap-3">
+                    <div className="text-xs">
+                      {earningsImportResult && (
+                        <span className="text-gray-300">{earningsImportResult.imported} Termine importiert</span>
+                      )}
+                      {earningsImportResult?.errors?.length > 0 && (
+                        <div className="text-red-400 mt-1 space-y-0.5">
+                          {earningsImportResult.errors.slice(0, 10).map((e, i) => <div key={i}>{e}</div>)}
+                        </div>
+                      )}
+                    </div>
+                    <button onClick={importEarningsCsv} disabled={importingEarnings || !earningsCsv.trim()}
+                      className="px-3 py-2 text-xs bg-accent-600 hover:bg-accent-500 disabled:bg-dark-600 disabled:text-gray-600 text-white rounded transition-colors">
+                      {importingEarnings ? 'Importiere...' : 'CSV importieren'}
+                    </button>
+                  </div>
+                </div>
+
+                <div className="bg-dark-800 rounded-lg border border-dark-600 overflow-hidden">
+                  <table className="w-full text-sm">
+                    <thead>
+                      <tr className="border-b border-dark-600 text-left text-gray-400">
+                        <th className="px-4 py-3 font-medium">Symbol</th>
+                        <th className="px-4 py-3 font-medium">Datum</th>
+                        <th className="px-4 py-3 font-medium">Zeitpunkt</th>
+                        <th className="px-4 py-3 font-medium">Quelle</th>
+                        <th className="px-4 py-3 font-medium"></th>
+                      </tr>
+                    </thead>
+                    <tbody>
+                      {earningsEvents.length === 0 && (
+                        <tr><td colSpan={5} className="px-4 py-6 text-center text-gray-500">Keine Earnings-Termine erfasst</td></tr>
+                      )}
+                      {earningsEvents.map(e => (
+                        <tr key={e.id} className="border-b border-dark-700 hover:bg-dark-700/50">
+                          <td className="px-4 py-3 text-white font-medium">{e.symbol}</td>
+                          <td className="px-4 py-3 text-gray-300">{new Date(e.date).toLocaleDateString('de-DE', { timeZone: 'UTC' })}</td>
+                          <td className="px-4 py-3 text-gray-300">{e.timing === 'bmo' ? 'Vor Börsenöffnung' : e.timing === 'amc' ? 'Nach Börsenschluss' : '-'}</td>
+                          <td className="px-4 py-3 text-gray-400 text-xs">{e.source}</td>
+                          <td className="px-4 py-3 text-right">
+                            <button onClick={() => deleteEarningsEvent(e.id)} className="text-xs text-red-400 hover:text-red-300">Löschen</button>
+                          </td>
+                        </tr>
+                      ))}
+                    </tbody>
+                  </table>
+                </div>
+              </div>
+            )}
+
+            {activeTab === 'universes' && (
+              <div className="space-y-4">
+                <div className="flex items-center justify-between gap-3">
+                  <h2 className="text-lg font-bold text-white">Universes</h2>
+                  <div className="flex gap-2">
+                    <input type="text" value={newUniverseName} onChange={e => setNewUniverseName(e.target.value)}
+                      onKeyDown={e => e.key === 'Enter' && newUniverseName.trim() && createUniverse()}
+                      placeholder="Name, z.B. S&P 500" className="w-48 bg-dark-700 border border-dark-500 rounded px-3 py-1.5 text-sm text-white placeholder-gray-600 focus:border-accent-500 focus:outline-none" />
+                    <button onClick={createUniverse} disabled={!newUniverseName.trim()}
+                      className="px-3 py-1.5 text-xs bg-accent-600 hover:bg-accent-500 disabled:bg-dark-600 disabled:text-gray-600 text-white rounded transition-colors">Anlegen</button>
+                  </div>
+                </div>
+                <p className="text-xs text-gray-500">Benannte Aktienlisten mit historischer Zugehörigkeit. Bots, Arena-Batch, Backtest Lab und Signal-Liste können statt der Watchlist ein Universe nutzen; Backtests handeln ein Symbol nur, solange es Mitglied war.</p>
+
+                <div className="grid md:grid-cols-3 gap-4">
+                  <div className="bg-dark-800 rounded-lg border border-dark-600 overflow-hidden">
+                    {universes.length === 0 && <div className="px-4 py-6 text-center text-gray-500 text-sm">Noch keine Universes</div>}
+                    {universes.map(u => (
+                      <div key={u.id} onClick={() => openUniverse(u.id)}
+                        className={`flex items-center justify-between px-4 py-3 border-b border-dark-700 cursor-pointer ${selectedUniverse?.id === u.id ? 'bg-dark-700' : 'hover:bg-dark-700/50'}`}>
+                        <div>
+                          <div className="text-white text-sm font-medium">{u.name}</div>
+                          <div className="text-gray-500 text-xs">{u.member_count} aktuelle Mitglieder</div>
+                        </div>
+                        <button onClick={e => { e.stopPropagation(); deleteUniverse(u.id) }} className="text-xs text-red-400 hover:text-red-300">Löschen</button>
+                      </div>
+                    ))}
+                  </div>
+
+                  {selectedUniverse && (
+                    <div className="md:col-span-2 space-y-4">
+                      <div className="bg-dark-800 rounded-lg border border-dark-600 p-4 space-y-3">
+                        <div className="flex items-center justify-between">
+                          <h3 className="text-sm font-bold text-white">{selectedUniverse.name} importieren</h3>
+                          <div className="flex gap-2">
+                            <button onClick={() => exportUniverse('csv')} className="px-3 py-1.5 text-xs bg-dark-700 hover:bg-dark-600 text-gray-300 rounded transition-colors">Export CSV</button>
+                            <button onClick={() => exportUniverse('json')} className="px-3 py-1.5 text-xs bg-dark-700 hover:bg-dark-600 text-gray-300 rounded transition-colors">Export JSON</button>
+                          </div>
+                        </div>
+                        <p className="text-xs text-gray-500">CSV mit <span className="font-mono">symbol,name,valid_from,valid_to</span> oder JSON-Array (auch Watchlist-Exporte wie sp500_import.json). Leere Daten = unbegrenzt, valid_to ist der erste Tag ohne Mitgliedschaft.</p>
+                        <textarea value={universeImport} onChange={e => setUniverseImport(e.target.value)} rows={5}
+                          placeholder={'symbol,name,valid_from,valid_to\nAAPL,Apple,,\nGE,General Electric,,2018-06-26'}
+                          className="w-full bg-dark-700 border border-dark-500 rounded px-3 py-2 text-sm text-white font-mono placeholder-gray-600 focus:border-accent-500 focus:outline-none" />
+                        <div className="flex items-center justify-between gap-3">
+                          <label className="flex items-center gap-1.5 text-xs text-gray-400">
+                            <input type="checkbox" checked={universeReplace} onChange={e => setUniverseReplace(e.target.checked)} />
+                            Bestehende Mitglieder ersetzen
+                          </label>
+                          <button onClick={importUniverseMembers} disabled={!universeImport.trim()}
+                            className="px-3 py-2 text-xs bg-accent-600 hover:bg-accent-500 disabled:bg-dark-600 disabled:text-gray-600 text-white rounded transition-colors">Importieren</button>
+                        </div>
+                        {universeImportResult && (
+                          <div className="text-xs">
+                            <span className="text-gray-300">{universeImportResult.imported} Einträge importiert</span>
+                            {universeImportResult.errors?.length > 0 && (
+                              <div className="text-red-400 mt-1 space-y-0.5">
+                                {universeImportResult.errors.slice(0, 10).map((e, i) => <div key={i}>{e}</div>)}
+                              </div>
+                            )}
+                          </div>
+                        )}
+                      </div>
+
+                      <div className="flex items-center gap-2">
+                        <span className="text-xs text-gray-400">Mitglieder am</span>
+                        <input type="date" value={universeAt} onChange={e => { setUniverseAt(e.target.value); openUniverse(selectedUniverse.id, e.target.value) }}
+                          className="bg-dark-700 border border-dark-500 rounded px-3 py-1.5 text-sm text-white focus:border-accent-500 focus:outline-none" />
+                        <span className="text-xs text-gray-500">{selectedUniverse.members?.length || 0} Einträge</span>
+                      </div>
+                      <div className="bg-dark-800 rounded-lg border border-dark-600 overflow-hidden max-h-[480px] overflow-y-auto">
+                        <table className="w-full text-sm">
+                          <thead>
+                            <tr className="border-b border-dark-600 text-left text-gray-400">
+                              <th className="px-4 py-3 font-medium">Symbol</th>
+                              <th className="px-4 py-3 font-medium">Name</th>
+                              <th className="px-4 py-3 font-medium">Ab</th>
+                              <th className="px-4 py-3 font-medium">Bis</th>
+                            </tr>
+                          </thead>
+                          <tbody>
+                            {(selectedUniverse.members || []).map(m => (
+                              <tr key={m.id} className="border-b border-dark-700 hover:bg-dark-700/50">
+                                <td className="px-4 py-2 text-white font-medium">{m.symbol}</td>
+                                <td className="px-4 py-2 text-gray-300">{m.name}</td>
+                                <td className="px-4 py-2 text-gray-400 text-xs">{m.valid_from ? new Date(m.valid_from).toLocaleDateString('de-DE', { timeZone: 'UTC' }) : '-'}</td>
+                                <td className="px-4 py-2 text-gray-400 text-xs">{m.valid_to ? new Date(m.valid_to).toLocaleDateString('de-DE', { timeZone: 'UTC' }) : '-'}</td>
+                              </tr>
+                            ))}
+                          </tbody>
+                        </table>
+                      </div>
+                    </div>
+                  )}
+                </div>
+              </div>
+            )}
+
+            {activeTab === 'securities' && (
+              <div className="space-y-4">
+                <div className="flex items-center justify-between gap-3">
+                  <h2 className="text-lg font-bold text-white">Wertpapiere</h2>
+                  <div className="flex gap-2">
+                    <input type="text" value={securityQuery} onChange={e => setSecurityQuery(e.target.value)}
+                      onKeyDown={e => e.key === 'Enter' && fetchSecurities()}
+                      placeholder="Symbol, Name, ISIN, WKN" className="w-48 bg-dark-700 border border-dark-500 rounded px-3 py-1.5 text-sm text-white placeholder-gray-600 focus:border-accent-500 focus:outline-none" />
+                    <button onClick={() => setEditingSecurity({ symbol: '' })}
+                      className="px-3 py-1.5 text-xs bg-accent-600 hover:bg-accent-500 text-white rounded transition-colors">Neu</button>
+                    <button onClick={syncSecurities}
+                      className="px-3 py-1.5 text-xs bg-dark-700 hover:bg-dark-600 text-gray-300 rounded transition-colors">Aus Watchlists übernehmen</button>
+                  </div>
+                </div>
+                <p className="text-xs text-gray-500">Security Master: kanonisches Symbol (Yahoo-Schreibweise) mit ISIN, WKN, Börse, Währung und abweichenden Provider-Tickern. Leere Ticker folgen der Standardregel (z.B. BRK-B → BRK.B bei Alpaca). Portfolio-Positionen können per ISIN/WKN angelegt werden.</p>
+                {securityMessage && <div className="text-xs text-gray-300">{securityMessage}</div>}
+
+                {editingSecurity && (
+                  <div className="bg-dark-800 rounded-lg border border-dark-600 p-4 space-y-3">
+                    <div className="grid grid-cols-2 md:grid-cols-5 gap-3">
+                      <div>
+                        <label className="text-xs text-gray-400 block mb-1">Symbol</label>
+                        <input type="text" value={editingSecurity.symbol || ''} onChange={e => setEditingSecurity({ ...editingSecurity, symbol: e.target.value })}
+                          disabled={!!editingSecurity.id}
+                          className="w-full bg-dark-700 border border-dark-500 rounded px-2 py-1.5 text-sm text-white disabled:text-gray-500 focus:border-accent-500 focus:outline-none" />
+                      </div>
+                      <div>
+                        <label className="text-xs text-gray-400 block mb-1">Name</label>
+                        <input type="text" value={editingSecurity.name || ''} onChange={e => setEditingSecurity({ ...editingSecurity, name: e.target.value })}
+                          className="w-full bg-dark-700 border border-dark-500 rounded px-2 py-1.5 text-sm text-white disabled:text-gray-500 focus:border-accent-500 focus:outline-none" />
+                      </div>
+                      <div>
+                        <label className="text-xs text-gray-400 block mb-1">ISIN</label>
+                        <input type="text" value={editingSecurity.isin || ''} onChange={e => setEditingSecurity({ ...editingSecurity, isin: e.target.value })}
+                          className="w-full bg-dark-700 border border-dark-500 rounded px-2 py-1.5 text-sm text-white disabled:text-gray-500 focus:border-accent-500 focus:outline-none" />
+                      </div>
+                      <div>
+                        <label className="text-xs text-gray-400 block mb-1">WKN</label>
+                        <input type="text" value={editingSecurity.wkn || ''} onChange={e => setEditingSecurity({ ...editingSecurity, wkn: e.target.value })}
+                          className="w-full bg-dark-700 border border-dark-500 rounded px-2 py-1.5 text-sm text-white disabled:text-gray-500 focus:border-accent-500 focus:outline-none" />
+                      </div>
+                      <div>
+                        <label className="text-xs text-gray-400 block mb-1">Börse</label>
+                        <input type="text" value={editingSecurity.exchange || ''} onChange={e => setEditingSecurity({ ...editingSecurity, exchange: e.target.value })}
+                          className="w-full bg-dark-700 border border-dark-500 rounded px-2 py-1.5 text-sm text-white disabled:text-gray-500 focus:border-accent-500 focus:outline-none" />
+                      </div>
+                      <div>
+                        <label className="text-xs text-gray-400 block mb-1">Währung</label>
+                        <input type="text" value={editingSecurity.currency || ''} onChange={e => setEditingSecurity({ ...editingSecurity, currency: e.target.value })}
+                          className="w-full bg-dark-700 border border-dark-500 rounded px-2 py-1.5 text-sm text-white disabled:text-gray-500 focus:border-accent-500 focus:outline-none" />
+                      </div>
+                      <div>
+                        <label className="text-xs text-gray-400 block mb-1">Yahoo</label>
+                        <input type="text" value={editingSecurity.yahoo_symbol || ''} onChange={e => setEditingSecurity({ ...editingSecurity, yahoo_symbol: e.target.value })}
+                          className="w-full bg-dark-700 border border-dark-500 rounded px-2 py-1.5 text-sm text-white disabled:text-gray-500 focus:border-accent-500 focus:outline-none" />
+                      </div>
+                      <div>
+                        <label className="text-xs text-gray-400 block mb-1">Alpaca</label>
+                        <input type="text" value={editingSecurity.alpaca_symbol || ''} onChange={e => setEditingSecurity({ ...editingSecurity, alpaca_symbol: e.target.value })}
+                          className="w-full bg-dark-700 border border-dark-500 rounded px-2 py-1.5 text-sm text-white disabled:text-gray-500 focus:border-accent-500 focus:outline-none" />
+                      </div>
+                      <div>
+                        <label className="text-xs text-gray-400 block mb-1">Twelve Data</label>
+                        <input type="text" value={editingSecurity.twelvedata_symbol || ''} onChange={e => setEditingSecurity({ ...editingSecurity, twelvedata_symbol: e.target.value })}
+                          className="w-full bg-dark-700 border border-dark-500 rounded px-2 py-1.5 text-sm text-white disabled:text-gray-500 focus:border-accent-500 focus:outline-none" />
+                      </div>
+                    </div>
+                    <div className="flex gap-2">
+                      <button onClick={saveSecurity} disabled={!editingSecurity.symbol}
+                        className="px-3 py-1.5 text-xs bg-accent-600 hover:bg-accent-500 disabled:bg-dark-600 disabled:text-gray-600 text-white rounded transition-colors">Speichern</button>
+                      <button onClick={() => { setEditingSecurity(null); setSecurityMessage('') }}
+                        className="px-3 py-1.5 text-xs bg-dark-700 hover:bg-dark-600 text-gray-300 rounded transition-colors">Abbrechen</button>
+                    </div>
+                  </div>
+                )}
+
+                <div className="bg-dark-800 rounded-lg border border-dark-600 overflow-hidden max-h-[560px] overflow-y-auto">
+                  <table className="w-full text-sm">
+                    <thead>
+                      <tr className="border-b border-dark-600 text-left text-gray-400">
+                        <th className="px-4 py-3 font-medium">Symbol</th>
+                        <th className="px-4 py-3 font-medium">Name</th>
+                        <th className="px-4 py-3 font-medium">ISIN / WKN</th>
+                        <th className="px-4 py-3 font-medium">Börse</th>
+                        <th className="px-4 py-3 font-medium">Ticker</th>
+                        <th className="px-4 py-3 font-medium">Alpaca</th>
+                      </tr>
+                    </thead>
+                    <tbody>
+                      {securities.length === 0 && (
+                        <tr><td colSpan={6} className="px-4 py-6 text-center text-gray-500">Keine Einträge</td></tr>
+                      )}
+                      {securities.map(sec => (
+                        <tr key={sec.id} onClick={() => setEditingSecurity(sec)} className="border-b border-dark-700 hover:bg-dark-700/50 cursor-pointer">
+                          <td className="px-4 py-2 text-white font-medium">{sec.symbol}</td>
+                          <td className="px-4 py-2 text-gray-300">{sec.name}</td>
+                          <td className="px-4 py-2 text-gray-400 text-xs">{sec.isin || '-'}{sec.wkn ? ` / ${sec.wkn}` : ''}</td>
+                          <td className="px-4 py-2 text-gray-400 text-xs">{sec.exchange || '-'} {sec.currency}</td>
+                          <td className="px-4 py-2 text-gray-400 text-xs">
+                            {[sec.yahoo_symbol && `Y: ${sec.yahoo_symbol}`, sec.alpaca_symbol && `A: ${sec.alpaca_symbol}`, sec.twelvedata_symbol && `TD: ${sec.twelvedata_symbol}`].filter(Boolean).join(', ') || '-'}
+                          </td>
+                          <td className="px-4 py-2 text-xs">
+                            {sec.alpaca_tradable
+                              ? <span className="text-green-400">handelbar{sec.alpaca_fractionable ? ', fraktional' : ''}</span>
+                              : <span className="text-gray-500">nein</span>}
+                          </td>
+                        </tr>
+                      ))}
+                    </tbody>
+                  </table>
+                </div>
+              </div>
+            )}
+
+            {activeTab === 'dataquality' && (
+              <div className="space-y-4">
+                <div className="flex items-center justify-between">
+                  <div>
+                    <h2 className="text-lg font-bold text-white">Datenqualität</h2>
+                    <p className="text-xs text-gray-500">Lücken, Reihenfolge, OHLC-Fehler und Sprünge ohne Volumen (&gt; {dataQuality.sigma} σ). Symbole in Quarantäne werden von Bots und Live-Trading nicht gehandelt.</p>
+                  </div>
+                  <div className="flex items-center gap-3">
+                    <span className="text-xs text-gray-400">{dataQuality.quarantined} in Quarantäne</span>
+                    <label className="flex items-center gap-1.5 text-xs text-gray-300">
+                      <input type="checkbox" checked={dataQualityOnlyQuarantined} onChange={e => { setDataQualityOnlyQuarantined(e.target.checked); fetchDataQuality(e.target.checked) }} />
+                      Nur Quarantäne
+                    </label>
+                    <button onClick={() => fetchDataQuality()} className="px-3 py-1.5 text-xs bg-dark-700 hover:bg-dark-600 text-gray-300 rounded transition-colors">Aktualisieren</button>
+                  </div>
+                </div>
+
+                <div className="bg-dark-800 rounded-lg border border-dark-600 overflow-hidden">
+                  <table className="w-full text-sm">
+                    <thead>
+                      <tr className="border-b border-dark-600 text-left text-gray-400">
+                        <th className="px-4 py-3 font-medium">Symbol</th>
+                        <th className="px-4 py-3 font-medium">Serie</th>
+                        <th className="px-4 py-3 font-medium text-right">Bars</th>
+                        <th className="px-4 py-3 font-medium text-right">Fehler</th>
+                        <th className="px-4 py-3 font-medium text-right">Warnungen</th>
+                        <th className="px-4 py-3 font-medium">Status</th>
+                        <th className="px-4 py-3 font-medium">Geprüft</th>
+                        <th className="px-4 py-3 font-medium"></th>
+                      </tr>
+                    </thead>
+                    <tbody>
+                      {dataQuality.reports.length === 0 && (
+                        <tr><td colSpan={8} className="px-4 py-6 text-center text-gray-500">Keine auffälligen Kursdaten</td></tr>
+                      )}
+                      {dataQuality.reports.map(r => (
+                        <tr key={r.id} className="border-b border-dark-700 hover:bg-dark-700/50 cursor-pointer" onClick={() => openDataQualityReport(r.id)}>
+                          <td className="px-4 py-3 text-white font-medium">{r.symbol}</td>
+                          <td className="px-4 py-3 text-gray-300">{r.namespace}/{r.interval}</td>
+                          <td className="px-4 py-3 text-right text-gray-300">{r.bars}</td>
+                          <td className={`px-4 py-3 text-right ${r.errors > 0 ? 'text-red-400' : 'text-gray-500'}`}>{r.errors}</td>
+                          <td className={`px-4 py-3 text-right ${r.warnings > 0 ? 'text-yellow-400' : 'text-gray-500'}`}>{r.warnings}</td>
+                          <td className="px-4 py-3 text-xs">
+                            {r.quarantined
+                              ? <span className="px-2 py-0.5 rounded bg-red-500/20 text-red-400">Quarantäne</span>
+                              : <span className="px-2 py-0.5 rounded bg-green-500/20 text-green-400">{r.resolved_by ? `Freigegeben (${r.resolved_by})` : 'OK'}</span>}
+                          </td>
+                          <td className="px-4 py-3 text-gray-400 text-xs">{new Date(r.checked_at).toLocaleString('de-DE')}</td>
+                          <td className="px-4 py-3 text-right whitespace-nowrap" onClick={e => e.stopPropagation()}>
+                            {r.quarantined && (
+                              <button onClick={() => dataQualityAction(r.id, 'resolve')} className="px-2 py-1 text-xs bg-green-600/20 hover:bg-green-600/30 text-green-400 rounded mr-1">Freigeben</button>
+                            )}
+                            <button onClick={() => dataQualityAction(r.id, 'recheck')} className="px-2 py-1 text-xs bg-dark-700 hover:bg-dark-600 text-gray-300 rounded mr-1">Prüfen</button>
+                            <button onClick={() => dataQualityAction(r.id, 'delete')} className="px-2 py-1 text-xs bg-red-600/20 hover:bg-red-600/30 text-red-400 rounded">Neu laden</button>
+                          </td>
+                        </tr>
+                      ))}
+                    </tbody>
+                  </table>
+                </div>
+
+                {dataQualityDetail && (
+                  <div className="bg-dark-800 rounded-lg border border-dark-600 overflow-hidden">
+                    <div className="px-4 py-3 border-b border-dark-600 text-sm font-bold text-white">
+                      {dataQualityDetail.symbol} {dataQualityDetail.namespace}/{dataQualityDetail.interval}
+                      {dataQualityDetail.quarantined_at && <span className="ml-2 text-xs font-normal text-gray-500">Quarantäne seit {new Date(dataQualityDetail.quarantined_at).toLocaleString('de-DE')}</span>}
+                    </div>
+                    <table className="w-full text-sm">
+                      <thead>
+                        <tr className="border-b border-dark-600 text-left text-gray-400">
+                          <th className="px-4 py-2 font-medium">Bar</th>
+                          <th className="px-4 py-2 font-medium">Typ</th>
+                          <th className="px-4 py-2 font-medium">Schwere</th>
+                          <th className="px-4 py-2 font-medium">Details</th>
+                        </tr>
+                      </thead>
+                      <tbody>
+                        {(dataQualityDetail.issues || []).map((i, idx) => (
+                          <tr key={idx} className="border-b border-dark-700">
+                            <td className="px-4 py-2 text-gray-400 text-xs">{new Date(i.time * 1000).toLocaleString('de-DE')}</td>
+                            <td className="px-4 py-2 text-gray-300">{i.type}</td>
+                            <td className={`px-4 py-2 text-xs ${i.severity === 'error' ? (dataQualityDetail.acknowledged.includes(`${i.type}|${i.time}`) ? 'text-gray-500' : 'text-red-400') : 'text-yellow-400'}`}>
+                              {i.severity === 'error' ? (dataQualityDetail.acknowledged.includes(`${i.type}|${i.time}`) ? 'Fehler (akzeptiert)' : 'Fehler') : 'Warnung'}
+                            </td>
+                            <td className="px-4 py-2 text-gray-400 text-xs">{i.detail}</td>
+                          </tr>
+                        ))}
+                      </tbody>
+                    </table>
+                  </div>
+                )}
               </div>
             )}
 
@@ -3,6 +3,9 @@ import { useCurrency } from '../context/CurrencyContext'
 import ArenaChart from './ArenaChart'
 import ArenaIndicatorChart from './ArenaIndicatorChart'
 import ArenaBacktestPanel from './ArenaBacktestPanel'
+import FundamentalFilterFields, { fundamentalFilterBody, hasFundamentalFilter } from './FundamentalFilterFields'
+import EarningsGuardFields, { earningsGuardBody, hasEarningsGuard } from './EarningsGuardFields'
+import UniverseSelect from './UniverseSelect'
 
 const BASE_MODES = [
   { value: 'defensive', label: 'Defensiv (FlipperBot)' },
@@ -86,6 +89,10 @@ function BacktestLab({ token, isAdmin }) {
     minWinrate: '', maxWinrate: '', minRR: '', maxRR: '',
     minAvgReturn: '', maxAvgReturn: '', minMarketCap: '50',
   })
+  const [fundamentalFilter, setFundamentalFilter] = useState({})
+  const [earningsGuard, setEarningsGuard] = useState({})
+  const [universeId, setUniverseId] = useState('')
+  const [earningsOpen, setEarningsOpen] = useState(false)
 
   // History
   const [history, setHistory] = useState([])
@@ -93,8 +100,11 @@ function BacktestLab({ token, isAdmin }) {
   const [selectedHistory, setSelectedHistory] = useState([])
 
   const handleFilterChange = (f, v) => setFilters(p => ({ ...p, [f]: v }))
-  const clearFilters = () => setFilters({ minWinrate: '', maxWinrate: '', minRR: '', maxRR: '', minAvgReturn: '', maxAvgReturn: '', minMarketCap: '' })
-  const hasActiveFilters = Object.values(filters).some(v => v !== '')
+  const clearFilters = () => {
+    setFilters({ minWinrate: '', maxWinrate: '', minRR: '', maxRR: '', minAvgReturn: '', maxAvgReturn: '', minMarketCap: '' })
+    setFundamentalFilter({})
+  }
+  const hasActiveFilters = Object.values(filters).some(v => v !== '') || hasFundamentalFilter(fundamentalFilter)
 
   useEffect(() => {
     const fetchStocks = async () => {
@@ -184,6 +194,7 @@ function BacktestLab({ token, isAdmin }) {
           base_mode: baseMode,
           rules,
           tsl,
+          ...earningsGuardBody(earningsGuard),
         }),
       })
       if (res.ok) {
@@ -224,6 +235,9 @@ function BacktestLab({ token, isAdmin }) {
       if (filters.minAvgReturn) body.min_avg_return = parseFloat(filters.minAvgReturn)
       if (filters.maxAvgReturn) body.max_avg_return = parseFloat(filters.maxAvgReturn)
       if (filters.minMarketCap) body.min_market_cap = parseFloat(filters.minMarketCap)
+      Object.assign(body, fundamentalFilterBody(fundamentalFilter))
+      Object.assign(body, earningsGuardBody(earningsGuard))
+      if (universeId) body.universe_id = universeId
 
       const res = await fetch('/api/backtest-lab/batch', {
         method: 'POST',
@@ -453,17 +467,24 @@ function BacktestLab({ token, isAdmin }) {
           </div>
         )}
 
-        {/* Time Range (batch mode) */}
+        {/* Time Range + Universe (batch mode) */}
         {batchMode && (
-          <div className="mb-4">
-            <div className="text-xs text-gray-400 mb-2">Zeitraum</div>
-            <div className="inline-flex bg-dark-700 rounded-lg p-1 border border-dark-600">
-              {TIME_RANGES.map(o => (
-                <button key={o.value} onClick={() => setTimeRange(o.value)}
-                  className={`px-3 py-1.5 text-xs font-medium rounded-md transition-all ${timeRange === o.value ? 'bg-indigo-600 text-white' : 'text-gray-400 hover:text-white hover:bg-dark-600'}`}>
-                  {o.label}
-                </button>
-              ))}
+          <div className="mb-4 flex flex-wrap gap-6">
+            <div>
+              <div className="text-xs text-gray-400 mb-2">Zeitraum</div>
+              <div className="inline-flex bg-dark-700 rounded-lg p-1 border border-dark-600">
+                {TIME_RANGES.map(o => (
+                  <button key={o.value} onClick={() => setTimeRange(o.value)}
+                    className={`px-3 py-1.5 text-xs font-medium rounded-md transition-all ${timeRange === o.value ? 'bg-indigo-600 text-white' : 'text-gray-400 hover:text-white hover:bg-dark-600'}`}>
+                    {o.label}
+                  </button>
+                ))}
+              </div>
+            </div>
+            <div>
+              <div className="text-xs text-gray-400 mb-2">Aktien</div>
+              <UniverseSelect value={universeId} onChange={setUniverseId}
+                className="bg-dark-700 border border-dark-600 rounded-lg px-3 py-2 text-xs text-gray-300 focus:outline-none" />
             </div>
           </div>
         )}
@@ -491,11 +512,30 @@ function BacktestLab({ token, isAdmin }) {
                     Filter zur\u00FCcksetzen
                   </button>
                 </div>
+                <div className="col-span-2 md:col-span-4 mt-2">
+                  <label className="block text-xs text-gray-400 mb-2">Fundamentaldaten</label>
+                  <FundamentalFilterFields value={fundamentalFilter} onChange={setFundamentalFilter} />
+                </div>
               </div>
             )}
           </div>
         )}
 
+        {/* Earnings guard (both modes) */}
+        <div className="mb-4">
+          <button
+            onClick={() => setEarningsOpen(!earningsOpen)}
+            className={`text-xs px-3 py-1.5 rounded border transition-colors ${hasEarningsGuard(earningsGuard) ? 'bg-indigo-600/20 text-indigo-400 border-indigo-500/50' : 'bg-dark-700 text-gray-400 border-dark-600 hover:text-white'}`}
+          >
+            Earnings {hasEarningsGuard(earningsGuard) && '(aktiv)'} {earningsOpen ? '\u25B2' : '\u25BC'}
+          </button>
+          {earningsOpen && (
+            <div className="mt-2">
+              <EarningsGuardFields value={earningsGuard} onChange={setEarningsGuard} />
+            </div>
+          )}
+        </div>
+
         {/* Presets */}
         <div className="mb-4">
           <div className="text-xs text-gray-400 mb-2">Presets</div>
@@ -573,6 +613,11 @@ function BacktestLab({ token, isAdmin }) {
         <>
           <div className="bg-dark-800 rounded-lg border border-dark-600 p-4 mb-4">
             <h3 className="text-sm font-medium text-white mb-2">{selectedSymbol} — Weekly Chart</h3>
+            {(results.earnings_blocked_trades > 0 || results.earnings_exit_trades > 0) && (
+              <div className="text-xs text-gray-500 mb-2">
+                {results.earnings_blocked_trades} Einstiege vor Earnings gesperrt | {results.earnings_exit_trades} Earnings-Exits
+              </div>
+            )}
             <ArenaChart
               symbol={selectedSymbol}
               interval="1wk"
@@ -869,7 +914,7 @@ function BatchResults({ data, sortedResults, formatPrice }) {
           Gesamt-Performance ({data.tested_stocks} Aktien getestet)
         </h3>
         <div className="text-xs text-gray-500 mb-3">
-          {data.total_stocks} Watchlist | {data.filtered_stocks} gefiltert | {data.tested_stocks} getestet | {data.skipped_stocks?.length || 0} \u00FCbersprungen
+          {data.total_stocks} Watchlist | {data.filtered_stocks} gefiltert | {data.tested_stocks} getestet{data.fundamental_filtered_trades > 0 && <> | {data.fundamental_filtered_trades} Trades fundamental gefiltert</>}{data.earnings_blocked_trades > 0 && <> | {data.earnings_blocked_trades} Einstiege vor Earnings gesperrt</>}{data.earnings_exit_trades > 0 && <> | {data.earnings_exit_trades} Earnings-Exits</>}{data.universe_filtered_trades > 0 && <> | {data.universe_filtered_trades} Trades außerhalb Universe</>} | {data.skipped_stocks?.length || 0} \u00FCbersprungen
         </div>
 
         {/* Total Metrics Grid */}
@@ -0,0 +1,76 @@
+// Inputs for the earnings guard shared by bot filters, live trading config and backtest lab.
+// value uses the API field names; empty day counts mean "off".
+
+const toInt = v => (v !== '' && v != null ? parseInt(v, 10) : null)
+
+// earningsGuardBody converts the form values into the API payload
+export function earningsGuardBody(value = {}) {
+  const mode = value.earnings_exit_mode || ''
+  return {
+    earnings_block_days: toInt(value.earnings_block_days),
+    earnings_exit_days: mode ? toInt(value.earnings_exit_days) : null,
+    earnings_exit_mode: mode,
+    earnings_tighten_pct: mode === 'tighten' && value.earnings_tighten_pct !== '' && value.earnings_tighten_pct != null
+      ? parseFloat(value.earnings_tighten_pct) : null,
+  }
+}
+
+export function hasEarningsGuard(value = {}) {
+  const body = earningsGuardBody(value)
+  return body.earnings_block_days != null || (body.earnings_exit_mode !== '' && body.earnings_exit_days != null)
+}
+
+export default function EarningsGuardFields({ value = {}, onChange, compact = false }) {
+  const set = (key, v) => onChange({ ...value, [key]: v })
+  const mode = value.earnings_exit_mode || ''
+  const box = compact ? '' : 'bg-dark-800 rounded-lg p-3'
+
+  return (
+    <div className="space-y-2">
+      <div className="grid grid-cols-2 md:grid-cols-4 gap-3">
+        <div className={box}>
+          <label className="text-xs text-gray-400 block mb-1">Keine Einstiege (Tage vor Earnings)</label>
+          <input type="number" min="0" max="60" step="1" placeholder="-"
+            value={value.earnings_block_days ?? ''}
+            onChange={e => set('earnings_block_days', e.target.value === '' ? null : e.target.value)}
+            className="w-full bg-dark-700 border border-dark-500 rounded px-2 py-1.5 text-white text-sm"
+          />
+        </div>
+        <div className={box}>
+          <label className="text-xs text-gray-400 block mb-1">Offene Positionen</label>
+          <select value={mode} onChange={e => set('earnings_exit_mode', e.target.value)}
+            className="w-full bg-dark-700 border border-dark-500 rounded px-2 py-1.5 text-white text-sm">
+            <option value="">Halten</option>
+            <option value="exit">Vorher schließen</option>
+            <option value="tighten">Stop anziehen</option>
+          </select>
+        </div>
+        {mode && (
+          <div className={box}>
+            <label className="text-xs text-gray-400 block mb-1">Tage vor Earnings</label>
+            <input type="number" min="0" max="60" step="1" placeholder="-"
+              value={value.earnings_exit_days ?? ''}
+              onChange={e => set('earnings_exit_days', e.target.value === '' ? null : e.target.value)}
+              className="w-full bg-dark-700 border border-dark-500 rounded px-2 py-1.5 text-white text-sm"
+            />
+          </div>
+        )}
+        {mode === 'tighten' && (
+          <div className={box}>
+            <label className="text-xs text-gray-400 block mb-1">Max Stop-Abstand (%)</label>
+            <input type="number" min="0.1" step="0.5" placeholder="-"
+              value={value.earnings_tighten_pct ?? ''}
+              onChange={e => set('earnings_tighten_pct', e.target.value === '' ? null : e.target.value)}
+              className="w-full bg-dark-700 border border-dark-500 rounded px-2 py-1.5 text-white text-sm"
+            />
+          </div>
+        )}
+      </div>
+      {!compact && (
+        <p className="text-xs text-gray-500">
+          0 Tage = nur am Earnings-Tag. Backtests nutzen die gespeicherten historischen Earnings-Termine.
+        </p>
+      )}
+    </div>
+  )
+}
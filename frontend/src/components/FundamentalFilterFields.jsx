@@ -0,0 +1,87 @@
+// Inputs for the fundamental filter shared by bot filters, signal list, backtest lab and arena batch.
+// value uses the API field names; empty strings / null mean "no limit".
+
+const TEXT_FIELDS = [
+  { key: 'exclude_sectors', label: 'Sektoren ausschließen', placeholder: 'z.B. Financial Services, Real Estate' },
+  { key: 'sectors', label: 'Nur Sektoren', placeholder: 'alle' },
+  { key: 'exclude_countries', label: 'Länder ausschließen', placeholder: '-' },
+  { key: 'countries', label: 'Nur Länder', placeholder: 'alle' },
+]
+
+const NUMBER_FIELDS = [
+  { key: 'min_pe', label: 'Min KGV', step: '1' },
+  { key: 'max_pe', label: 'Max KGV', step: '1' },
+  { key: 'max_forward_pe', label: 'Max Forward-KGV', step: '1' },
+  { key: 'min_dividend_yield', label: 'Min Div.-Rendite (%)', step: '0.1' },
+  { key: 'min_revenue_growth', label: 'Min Umsatzwachstum (%)', step: '1' },
+  { key: 'min_gross_margin', label: 'Min Bruttomarge (%)', step: '1' },
+  { key: 'min_operating_margin', label: 'Min Op. Marge (%)', step: '1' },
+  { key: 'min_profit_margin', label: 'Min Nettomarge (%)', step: '1' },
+  { key: 'max_debt_to_equity', label: 'Max Debt/Equity', step: '0.1' },
+]
+
+// fundamentalFilterBody converts the form values into the API payload
+export function fundamentalFilterBody(value = {}) {
+  const body = {
+    profitable_only: !!value.profitable_only,
+    require_fundamentals: !!value.require_fundamentals,
+  }
+  for (const f of TEXT_FIELDS) body[f.key] = (value[f.key] || '').trim()
+  for (const f of NUMBER_FIELDS) {
+    const v = value[f.key]
+    body[f.key] = v !== '' && v != null ? parseFloat(v) : null
+  }
+  return body
+}
+
+export function hasFundamentalFilter(value = {}) {
+  return !!value.profitable_only ||
+    TEXT_FIELDS.some(f => (value[f.key] || '').trim() !== '') ||
+    NUMBER_FIELDS.some(f => value[f.key] !== '' && value[f.key] != null)
+}
+
+export default function FundamentalFilterFields({ value = {}, onChange }) {
+  const set = (key, v) => onChange({ ...value, [key]: v })
+
+  return (
+    <div className="space-y-3">
+      <div className="grid grid-cols-1 md:grid-cols-2 gap-3">
+        {TEXT_FIELDS.map(f => (
+          <div key={f.key} className="bg-dark-800 rounded-lg p-3">
+            <label className="text-xs text-gray-400 block mb-1">{f.label}</label>
+            <input type="text" placeholder={f.placeholder}
+              value={value[f.key] ?? ''}
+              onChange={e => set(f.key, e.target.value)}
+              className="w-full bg-dark-700 border border-dark-500 rounded px-2 py-1.5 text-white text-sm"
+            />
+          </div>
+        ))}
+      </div>
+      <div className="grid grid-cols-2 md:grid-cols-3 gap-3">
+        {NUMBER_FIELDS.map(f => (
+          <div key={f.key} className="bg-dark-800 rounded-lg p-3">
+            <label className="text-xs text-gray-400 block mb-1">{f.label}</label>
+            <input type="number" step={f.step} placeholder="-"
+              value={value[f.key] ?? ''}
+              onChange={e => set(f.key, e.target.value === '' ? null : e.target.value)}
+              className="w-full bg-dark-700 border border-dark-500 rounded px-2 py-1.5 text-white text-sm"
+            />
+          </div>
+        ))}
+      </div>
+      <div className="flex flex-wrap gap-4 text-sm text-gray-300">
+        <label className="flex items-center gap-2 cursor-pointer">
+          <input type="checkbox" checked={!!value.profitable_only} onChange={e => set('profitable_only', e.target.checked)} />
+          Nur profitable Unternehmen
+        </label>
+        <label className="flex items-center gap-2 cursor-pointer" title="Ohne diese Option passieren Aktien mit unbekannten Werten den Filter">
+          <input type="checkbox" checked={!!value.require_fundamentals} onChange={e => set('require_fundamentals', e.target.checked)} />
+          Unbekannte Werte ausschließen
+        </label>
+      </div>
+      <p className="text-xs text-gray-500">
+        In Backtests gelten die zum Einstiegszeitpunkt veröffentlichten Zahlen (keine Lookahead-Daten).
+      </p>
+    </div>
+  )
+}
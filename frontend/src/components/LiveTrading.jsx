@@ -5,6 +5,7 @@ import { useCurrency } from '../context/CurrencyContext'
 import ArenaChart from './ArenaChart'
 import ArenaIndicatorChart from './ArenaIndicatorChart'
 import ArenaBacktestPanel from './ArenaBacktestPanel'
+import EarningsGuardFields, { earningsGuardBody } from './EarningsGuardFields'
 import { STRATEGIES, STRATEGY_PARAMS } from '../utils/arenaConfig'
 
 const STRATEGY_LABELS = Object.fromEntries(STRATEGIES.map(s => [s.value, s.label]))
@@ -324,6 +325,9 @@ function LiveTrading({ isAdmin, token }) {
   const [alpacaEnabled, setAlpacaEnabled] = useState(false)
   const [alpacaPaper, setAlpacaPaper] = useState(true)
   const [tradeAmount, setTradeAmount] = useState(500)
+  const [brokerCfg, setBrokerCfg] = useState({ broker: 'alpaca', broker_url: '', broker_account: '', extended_hours: false, capture_ticks: false })
+  const [earningsGuard, setEarningsGuard] = useState({})
+  const [sizing, setSizing] = useState({ sizing_mode: 'amount', risk_percent: 1, equity_percent: 5, vol_target_pct: 2, fixed_shares: 1 })
   const [alpacaAccounts, setAlpacaAccounts] = useState([])
   const [selectedAccountId, setSelectedAccountId] = useState(0)
   const [alpacaPortfolio, setAlpacaPortfolio] = useState(null)
@@ -353,6 +357,13 @@ function LiveTrading({ isAdmin, token }) {
   const debugPollRef = useRef(null)
   const notifyPollRef = useRef(null)
   const lastNotifyLogId = useRef(0)
+  // Server-push event stream (replaces polling while connected)
+  const [streamConnected, setStreamConnected] = useState(false)
+  const streamSeq = useRef(0)
+  const streamEpoch = useRef('')
+  const streamHandlerRef = useRef(null)
+  const [drift, setDrift] = useState(null)
+  const [driftLoading, setDriftLoading] = useState(false)
 
   const headers = token ? { 'Authorization': `Bearer ${token}` } : {}
 
@@ -406,6 +417,9 @@ function LiveTrading({ isAdmin, token }) {
         if (data.alpaca_enabled != null) setAlpacaEnabled(data.alpaca_enabled)
         if (data.alpaca_paper != null) setAlpacaPaper(data.alpaca_paper)
         if (data.trade_amount) setTradeAmount(data.trade_amount)
+        if (data.broker) setBrokerCfg({ broker: data.broker, broker_url: data.broker_url || '', broker_account: data.broker_account || '', extended_hours: !!data.extended_hours, capture_ticks: !!data.capture_ticks })
+        setEarningsGuard(data.earnings_guard || {})
+        if (data.sizing_mode) setSizing({ sizing_mode: data.sizing_mode, risk_percent: data.risk_percent, equity_percent: data.equity_percent, vol_target_pct: data.vol_target_pct, fixed_shares: data.fixed_shares })
         if (data.alpaca_account_id) setSelectedAccountId(data.alpaca_account_id)
         if (data.strategy_symbols) setStrategySymbols(data.strategy_symbols)
       }
@@ -478,6 +492,23 @@ function LiveTrading({ isAdmin, token }) {
     } catch (err) { console.log(`[LT] fetchAlpacaPortfolio error:`, err) }
   }, [token])
 
+  const fetchDrift = useCallback(async (sid, run = false) => {
+    if (!sid) return
+    if (run) setDriftLoading(true)
+    try {
+      const res = await fetch(`/api/trading/live/session/${sid}/drift`, { method: run ? 'POST' : 'GET', headers })
+      const data = await res.json()
+      if (res.ok) setDrift(data)
+      else if (run) alert(data.error || 'Drift-Report fehlgeschlagen')
+    } catch { /* ignore */ }
+    if (run) setDriftLoading(false)
+  }, [token])
+
+  useEffect(() => {
+    setDrift(null)
+    fetchDrift(urlSessionId)
+  }, [urlSessionId])
+
   // Load on mount / when URL session changes
   useEffect(() => {
     console.log(`[LT] EFFECT:mount urlSessionId=${urlSessionId}`)
@@ -518,7 +549,7 @@ function LiveTrading({ isAdmin, token }) {
   // Poll status + sessions when session is active
   useEffect(() => {
     console.log(`[LT] EFFECT:statusPoll is_running=${status?.is_running}`)
-    if (!status?.is_running) {
+    if (!status?.is_running || streamConnected) {
       if (pollRef.current) clearInterval(pollRef.current)
       return
     }
@@ -527,7 +558,7 @@ function LiveTrading({ isAdmin, token }) {
       fetchSessions()
     }, 5000)
     return () => clearInterval(pollRef.current)
-  }, [status?.is_running])
+  }, [status?.is_running, streamConnected])
 
   // Poll positions when session is active
   useEffect(() => {
@@ -537,9 +568,10 @@ function LiveTrading({ isAdmin, token }) {
       return
     }
     fetchPositions(urlSessionId)
+    if (streamConnected) return
     posPollRef.current = setInterval(() => fetchPositions(urlSessionId), 10000)
     return () => clearInterval(posPollRef.current)
-  }, [status?.is_running, urlSessionId])
+  }, [status?.is_running, urlSessionId, streamConnected])
 
   // Debug log polling
   useEffect(() => {
@@ -565,9 +597,10 @@ function LiveTrading({ isAdmin, token }) {
       } catch { /* ignore */ }
     }
     fetchLogs()
+    if (streamConnected) return
     debugPollRef.current = setInterval(fetchLogs, 5000)
     return () => clearInterval(debugPollRef.current)
-  }, [showDebug, urlSessionId])
+  }, [showDebug, urlSessionId, streamConnected])
 
   // Trade event notification polling (independent of debug panel)
   const playNotificationSound = useCallback((isWin) => {
@@ -607,6 +640,19 @@ function LiveTrading({ isAdmin, token }) {
     setNotificationsEnabled(true) // enable sound even without browser notification permission
   }, [])
 
+  const notifyTradeEvent = useCallback((evt) => {
+    const isOpen = evt.level === 'OPEN'
+    const isWin = evt.level === 'TP' || (evt.level === 'CLOSE' && evt.message.includes('+'))
+    playNotificationSound(isOpen || isWin)
+    if ('Notification' in window && Notification.permission === 'granted') {
+      const icon = isOpen ? 'OPEN' : evt.level === 'TP' ? 'TP' : evt.level === 'SL' ? 'SL' : 'CLOSE'
+      new Notification(`${icon} ${evt.symbol}`, {
+        body: evt.message,
+        tag: `trade-${evt.id}`,
+      })
+    }
+  }, [playNotificationSound])
+
   useEffect(() => {
     if (notifyPollRef.current) clearInterval(notifyPollRef.current)
     if (!notificationsEnabled || !status?.is_running || !urlSessionId) return
@@ -627,26 +673,111 @@ function LiveTrading({ isAdmin, token }) {
           return
         }
         lastNotifyLogId.current = maxId
-        const tradeEvents = logs.filter(l => TRADE_LEVELS.has(l.level))
-        for (const evt of tradeEvents) {
-          const isOpen = evt.level === 'OPEN'
-          const isWin = evt.level === 'TP' || (evt.level === 'CLOSE' && evt.message.includes('+'))
-          playNotificationSound(isOpen || isWin)
-          if ('Notification' in window && Notification.permission === 'granted') {
-            const icon = isOpen ? 'OPEN' : evt.level === 'TP' ? 'TP' : evt.level === 'SL' ? 'SL' : 'CLOSE'
-            new Notification(`${icon} ${evt.symbol}`, {
-              body: evt.message,
-              icon: isOpen || isWin ? undefined : undefined,
-              tag: `trade-${evt.id}`,
-            })
-          }
-        }
+        logs.filter(l => TRADE_LEVELS.has(l.level)).forEach(notifyTradeEvent)
       } catch { /* ignore */ }
     }
+    if (streamConnected) return // trade events arrive via the event stream
     checkTradeEvents()
     notifyPollRef.current = setInterval(checkTradeEvents, 5000)
     return () => clearInterval(notifyPollRef.current)
-  }, [notificationsEnabled, status?.is_running, urlSessionId])
+  }, [notificationsEnabled, status?.is_running, urlSessionId, streamConnected])
+
+  // Handle one pushed event — re-assigned every render so it sees current state
+  streamHandlerRef.current = (evt) => {
+    const ownSession = String(evt.session_id) === String(urlSessionId)
+    switch (evt.type) {
+      case 'session_started':
+      case 'session_stopped':
+        fetchStatus()
+        fetchSessions()
+        break
+      case 'scan_progress':
+      case 'ws_state':
+        setStatus(prev => {
+          if (!prev) return prev
+          const patch = evt.type === 'scan_progress'
+            ? { scan_progress_current: evt.data?.progress, scan_progress_total: evt.data?.total, current_symbol: evt.data?.done ? '' : (evt.symbol || ''), is_polling: !evt.data?.done }
+            : { ws_connected: evt.data?.connected }
+          const active_sessions = (prev.active_sessions || []).map(s => s.session_id === evt.session_id ? { ...s, ...patch } : s)
+          const flat = prev.session_id === evt.session_id ? patch : {}
+          return { ...prev, ...flat, active_sessions }
+        })
+        break
+      case 'position_opened':
+      case 'position_closed':
+      case 'sltp_hit':
+      case 'order_filled':
+        if (ownSession) fetchPositions(urlSessionId)
+        fetchStatus()
+        if (notificationsEnabled && ownSession && evt.type !== 'order_filled') notifyTradeEvent({ ...evt, id: evt.log_id || evt.seq })
+        break
+      default:
+        break
+    }
+    if (showDebug && ownSession && evt.log_id) {
+      const logEntry = { id: evt.log_id, session_id: evt.session_id, level: evt.level, symbol: evt.symbol, message: evt.message, strategy: evt.strategy, created_at: evt.time }
+      setDebugLogs(prev => prev.some(l => l.id === logEntry.id) ? prev : [logEntry, ...prev])
+      setLastLogId(prev => Math.max(prev, evt.log_id))
+    }
+    // Keep the polling fallback from re-notifying events the stream already delivered
+    if (evt.log_id && lastNotifyLogId.current > 0) lastNotifyLogId.current = Math.max(lastNotifyLogId.current, evt.log_id)
+  }
+
+  // Live event stream (SSE) — reconnects and replays from the last sequence number
+  useEffect(() => {
+    if (!status?.is_running || !token) return
+    const controller = new AbortController()
+    let retryTimer = null
+    const connect = async () => {
+      try {
+        const params = new URLSearchParams()
+        if (streamSeq.current) params.set('since', streamSeq.current)
+        if (streamEpoch.current) params.set('epoch', streamEpoch.current)
+        const res = await fetch(`/api/trading/live/events?${params}`, { headers, signal: controller.signal })
+        if (!res.ok || !res.body) throw new Error(`stream ${res.status}`)
+        const reader = res.body.getReader()
+        const decoder = new TextDecoder()
+        let buffer = ''
+        while (true) {
+          const { done, value } = await reader.read()
+          if (done) break
+          buffer += decoder.decode(value, { stream: true })
+          const lines = buffer.split('\n')
+          buffer = lines.pop()
+          for (const line of lines) {
+            if (!line.startsWith('data: ')) continue
+            try {
+              const msg = JSON.parse(line.slice(6))
+              if (msg.type === 'hello') {
+                streamEpoch.current = msg.epoch
+                setStreamConnected(true)
+                if (msg.resync) {
+                  // Missed events could not be replayed — reload everything via REST
+                  fetchStatus()
+                  fetchSessions()
+                  if (urlSessionId) fetchPositions(urlSessionId)
+                }
+                continue
+              }
+              if (msg.seq) streamSeq.current = Math.max(streamSeq.current, msg.seq)
+              streamHandlerRef.current?.(msg)
+            } catch { /* ignore malformed line */ }
+          }
+        }
+      } catch (err) {
+        if (controller.signal.aborted) return
+        console.log('[LT] event stream error:', err)
+      }
+      setStreamConnected(false)
+      if (!controller.signal.aborted) retryTimer = setTimeout(connect, 3000)
+    }
+    connect()
+    return () => {
+      controller.abort()
+      clearTimeout(retryTimer)
+      setStreamConnected(false)
+    }
+  }, [status?.is_running, token])
 
   const goLive = async () => {
     if (!urlSessionId) return
@@ -1276,6 +1407,61 @@ function LiveTrading({ isAdmin, token }) {
               <input type="number" value={tradeAmount} onChange={e => setTradeAmount(Number(e.target.value) || 0)}
                 min="1" step="50" className="w-full bg-dark-700 border border-dark-500 rounded px-3 py-2 text-sm text-white focus:border-accent-500 focus:outline-none" />
             </div>
+            <div className="w-32">
+              <label className="text-xs text-gray-500 block mb-1">Broker</label>
+              <select value={brokerCfg.broker} onChange={e => setBrokerCfg(b => ({ ...b, broker: e.target.value }))}
+                className="w-full bg-dark-700 border border-dark-500 rounded px-3 py-2 text-sm text-white focus:border-accent-500 focus:outline-none">
+                <option value="alpaca">Alpaca</option>
+                <option value="ibkr">IBKR</option>
+              </select>
+            </div>
+            {brokerCfg.broker === 'ibkr' && (
+              <>
+                <div className="w-56">
+                  <label className="text-xs text-gray-500 block mb-1">Gateway-URL</label>
+                  <input type="text" value={brokerCfg.broker_url} placeholder="https://localhost:5000/v1/api" onChange={e => setBrokerCfg(b => ({ ...b, broker_url: e.target.value }))}
+                    className="w-full bg-dark-700 border border-dark-500 rounded px-3 py-2 text-sm text-white focus:border-accent-500 focus:outline-none" />
+                </div>
+                <div className="w-32">
+                  <label className="text-xs text-gray-500 block mb-1">Konto</label>
+                  <input type="text" value={brokerCfg.broker_account} placeholder="U1234567" onChange={e => setBrokerCfg(b => ({ ...b, broker_account: e.target.value }))}
+                    className="w-full bg-dark-700 border border-dark-500 rounded px-3 py-2 text-sm text-white focus:border-accent-500 focus:outline-none" />
+                </div>
+              </>
+            )}
+            <label className="flex items-center gap-1.5 text-xs text-gray-400 pb-2" title="Pre-/Post-Market handeln (Limit-Orders)">
+              <input type="checkbox" checked={brokerCfg.extended_hours} onChange={e => setBrokerCfg(b => ({ ...b, extended_hours: e.target.checked }))} />
+              Extended Hours
+            </label>
+            <label className="flex items-center gap-1.5 text-xs text-gray-400 pb-2" title="Trades und Quotes offener SL/TP-Positionen streamen und speichern; SL/TP wird pro Tick geprüft">
+              <input type="checkbox" checked={brokerCfg.capture_ticks} onChange={e => setBrokerCfg(b => ({ ...b, capture_ticks: e.target.checked }))} />
+              Tick-Daten
+            </label>
+            <div className="w-full">
+              <label className="text-xs text-gray-500 block mb-1">Earnings</label>
+              <EarningsGuardFields value={earningsGuard} onChange={setEarningsGuard} compact />
+            </div>
+            <div className="w-40">
+              <label className="text-xs text-gray-500 block mb-1">Positionsgröße</label>
+              <select value={sizing.sizing_mode} onChange={e => setSizing(s => ({ ...s, sizing_mode: e.target.value }))}
+                className="w-full bg-dark-700 border border-dark-500 rounded px-3 py-2 text-sm text-white focus:border-accent-500 focus:outline-none">
+                <option value="amount">Fester Betrag</option>
+                <option value="risk">Risiko pro Trade</option>
+                <option value="equity_pct">% der Equity</option>
+                <option value="volatility">Volatilität (ATR)</option>
+                <option value="fixed_shares">Feste Stückzahl</option>
+              </select>
+            </div>
+            {sizing.sizing_mode !== 'amount' && (() => {
+              const field = { risk: ['risk_percent', 'Risiko (%)'], equity_pct: ['equity_percent', 'Equity (%)'], volatility: ['vol_target_pct', 'Ziel-ATR (%)'], fixed_shares: ['fixed_shares', 'Stück'] }[sizing.sizing_mode]
+              return (
+                <div className="w-28">
+                  <label className="text-xs text-gray-500 block mb-1">{field[1]}</label>
+                  <input type="number" value={sizing[field[0]] ?? ''} onChange={e => setSizing(s => ({ ...s, [field[0]]: Number(e.target.value) || 0 }))}
+                    min="0" step="0.1" className="w-full bg-dark-700 border border-dark-500 rounded px-3 py-2 text-sm text-white focus:border-accent-500 focus:outline-none" />
+                </div>
+              )
+            })()}
             <button
               onClick={async () => {
                 try {
@@ -1289,6 +1475,9 @@ function LiveTrading({ isAdmin, token }) {
                       alpaca_account_id: selectedAccountId || 0,
                       alpaca_enabled: selectedAccountId > 0,
                       trade_amount: tradeAmount,
+                      ...sizing,
+                      ...brokerCfg,
+                      earnings_guard: earningsGuardBody(earningsGuard),
                     })
                   })
                   if (urlSessionId) {
@@ -1726,6 +1915,59 @@ function LiveTrading({ isAdmin, token }) {
         </div>
       )}
 
+      {/* Live vs Backtest Drift */}
+      {urlSessionId && (status?.is_running || positions.length > 0) && (
+        <div className="bg-dark-800 rounded-lg border border-dark-600 p-4 mb-4">
+          <div className="flex items-center justify-between mb-3">
+            <h3 className="text-sm font-medium text-white">Live vs. Backtest Drift</h3>
+            {isAdmin && (
+              <button onClick={() => fetchDrift(urlSessionId, true)} disabled={driftLoading}
+                className="px-2 py-1 text-[10px] rounded bg-dark-700 border border-dark-600 text-gray-300 hover:text-white disabled:opacity-50">
+                {driftLoading ? 'Berechne...' : 'Jetzt berechnen'}
+              </button>
+            )}
+          </div>
+          {!drift?.latest?.length ? (
+            <div className="text-xs text-gray-500">Noch kein Drift-Report — wird alle 6h automatisch berechnet.</div>
+          ) : (
+            <div className="overflow-x-auto">
+              <table className="w-full text-xs">
+                <thead>
+                  <tr className="text-gray-500 text-left">
+                    <th className="py-1 pr-3">Strategie</th>
+                    <th className="py-1 pr-3 text-right">Match</th>
+                    <th className="py-1 pr-3 text-right">Verpasst</th>
+                    <th className="py-1 pr-3 text-right">Zusätzlich</th>
+                    <th className="py-1 pr-3 text-right">Slippage Entry</th>
+                    <th className="py-1 pr-3 text-right">Slippage Exit</th>
+                    <th className="py-1 pr-3 text-right">Rendite Live / BT</th>
+                    <th className="py-1 pr-3 text-right">P&L Diff</th>
+                    <th className="py-1 text-right">Stand</th>
+                  </tr>
+                </thead>
+                <tbody>
+                  {drift.latest.map(({ report: r }) => (
+                    <tr key={r.id} className="border-t border-dark-700 text-gray-300">
+                      <td className="py-1 pr-3 text-white">{STRATEGY_LABELS[r.strategy] || r.strategy}</td>
+                      <td className={`py-1 pr-3 text-right ${r.match_rate >= 90 ? 'text-green-400' : r.match_rate >= 70 ? 'text-yellow-400' : 'text-red-400'}`}>
+                        {r.matched_trades}/{r.backtest_trades} ({r.match_rate.toFixed(0)}%)
+                      </td>
+                      <td className="py-1 pr-3 text-right">{r.missed_signals}</td>
+                      <td className="py-1 pr-3 text-right">{r.extra_trades}</td>
+                      <td className={`py-1 pr-3 text-right ${r.avg_entry_slippage_pct > 0 ? 'text-red-400' : 'text-green-400'}`}>{r.avg_entry_slippage_pct.toFixed(2)}%</td>
+                      <td className={`py-1 pr-3 text-right ${r.avg_exit_slippage_pct > 0 ? 'text-red-400' : 'text-green-400'}`}>{r.avg_exit_slippage_pct.toFixed(2)}%</td>
+                      <td className="py-1 pr-3 text-right">{r.live_return_pct.toFixed(2)}% / {r.backtest_return_pct.toFixed(2)}%</td>
+                      <td className={`py-1 pr-3 text-right ${r.pnl_diff >= 0 ? 'text-green-400' : 'text-red-400'}`}>{r.pnl_diff >= 0 ? '+' : ''}{r.pnl_diff.toFixed(2)}</td>
+                      <td className="py-1 text-right text-gray-500">{new Date(r.created_at).toLocaleString('de-DE')}</td>
+                    </tr>
+                  ))}
+                </tbody>
+              </table>
+            </div>
+          )}
+        </div>
+      )}
+
       {/* Session Statistiken (zugeklappt) */}
       {(status?.is_running || positions.length > 0) && (() => {
         const allPos = positions
@@ -2307,9 +2549,12 @@ function LiveTrading({ isAdmin, token }) {
               ERROR: 'text-orange-400',
               TRADE: 'text-purple-400',
               ALPACA: 'text-purple-400',
+              SIZING: 'text-indigo-400',
+              IBKR: 'text-rose-400',
               REFRESH: 'text-cyan-400',
               DEBUG: 'text-teal-400',
               DATA_MISMATCH: 'text-orange-500',
+              DRIFT: 'text-amber-400',
             }
             const levelBg = {
               SCAN: 'bg-blue-500/20 border-blue-500/30',
@@ -2324,9 +2569,12 @@ function LiveTrading({ isAdmin, token }) {
               ERROR: 'bg-orange-500/20 border-orange-500/30',
               TRADE: 'bg-purple-500/20 border-purple-500/30',
               ALPACA: 'bg-purple-500/20 border-purple-500/30',
+              SIZING: 'bg-indigo-500/20 border-indigo-500/30',
+              IBKR: 'bg-rose-500/20 border-rose-500/30',
               REFRESH: 'bg-cyan-500/20 border-cyan-500/30',
               DEBUG: 'bg-teal-500/20 border-teal-500/30',
               DATA_MISMATCH: 'bg-orange-500/30 border-orange-500/40',
+              DRIFT: 'bg-amber-500/20 border-amber-500/30',
             }
             const allLevels = [...new Set(debugLogs.map(l => l.level))].sort()
             const searchLower = debugSearch.toLowerCase()
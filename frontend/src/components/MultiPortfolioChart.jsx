@@ -15,6 +15,12 @@ const PORTFOLIO_COLORS = [
   '#a855f7', // purple
 ]
 
+// Further portfolios of a user are labeled with their name, the default one with the username
+export function portfolioLabel(portfolio) {
+  if (portfolio.is_default !== false || !portfolio.portfolio_name) return portfolio.username
+  return `${portfolio.username} · ${portfolio.portfolio_name}`
+}
+
 export function getPortfolioColor(index) {
   return PORTFOLIO_COLORS[index % PORTFOLIO_COLORS.length]
 }
@@ -154,12 +160,12 @@ function MultiPortfolioChart({ token, height = 300, portfolios = [], onColorMap,
         const legend = []
         data.forEach((portfolio, index) => {
           const color = getPortfolioColor(index)
-          colorMap[portfolio.user_id] = color
+          colorMap[portfolio.portfolio_id] = color
 
           const series = chartRef.current.addLineSeries({
             color: color,
             lineWidth: 2,
-            visible: !hiddenUsers.has(portfolio.user_id),
+            visible: !hiddenUsers.has(portfolio.portfolio_id),
             priceLineVisible: false,
             lastValueVisible: false,
             priceFormat: {
@@ -176,11 +182,11 @@ function MultiPortfolioChart({ token, height = 300, portfolios = [], onColorMap,
           }))
 
           const lastPct = chartData.length > 0 ? chartData[chartData.length - 1].value : 0
-          legend.push({ name: portfolio.username, color, pct: lastPct, userId: portfolio.user_id })
+          legend.push({ name: portfolioLabel(portfolio), color, pct: lastPct, userId: portfolio.portfolio_id })
 
           series.setData(chartData)
           seriesRef.current.push(series)
-          userSeriesMapRef.current[portfolio.user_id] = series
+          userSeriesMapRef.current[portfolio.portfolio_id] = series
         })
         setLegendItems(legend)
 
@@ -1,15 +1,19 @@
 import { useEffect, useRef, useState } from 'react'
 import { createChart } from 'lightweight-charts'
 
-function PortfolioChart({ token, height = 300, botType = null, title = "Portfolio Performance", userId = null, extraParams = '' }) {
+function PortfolioChart({ token, height = 300, botType = null, title = "Portfolio Performance", userId = null, portfolioId = null, extraParams = '' }) {
   const chartContainerRef = useRef(null)
   const chartRef = useRef(null)
-  const seriesRef = useRef({ live: null, sim: null })
+  const seriesRef = useRef({ live: null, sim: null, benchmark: null })
   const [period, setPeriod] = useState('1m')
   const [loading, setLoading] = useState(true)
   const [error, setError] = useState(null)
   const [hasLive, setHasLive] = useState(false)
   const [hasSim, setHasSim] = useState(false)
+  const [benchmark, setBenchmark] = useState('')
+  const [benchmarkPresets, setBenchmarkPresets] = useState([])
+  const [benchmarksLoaded, setBenchmarksLoaded] = useState(false)
+  const [hasBenchmark, setHasBenchmark] = useState(false)
 
   const periodLabels = {
     '1w': 'Woche',
@@ -67,11 +71,48 @@ function PortfolioChart({ token, height = 300, botType = null, title = "Portfoli
   }, [height])
 
   useEffect(() => {
-    if (!chartRef.current || !token) return
+    if (!token) return
+    fetch('/api/benchmarks', { headers: { 'Authorization': `Bearer ${token}` } })
+      .then(res => res.ok ? res.json() : null)
+      .then(data => {
+        if (data) {
+          setBenchmarkPresets(data.presets || [])
+          setBenchmark(data.selected || '')
+        }
+      })
+      .catch(() => {})
+      .finally(() => setBenchmarksLoaded(true))
+  }, [token])
+
+  // Dashed line of the benchmark's change since the first point
+  const drawBenchmark = (chart, data) => {
+    const points = (data || []).filter(p => p.benchmark_pct !== undefined)
+    setHasBenchmark(points.length > 0)
+    if (points.length === 0) return
+    const benchSeries = chart.addLineSeries({
+      color: '#f59e0b',
+      lineWidth: 1,
+      lineStyle: 2,
+      lastValueVisible: false,
+      priceLineVisible: false,
+      priceFormat: {
+        type: 'custom',
+        formatter: (price) => price.toFixed(2) + '%',
+        minMove: 0.01,
+      },
+    })
+    benchSeries.setData(points.map(p => ({ time: p.time, value: p.benchmark_pct })))
+    seriesRef.current.benchmark = benchSeries
+  }
+
+  useEffect(() => {
+    if (!chartRef.current || !token || !benchmarksLoaded) return
+    const benchmarkQuery = benchmark ? `&benchmark=${encodeURIComponent(benchmark)}` : ''
 
     const fetchData = async () => {
       setLoading(true)
       setError(null)
+      setHasBenchmark(false)
 
       try {
         const chart = chartRef.current
@@ -85,14 +126,18 @@ function PortfolioChart({ token, height = 300, botType = null, title = "Portfoli
           try { chart.removeSeries(seriesRef.current.sim) } catch (e) {}
           seriesRef.current.sim = null
         }
+        if (seriesRef.current.benchmark) {
+          try { chart.removeSeries(seriesRef.current.benchmark) } catch (e) {}
+          seriesRef.current.benchmark = null
+        }
 
         // For bot charts: fetch both live + simulation in parallel (unless extraParams forces single mode)
         if (botType && !extraParams) {
           const [liveRes, simRes] = await Promise.all([
-            fetch(`/api/${botType}/history?period=${period}&live=true`, {
+            fetch(`/api/${botType}/history?period=${period}&live=true${benchmarkQuery}`, {
               headers: { 'Authorization': `Bearer ${token}` }
             }),
-            fetch(`/api/${botType}/history?period=${period}&live=false`, {
+            fetch(`/api/${botType}/history?period=${period}&live=false${benchmarkQuery}`, {
               headers: { 'Authorization': `Bearer ${token}` }
             })
           ])
@@ -145,6 +190,8 @@ function PortfolioChart({ token, height = 300, botType = null, title = "Portfoli
             seriesRef.current.live = liveSeries
           }
 
+          drawBenchmark(chart, hasLiveData ? liveData : simData)
+
           // Baseline at 0%
           const baseSeries = seriesRef.current.live || seriesRef.current.sim
           if (baseSeries) {
@@ -158,14 +205,16 @@ function PortfolioChart({ token, height = 300, botType = null, title = "Portfoli
             })
           }
         } else {
-          // Single line mode: user portfolio OR bot with extraParams (AdminPanel)
+          // Single line mode: user portfolio, a specific portfolio OR bot with extraParams (AdminPanel)
           let endpoint
           if (botType && extraParams) {
-            endpoint = `/api/${botType}/history?period=${period}&${extraParams}`
+            endpoint = `/api/${botType}/history?period=${period}&${extraParams}${benchmarkQuery}`
+          } else if (portfolioId) {
+            endpoint = `/api/portfolio/history?period=${period}&portfolio=${portfolioId}${benchmarkQuery}`
           } else if (userId) {
-            endpoint = `/api/portfolios/history/${userId}?period=${period}`
+            endpoint = `/api/portfolios/history/${userId}?period=${period}${benchmarkQuery}`
           } else {
-            endpoint = `/api/portfolio/history?period=${period}`
+            endpoint = `/api/portfolio/history?period=${period}${benchmarkQuery}`
           }
 
           const res = await fetch(endpoint, {
@@ -196,6 +245,7 @@ function PortfolioChart({ token, height = 300, botType = null, title = "Portfoli
           })
           series.setData(data.map(p => ({ time: p.time, value: p.pct })))
           seriesRef.current.live = series
+          drawBenchmark(chart, data)
 
           series.createPriceLine({
             price: 0,
@@ -217,14 +267,28 @@ function PortfolioChart({ token, height = 300, botType = null, title = "Portfoli
     }
 
     fetchData()
-  }, [period, userId, token, botType, extraParams])
+  }, [period, userId, portfolioId, token, botType, extraParams, benchmark, benchmarksLoaded])
 
   return (
     <div className="bg-dark-800 rounded-xl border border-dark-600 overflow-hidden">
       {/* Header with period selector */}
       <div className="flex flex-col sm:flex-row sm:items-center justify-between p-4 border-b border-dark-600 gap-3">
         <h3 className="text-white font-semibold">{title}</h3>
-        <div className="flex flex-wrap gap-1">
+        <div className="flex flex-wrap items-center gap-1">
+          <select
+            value={benchmark}
+            onChange={(e) => setBenchmark(e.target.value)}
+            className="bg-dark-700 text-gray-300 text-xs md:text-sm rounded-md px-2 py-1 mr-1 border border-dark-600"
+            title="Benchmark"
+          >
+            <option value="">Kein Benchmark</option>
+            {benchmarkPresets.map(b => (
+              <option key={b.symbol} value={b.symbol}>{b.name}</option>
+            ))}
+            {benchmark && !benchmarkPresets.some(b => b.symbol === benchmark) && (
+              <option value={benchmark}>{benchmark}</option>
+            )}
+          </select>
           {Object.entries(periodLabels).map(([key, label]) => (
             <button
               key={key}
@@ -276,6 +340,12 @@ function PortfolioChart({ token, height = 300, botType = null, title = "Portfoli
         ) : (
           <span>Performance in % seit Kauf</span>
         )}
+        {hasBenchmark && (
+          <div className="flex items-center gap-1.5">
+            <span className="w-3 h-0.5 bg-amber-500 rounded-full inline-block"></span>
+            {benchmarkPresets.find(b => b.symbol === benchmark)?.name || benchmark}
+          </div>
+        )}
       </div>
     </div>
   )
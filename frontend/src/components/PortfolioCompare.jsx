@@ -1,7 +1,7 @@
 import { useState, useEffect, useCallback, useMemo } from 'react'
 import { Link } from 'react-router-dom'
 import { useCurrency } from '../context/CurrencyContext'
-import MultiPortfolioChart, { getPortfolioColor } from './MultiPortfolioChart'
+import MultiPortfolioChart, { getPortfolioColor, portfolioLabel } from './MultiPortfolioChart'
 
 function PortfolioCompare({ user, isAdmin }) {
   const token = localStorage.getItem('authToken')
@@ -64,19 +64,20 @@ function PortfolioCompareContent({ token, user, isAdmin }) {
     setHistoryData(data || [])
   }, [])
 
-  // Get period-specific return for a user
-  const getPeriodReturn = useCallback((userId) => {
-    const entry = historyData.find(h => h.user_id === userId)
+  // Get period-specific return for a portfolio
+  const getPeriodReturn = useCallback((portfolioId) => {
+    const entry = historyData.find(h => h.portfolio_id === portfolioId)
     if (entry && entry.period_return_pct !== undefined) {
       return entry.period_return_pct
     }
-    const portfolio = portfolios.find(p => p.user_id === userId)
-    return portfolio ? portfolio.total_return_pct : 0
+    const portfolio = portfolios.find(p => p.portfolio_id === portfolioId)
+    if (!portfolio) return 0
+    return portfolio.twr_pct !== undefined ? portfolio.twr_pct : portfolio.total_return_pct
   }, [historyData, portfolios])
 
   // Sort portfolios by period return for ranking
   const rankedPortfolios = useMemo(() => {
-    return [...portfolios].sort((a, b) => getPeriodReturn(b.user_id) - getPeriodReturn(a.user_id))
+    return [...portfolios].sort((a, b) => getPeriodReturn(b.portfolio_id) - getPeriodReturn(a.portfolio_id))
   }, [portfolios, getPeriodReturn])
 
   useEffect(() => {
@@ -85,12 +86,12 @@ function PortfolioCompareContent({ token, user, isAdmin }) {
 
   const fetchPortfolios = async () => {
     try {
-      const res = await fetch('/api/portfolios/compare', {
+      const res = await fetch('/api/portfolios/compare?benchmark=default', {
         headers: { 'Authorization': `Bearer ${token}` }
       })
       const data = await res.json()
-      // Sort by total return descending
-      data.sort((a, b) => b.total_return_pct - a.total_return_pct)
+      // Sort by time-weighted return descending (independent of deposit timing)
+      data.sort((a, b) => (b.twr_pct ?? b.total_return_pct) - (a.twr_pct ?? a.total_return_pct))
       setPortfolios(data)
     } catch (err) {
       console.error('Failed to fetch portfolios:', err)
@@ -106,7 +107,7 @@ function PortfolioCompareContent({ token, user, isAdmin }) {
   }
 
   // Get max absolute return for chart scaling
-  const maxReturn = Math.max(...portfolios.map(p => Math.abs(getPeriodReturn(p.user_id))), 10)
+  const maxReturn = Math.max(...portfolios.map(p => Math.abs(getPeriodReturn(p.portfolio_id))), 10)
 
   // Calculate bar width percentage (for half of the container since 0 is in the middle)
   const getBarWidth = (returnPct) => {
@@ -187,19 +188,19 @@ function PortfolioCompareContent({ token, user, isAdmin }) {
               </div>
               <div className="space-y-3">
                 {rankedPortfolios.map((portfolio, index) => {
-                  const lineColor = colorMap[portfolio.user_id] || getPortfolioColor(index)
-                  const periodReturn = getPeriodReturn(portfolio.user_id)
+                  const lineColor = colorMap[portfolio.portfolio_id] || getPortfolioColor(index)
+                  const periodReturn = getPeriodReturn(portfolio.portfolio_id)
 
                   return (
                     <div
-                      key={portfolio.user_id}
+                      key={portfolio.portfolio_id}
                       className="flex items-center gap-3 p-2 -m-2 rounded-lg"
                     >
                       {/* Color indicator */}
                       <div
                         className="w-4 h-4 rounded-full shrink-0"
                         style={{ backgroundColor: lineColor }}
-                        title={`Linienfarbe: ${portfolio.username}`}
+                        title={`Linienfarbe: ${portfolioLabel(portfolio)}`}
                       />
 
                       {/* Rank */}
@@ -214,7 +215,7 @@ function PortfolioCompareContent({ token, user, isAdmin }) {
 
                       {/* Username */}
                       <div className="w-24 md:w-32 truncate text-sm text-white font-medium text-left">
-                        {portfolio.username}{isAdmin && !portfolio.visible_in_ranking && ' 👻'}
+                        {portfolioLabel(portfolio)}{isAdmin && !portfolio.visible_in_ranking && ' 👻'}
                       </div>
 
                       {/* Bar Chart - centered at 0 */}
@@ -247,6 +248,19 @@ function PortfolioCompareContent({ token, user, isAdmin }) {
                       <div className="hidden md:block w-20 text-right text-xs text-gray-500">
                         {portfolio.position_count} Aktie{portfolio.position_count !== 1 ? 'n' : ''}
                       </div>
+
+                      {/* Alpha/beta against the benchmark since the first transaction */}
+                      <div
+                        className="hidden md:block w-32 text-right text-xs text-gray-500"
+                        title={portfolio.benchmark ? `vs. ${portfolio.benchmark.name} · Tracking Error ${formatPercent(portfolio.benchmark.tracking_error)} · IR ${portfolio.benchmark.information_ratio.toFixed(2)} · Rel. Drawdown ${formatPercent(portfolio.benchmark.relative_max_drawdown)}` : ''}
+                      >
+                        {portfolio.benchmark ? (
+                          <>
+                            <span className={portfolio.benchmark.alpha >= 0 ? 'text-green-400' : 'text-red-400'}>α {formatPercent(portfolio.benchmark.alpha)}</span>
+                            {' '}· β {portfolio.benchmark.beta.toFixed(2)}
+                          </>
+                        ) : '--'}
+                      </div>
                     </div>
                   )
                 })}
@@ -263,12 +277,12 @@ function PortfolioCompareContent({ token, user, isAdmin }) {
               {/* Mobile Card View */}
               <div className="md:hidden">
                 {portfolios.map((portfolio, index) => {
-                  const lineColor = colorMap[portfolio.user_id] || getPortfolioColor(index)
+                  const lineColor = colorMap[portfolio.portfolio_id] || getPortfolioColor(index)
                   return (
-                  <div key={portfolio.user_id} className="border-b border-dark-700 last:border-0">
+                  <div key={portfolio.portfolio_id} className="border-b border-dark-700 last:border-0">
                     <button
                       onClick={() => setExpandedPortfolio(
-                        expandedPortfolio === portfolio.user_id ? null : portfolio.user_id
+                        expandedPortfolio === portfolio.portfolio_id ? null : portfolio.portfolio_id
                       )}
                       className="w-full p-4 flex items-center justify-between hover:bg-dark-700/50 transition-colors"
                     >
@@ -284,7 +298,7 @@ function PortfolioCompareContent({ token, user, isAdmin }) {
                           </span>
                         </div>
                         <div className="text-left">
-                          <div className="text-white font-medium">{portfolio.username}{isAdmin && !portfolio.visible_in_ranking && ' 👻'}</div>
+                          <div className="text-white font-medium">{portfolioLabel(portfolio)}{isAdmin && !portfolio.visible_in_ranking && ' 👻'}</div>
                           <div className="text-xs text-gray-500">
                             {portfolio.position_count} Position{portfolio.position_count !== 1 ? 'en' : ''}
                           </div>
@@ -292,7 +306,7 @@ function PortfolioCompareContent({ token, user, isAdmin }) {
                       </div>
                       <div className="flex items-center gap-2">
                         {(() => {
-                          const periodRet = getPeriodReturn(portfolio.user_id)
+                          const periodRet = getPeriodReturn(portfolio.portfolio_id)
                           return (
                             <span className={`text-lg font-bold ${
                               periodRet >= 0 ? 'text-green-400' : 'text-red-400'
@@ -303,7 +317,7 @@ function PortfolioCompareContent({ token, user, isAdmin }) {
                         })()}
                         <svg
                           className={`w-5 h-5 text-gray-400 transition-transform ${
-                            expandedPortfolio === portfolio.user_id ? 'rotate-180' : ''
+                            expandedPortfolio === portfolio.portfolio_id ? 'rotate-180' : ''
                           }`}
                           fill="none"
                           stroke="currentColor"
@@ -315,7 +329,7 @@ function PortfolioCompareContent({ token, user, isAdmin }) {
                     </button>
 
                     {/* Expanded Positions */}
-                    {expandedPortfolio === portfolio.user_id && (
+                    {expandedPortfolio === portfolio.portfolio_id && (
                       <div className="px-4 pb-4 space-y-2">
                         {portfolio.positions.slice().sort((a, b) => (b.total_return_pct || 0) - (a.total_return_pct || 0)).map((pos, idx) => (
                           <div key={idx} className="bg-dark-700 rounded-lg p-3">
@@ -362,13 +376,13 @@ function PortfolioCompareContent({ token, user, isAdmin }) {
                   </thead>
                   <tbody>
                     {portfolios.map((portfolio, index) => {
-                      const lineColor = colorMap[portfolio.user_id] || getPortfolioColor(index)
+                      const lineColor = colorMap[portfolio.portfolio_id] || getPortfolioColor(index)
                       return (
                       <>
                         <tr
-                          key={portfolio.user_id}
+                          key={portfolio.portfolio_id}
                           onClick={() => setExpandedPortfolio(
-                            expandedPortfolio === portfolio.user_id ? null : portfolio.user_id
+                            expandedPortfolio === portfolio.portfolio_id ? null : portfolio.portfolio_id
                           )}
                           className="border-b border-dark-700/50 hover:bg-dark-700/30 transition-colors cursor-pointer"
                         >
@@ -386,7 +400,7 @@ function PortfolioCompareContent({ token, user, isAdmin }) {
                                   {portfolio.username.charAt(0).toUpperCase()}
                                 </span>
                               </div>
-                              <span className="text-white font-medium">{portfolio.username}{isAdmin && !portfolio.visible_in_ranking && ' 👻'}</span>
+                              <span className="text-white font-medium">{portfolioLabel(portfolio)}{isAdmin && !portfolio.visible_in_ranking && ' 👻'}</span>
                             </div>
                           </td>
                           <td className="p-4 text-gray-400">
@@ -411,7 +425,7 @@ function PortfolioCompareContent({ token, user, isAdmin }) {
                           </td>
                           <td className="p-4 text-right">
                             {(() => {
-                              const periodRet = getPeriodReturn(portfolio.user_id)
+                              const periodRet = getPeriodReturn(portfolio.portfolio_id)
                               return (
                             <div className="flex items-center justify-end gap-2">
                               <span className={`text-lg font-bold ${
@@ -421,7 +435,7 @@ function PortfolioCompareContent({ token, user, isAdmin }) {
                               </span>
                               <svg
                                 className={`w-5 h-5 text-gray-400 transition-transform ${
-                                  expandedPortfolio === portfolio.user_id ? 'rotate-180' : ''
+                                  expandedPortfolio === portfolio.portfolio_id ? 'rotate-180' : ''
                                 }`}
                                 fill="none"
                                 stroke="currentColor"
@@ -436,8 +450,8 @@ function PortfolioCompareContent({ token, user, isAdmin }) {
                         </tr>
 
                         {/* Expanded Row */}
-                        {expandedPortfolio === portfolio.user_id && (
-                          <tr key={`${portfolio.user_id}-expanded`}>
+                        {expandedPortfolio === portfolio.portfolio_id && (
+                          <tr key={`${portfolio.portfolio_id}-expanded`}>
                             <td colSpan={5} className="p-0">
                               <div className="bg-dark-900/50 p-4">
                                 <table className="w-full">
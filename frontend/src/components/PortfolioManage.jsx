@@ -45,7 +45,47 @@ function PortfolioManage() {
   return <PortfolioContent token={token} />
 }
 
+const EMPTY_TX = { type: 'buy', symbol: '', date: '', quantity: '', price: '', amount: '', fees: '', taxes: '', ratio: '', currency: 'EUR', note: '' }
+
+const TX_TYPES = {
+  buy: 'Kauf',
+  sell: 'Verkauf',
+  dividend: 'Dividende',
+  fee: 'Gebühr',
+  split: 'Split',
+  transfer_in: 'Depotübertrag (ein)',
+  transfer_out: 'Depotübertrag (aus)'
+}
+
+const EMPTY_SAVINGS_PLAN = { symbol: '', amount: '', fee: '', currency: 'EUR', interval: 'monthly', execution_day: 1, start_date: '', end_date: '', active: true, mode: 'defensive' }
+
+const SAVINGS_INTERVALS = {
+  weekly: 'wöchentlich',
+  biweekly: '14-tägig',
+  monthly: 'monatlich',
+  quarterly: 'quartalsweise'
+}
+
+const EMPTY_PORTFOLIO = { name: '', currency: 'EUR', visibility: 'private', paper: false, shared_with: '' }
+
+const RETURN_PERIODS = [
+  ['1w', '1W'], ['1m', '1M'], ['3m', '3M'], ['6m', '6M'], ['ytd', 'YTD'], ['1y', '1J'], ['5y', '5J'], ['max', 'Max']
+]
+
+const BENCHMARK_METRICS = [
+  ['excess_return', 'Überrendite', true],
+  ['alpha', 'Alpha p.a.', true],
+  ['beta', 'Beta', false],
+  ['tracking_error', 'Tracking Error', true],
+  ['information_ratio', 'Information Ratio', false],
+  ['relative_max_drawdown', 'Rel. Max Drawdown', true]
+]
+
 function PortfolioContent({ token }) {
+  const [portfolioList, setPortfolioList] = useState({ own: [], shared: [] })
+  const [portfolioId, setPortfolioId] = useState(null)
+  const [portfolioForm, setPortfolioForm] = useState(null)
+  const [portfolioError, setPortfolioError] = useState('')
   const [positions, setPositions] = useState([])
   const [trades, setTrades] = useState([])
   const [performance, setPerformance] = useState(null)
@@ -54,6 +94,41 @@ function PortfolioContent({ token }) {
   const [editingPosition, setEditingPosition] = useState(null)
   const [sellingPosition, setSellingPosition] = useState(null)
   const [sellPrice, setSellPrice] = useState('')
+  const [sellQuantity, setSellQuantity] = useState('')
+  const [sellDate, setSellDate] = useState('')
+  const [sellFees, setSellFees] = useState('')
+  const [transactions, setTransactions] = useState([])
+  const [lots, setLots] = useState(null)
+  const [showLedger, setShowLedger] = useState(false)
+  const [txForm, setTxForm] = useState(EMPTY_TX)
+  const [editingTx, setEditingTx] = useState(null)
+  const [ledgerError, setLedgerError] = useState('')
+  const [importData, setImportData] = useState(null)
+  const [importBroker, setImportBroker] = useState('auto')
+  const [importPreview, setImportPreview] = useState(null)
+  const [importing, setImporting] = useState(false)
+  const [showTax, setShowTax] = useState(false)
+  const [taxParams, setTaxParams] = useState({ owner: 'me', year: new Date().getFullYear(), allowance: 1000, church_tax: 0 })
+  const [taxReport, setTaxReport] = useState(null)
+  const [showRebalance, setShowRebalance] = useState(false)
+  const [allocation, setAllocation] = useState(null)
+  const [rebalanceCash, setRebalanceCash] = useState('')
+  const [rebalanceProposal, setRebalanceProposal] = useState(null)
+  const [rebalancePlans, setRebalancePlans] = useState([])
+  const [rebalanceError, setRebalanceError] = useState('')
+  const [showSavings, setShowSavings] = useState(false)
+  const [savingsPlans, setSavingsPlans] = useState([])
+  const [savingsForm, setSavingsForm] = useState(EMPTY_SAVINGS_PLAN)
+  const [savingsError, setSavingsError] = useState('')
+  const [savingsBacktest, setSavingsBacktest] = useState(null)
+  const [backtesting, setBacktesting] = useState(false)
+  const [showRisk, setShowRisk] = useState(false)
+  const [riskParams, setRiskParams] = useState({ owner: 'me', years: 3 })
+  const [riskReport, setRiskReport] = useState(null)
+  const [showDividends, setShowDividends] = useState(false)
+  const [dividendOwner, setDividendOwner] = useState('me')
+  const [dividendReport, setDividendReport] = useState(null)
+  const [benchmarks, setBenchmarks] = useState(null)
   const [searchQuery, setSearchQuery] = useState('')
   const [searchResults, setSearchResults] = useState([])
   const [searching, setSearching] = useState(false)
@@ -73,11 +148,20 @@ function PortfolioContent({ token }) {
   const { formatPrice, currency } = useCurrency()
 
   useEffect(() => {
-    fetchPortfolio()
-    fetchPerformance()
-    fetchTrades()
+    fetchPortfolios()
+    fetchBenchmarks()
   }, [])
 
+  // Everything below the selector belongs to the selected portfolio
+  useEffect(() => {
+    refreshAll()
+    if (showSavings) fetchSavingsPlans()
+    if (showRebalance) fetchAllocation()
+    if (showRisk) fetchRisk()
+    if (showDividends) fetchDividends()
+    setTaxReport(null)
+  }, [portfolioId])
+
   useEffect(() => {
     const handleClickOutside = (e) => {
       if (searchRef.current && !searchRef.current.contains(e.target)) {
@@ -88,9 +172,86 @@ function PortfolioContent({ token }) {
     return () => document.removeEventListener('mousedown', handleClickOutside)
   }, [])
 
+  // withPortfolio adds the selected portfolio to a request; the default portfolio needs no parameter
+  const withPortfolio = (url) => {
+    if (!portfolioId) return url
+    return `${url}${url.includes('?') ? '&' : '?'}portfolio=${portfolioId}`
+  }
+
+  const fetchPortfolios = async () => {
+    try {
+      const res = await fetch('/api/portfolios/mine', { headers: { 'Authorization': `Bearer ${token}` } })
+      if (res.ok) setPortfolioList(await res.json())
+    } catch (err) {
+      console.error('Failed to fetch portfolios:', err)
+    }
+  }
+
+  const allPortfolios = [...portfolioList.own, ...portfolioList.shared]
+  const selectedPortfolio = allPortfolios.find(p => p.id === portfolioId) || portfolioList.own.find(p => p.is_default)
+  const readOnly = !!selectedPortfolio && portfolioList.shared.some(p => p.id === selectedPortfolio.id)
+  const portfolioCurrency = selectedPortfolio?.currency || 'EUR'
+
+  // New transactions, positions and savings plans start in the portfolio currency
+  useEffect(() => {
+    setTxForm(f => ({ ...f, currency: portfolioCurrency }))
+    setSavingsForm(f => (f.id ? f : { ...f, currency: portfolioCurrency }))
+    setFormData(f => ({ ...f, currency: portfolioCurrency }))
+  }, [portfolioCurrency])
+
+  const editPortfolio = (portfolio) => {
+    setPortfolioError('')
+    setPortfolioForm(portfolio
+      ? { ...portfolio, shared_with: (portfolio.shared_with || []).join(', ') }
+      : EMPTY_PORTFOLIO)
+  }
+
+  const savePortfolio = async (makeDefault = false) => {
+    setPortfolioError('')
+    const payload = {
+      name: portfolioForm.name,
+      currency: portfolioForm.currency,
+      visibility: portfolioForm.visibility,
+      paper: !!portfolioForm.paper,
+      shared_with: portfolioForm.shared_with.split(',').map(n => n.trim()).filter(Boolean)
+    }
+    const url = portfolioForm.id
+      ? `/api/portfolios/mine/${portfolioForm.id}${makeDefault ? '?default=true' : ''}`
+      : '/api/portfolios/mine'
+    const res = await fetch(url, {
+      method: portfolioForm.id ? 'PUT' : 'POST',
+      headers: { 'Authorization': `Bearer ${token}`, 'Content-Type': 'application/json' },
+      body: JSON.stringify(payload)
+    })
+    const data = await res.json()
+    if (!res.ok) {
+      setPortfolioError(data.error || 'Portfolio konnte nicht gespeichert werden')
+      return
+    }
+    setPortfolioForm(null)
+    await fetchPortfolios()
+    setPortfolioId(data.is_default ? null : data.id)
+  }
+
+  const deletePortfolio = async (portfolio) => {
+    if (!confirm(`Portfolio "${portfolio.name}" mit allen Transaktionen löschen?`)) return
+    const res = await fetch(`/api/portfolios/mine/${portfolio.id}`, {
+      method: 'DELETE',
+      headers: { 'Authorization': `Bearer ${token}` }
+    })
+    if (!res.ok) {
+      const data = await res.json()
+      setPortfolioError(data.error || 'Portfolio konnte nicht gelöscht werden')
+      return
+    }
+    setPortfolioForm(null)
+    setPortfolioId(null)
+    fetchPortfolios()
+  }
+
   const fetchPortfolio = async () => {
     try {
-      const res = await fetch('/api/portfolio', {
+      const res = await fetch(withPortfolio('/api/portfolio'), {
         headers: { 'Authorization': `Bearer ${token}` }
       })
       const data = await res.json()
@@ -104,7 +265,7 @@ function PortfolioContent({ token }) {
 
   const fetchPerformance = async () => {
     try {
-      const res = await fetch('/api/portfolio/performance', {
+      const res = await fetch(withPortfolio('/api/portfolio/performance?benchmark=default'), {
         headers: { 'Authorization': `Bearer ${token}` }
       })
       const data = await res.json()
@@ -114,9 +275,406 @@ function PortfolioContent({ token }) {
     }
   }
 
+  const fetchBenchmarks = async () => {
+    try {
+      const res = await fetch('/api/benchmarks', { headers: { 'Authorization': `Bearer ${token}` } })
+      if (res.ok) setBenchmarks(await res.json())
+    } catch (err) {
+      console.error('Failed to fetch benchmarks:', err)
+    }
+  }
+
+  const saveBenchmark = async (symbol) => {
+    try {
+      const res = await fetch('/api/benchmarks/preference', {
+        method: 'PUT',
+        headers: { 'Authorization': `Bearer ${token}`, 'Content-Type': 'application/json' },
+        body: JSON.stringify({ symbol })
+      })
+      if (!res.ok) {
+        const data = await res.json()
+        alert(data.error || 'Benchmark konnte nicht gespeichert werden')
+        return
+      }
+      fetchBenchmarks()
+      fetchPerformance()
+    } catch (err) {
+      console.error('Failed to save benchmark:', err)
+    }
+  }
+
+  const fetchLedger = async () => {
+    try {
+      const [txRes, lotsRes] = await Promise.all([
+        fetch(withPortfolio('/api/portfolio/transactions'), { headers: { 'Authorization': `Bearer ${token}` } }),
+        fetch(withPortfolio('/api/portfolio/lots'), { headers: { 'Authorization': `Bearer ${token}` } })
+      ])
+      if (txRes.ok) setTransactions((await txRes.json() || []).reverse())
+      if (lotsRes.ok) setLots(await lotsRes.json())
+    } catch (err) {
+      console.error('Failed to fetch ledger:', err)
+    }
+  }
+
+  const refreshAll = () => {
+    fetchPortfolio()
+    fetchPerformance()
+    fetchTrades()
+    if (showLedger) fetchLedger()
+  }
+
+  const handleTxSubmit = async (e) => {
+    e.preventDefault()
+    setLedgerError('')
+    const num = (v) => (v === '' || v === null || v === undefined ? 0 : parseFloat(v))
+    const payload = {
+      ...txForm,
+      quantity: num(txForm.quantity),
+      price: num(txForm.price),
+      amount: num(txForm.amount),
+      fees: num(txForm.fees),
+      taxes: num(txForm.taxes),
+      ratio: num(txForm.ratio)
+    }
+    try {
+      const res = await fetch(withPortfolio(editingTx ? `/api/portfolio/transactions/${editingTx.id}` : '/api/portfolio/transactions'), {
+        method: editingTx ? 'PUT' : 'POST',
+        headers: { 'Content-Type': 'application/json', 'Authorization': `Bearer ${token}` },
+        body: JSON.stringify(payload)
+      })
+      const data = await res.json()
+      if (!res.ok) {
+        setLedgerError(data.error || 'Speichern fehlgeschlagen')
+        return
+      }
+      setTxForm({ ...EMPTY_TX, currency: portfolioCurrency })
+      setEditingTx(null)
+      refreshAll()
+      fetchLedger()
+    } catch (err) {
+      console.error('Failed to save transaction:', err)
+    }
+  }
+
+  const handleTxEdit = (tx) => {
+    setEditingTx(tx)
+    setLedgerError('')
+    setTxForm({
+      type: tx.type,
+      symbol: tx.symbol,
+      date: tx.date ? tx.date.split('T')[0] : '',
+      quantity: tx.quantity || '',
+      price: tx.price || '',
+      amount: tx.amount || '',
+      fees: tx.fees || '',
+      taxes: tx.taxes || '',
+      ratio: tx.ratio || '',
+      currency: tx.currency || 'EUR',
+      note: tx.note || ''
+    })
+  }
+
+  const handleTxDelete = async (tx) => {
+    if (!confirm(`${TX_TYPES[tx.type]} ${tx.symbol} vom ${formatDate(tx.date)} löschen?`)) return
+    setLedgerError('')
+    const res = await fetch(withPortfolio(`/api/portfolio/transactions/${tx.id}`), {
+      method: 'DELETE',
+      headers: { 'Authorization': `Bearer ${token}` }
+    })
+    if (!res.ok) {
+      const data = await res.json()
+      setLedgerError(data.error || 'Löschen fehlgeschlagen')
+      return
+    }
+    refreshAll()
+    fetchLedger()
+  }
+
+  const runImport = async (dryRun, data = importData) => {
+    if (!data) return
+    setImporting(true)
+    try {
+      const res = await fetch(withPortfolio(`/api/portfolio/import?broker=${importBroker}&dry_run=${dryRun}`), {
+        method: 'POST',
+        headers: { 'Content-Type': 'text/csv', 'Authorization': `Bearer ${token}` },
+        body: data
+      })
+      const result = await res.json()
+      setImportPreview(result)
+      if (res.ok && !dryRun) {
+        setImportData(null)
+        refreshAll()
+        fetchLedger()
+      }
+    } catch (err) {
+      console.error('Failed to import transactions:', err)
+    } finally {
+      setImporting(false)
+    }
+  }
+
+  const handleImportFile = async (e) => {
+    const file = e.target.files?.[0]
+    e.target.value = ''
+    if (!file) return
+    const data = await file.arrayBuffer()
+    setImportData(data)
+    runImport(true, data)
+  }
+
+  const taxReportUrl = (format) => {
+    const base = taxParams.owner === 'me' ? '/api/portfolio/tax-report' : `/api/portfolios/tax-report/${taxParams.owner}`
+    return withPortfolio(`${base}?year=${taxParams.year}&allowance=${taxParams.allowance}&church_tax=${taxParams.church_tax}${format ? `&format=${format}` : ''}`)
+  }
+
+  const fetchAllocation = async () => {
+    try {
+      const [targetsRes, plansRes] = await Promise.all([
+        fetch(withPortfolio('/api/portfolio/targets'), { headers: { 'Authorization': `Bearer ${token}` } }),
+        fetch(withPortfolio('/api/portfolio/rebalance/plans'), { headers: { 'Authorization': `Bearer ${token}` } })
+      ])
+      if (targetsRes.ok) setAllocation(await targetsRes.json())
+      if (plansRes.ok) setRebalancePlans(await plansRes.json())
+    } catch (err) {
+      console.error('Failed to fetch allocation:', err)
+    }
+  }
+
+  const updateTarget = (index, changes) => {
+    const targets = allocation.targets.map((t, i) => i === index ? { ...t, ...changes } : t)
+    setAllocation({ ...allocation, targets })
+  }
+
+  const saveAllocation = async () => {
+    setRebalanceError('')
+    const targets = allocation.targets.map(t => t.category_id
+      ? { category_id: Number(t.category_id), weight: Number(t.weight) }
+      : { symbol: t.symbol, weight: Number(t.weight) })
+    const settings = { ...allocation.settings, threshold: Number(allocation.settings.threshold), min_trade: Number(allocation.settings.min_trade) }
+    const res = await fetch(withPortfolio('/api/portfolio/targets'), {
+      method: 'PUT',
+      headers: { 'Authorization': `Bearer ${token}`, 'Content-Type': 'application/json' },
+      body: JSON.stringify({ targets, settings })
+    })
+    const data = await res.json()
+    if (!res.ok) {
+      setRebalanceError(data.error || 'Speichern fehlgeschlagen')
+      return false
+    }
+    setAllocation({ ...allocation, targets: data.targets, settings: data.settings })
+    return true
+  }
+
+  const rebalanceQuery = () => `cash=${Number(rebalanceCash) || 0}&currency=${currency}`
+
+  const proposeRebalance = async () => {
+    if (!(await saveAllocation())) return
+    const res = await fetch(withPortfolio(`/api/portfolio/rebalance?${rebalanceQuery()}`), { headers: { 'Authorization': `Bearer ${token}` } })
+    const data = await res.json()
+    if (!res.ok) {
+      setRebalanceError(data.error || 'Berechnung fehlgeschlagen')
+      setRebalanceProposal(null)
+      return
+    }
+    setRebalanceProposal(data)
+  }
+
+  const saveRebalancePlan = async () => {
+    const res = await fetch(withPortfolio(`/api/portfolio/rebalance/plans?${rebalanceQuery()}`), {
+      method: 'POST',
+      headers: { 'Authorization': `Bearer ${token}` }
+    })
+    const data = await res.json()
+    if (!res.ok) {
+      setRebalanceError(data.error || 'Speichern fehlgeschlagen')
+      return
+    }
+    setRebalanceProposal(null)
+    fetchAllocation()
+  }
+
+  const executeRebalancePlan = async (plan) => {
+    if (!confirm(`${plan.trades.length} Trades von Plan #${plan.id} ins Depot buchen?`)) return
+    const res = await fetch(`/api/portfolio/rebalance/plans/${plan.id}/execute`, {
+      method: 'POST',
+      headers: { 'Authorization': `Bearer ${token}` }
+    })
+    const data = await res.json()
+    if (!res.ok) {
+      setRebalanceError(data.error || 'Ausführung fehlgeschlagen')
+      return
+    }
+    refreshAll()
+    fetchAllocation()
+  }
+
+  const deleteRebalancePlan = async (plan) => {
+    await fetch(`/api/portfolio/rebalance/plans/${plan.id}`, {
+      method: 'DELETE',
+      headers: { 'Authorization': `Bearer ${token}` }
+    })
+    fetchAllocation()
+  }
+
+  const fetchSavingsPlans = async () => {
+    try {
+      const res = await fetch(withPortfolio('/api/portfolio/savings-plans'), { headers: { 'Authorization': `Bearer ${token}` } })
+      if (res.ok) setSavingsPlans(await res.json())
+    } catch (err) {
+      console.error('Failed to fetch savings plans:', err)
+    }
+  }
+
+  const savingsPayload = () => ({
+    ...savingsForm,
+    amount: Number(savingsForm.amount),
+    fee: Number(savingsForm.fee) || 0,
+    execution_day: Number(savingsForm.execution_day) || 1
+  })
+
+  const saveSavingsPlan = async () => {
+    setSavingsError('')
+    const editing = savingsForm.id
+    const res = await fetch(editing ? `/api/portfolio/savings-plans/${savingsForm.id}` : withPortfolio('/api/portfolio/savings-plans'), {
+      method: editing ? 'PUT' : 'POST',
+      headers: { 'Authorization': `Bearer ${token}`, 'Content-Type': 'application/json' },
+      body: JSON.stringify(savingsPayload())
+    })
+    const data = await res.json()
+    if (!res.ok) {
+      setSavingsError(data.error || 'Speichern fehlgeschlagen')
+      return
+    }
+    setSavingsForm({ ...EMPTY_SAVINGS_PLAN, currency: portfolioCurrency })
+    fetchSavingsPlans()
+    if (data.executed > 0) refreshAll()
+  }
+
+  const editSavingsPlan = (plan) => {
+    setSavingsForm({
+      id: plan.id, symbol: plan.symbol, name: plan.name, amount: plan.amount, fee: plan.fee,
+      currency: plan.currency, interval: plan.interval, execution_day: plan.execution_day,
+      start_date: plan.start_date.slice(0, 10), end_date: plan.end_date ? plan.end_date.slice(0, 10) : '', active: plan.active
+    })
+  }
+
+  const toggleSavingsPlan = async (plan) => {
+    await fetch(`/api/portfolio/savings-plans/${plan.id}`, {
+      method: 'PUT',
+      headers: { 'Authorization': `Bearer ${token}`, 'Content-Type': 'application/json' },
+      body: JSON.stringify({
+        ...plan, start_date: plan.start_date.slice(0, 10), end_date: plan.end_date ? plan.end_date.slice(0, 10) : '', active: !plan.active
+      })
+    })
+    fetchSavingsPlans()
+  }
+
+  const runSavingsPlan = async (plan) => {
+    const res = await fetch(`/api/portfolio/savings-plans/${plan.id}/run`, {
+      method: 'POST',
+      headers: { 'Authorization': `Bearer ${token}` }
+    })
+    const data = await res.json()
+    if (!res.ok) {
+      setSavingsError(data.error || 'Ausführung fehlgeschlagen')
+      return
+    }
+    fetchSavingsPlans()
+    if (data.executed > 0) refreshAll()
+  }
+
+  const deleteSavingsPlan = async (plan) => {
+    if (!confirm(`Sparplan ${plan.symbol} löschen? Bereits gebuchte Käufe bleiben im Depot.`)) return
+    await fetch(`/api/portfolio/savings-plans/${plan.id}`, {
+      method: 'DELETE',
+      headers: { 'Authorization': `Bearer ${token}` }
+    })
+    fetchSavingsPlans()
+  }
+
+  const runSavingsBacktest = async () => {
+    setSavingsError('')
+    setBacktesting(true)
+    const f = savingsPayload()
+    const params = new URLSearchParams({
+      symbol: f.symbol, amount: f.amount, fee: f.fee, currency: f.currency, interval: f.interval,
+      day: f.execution_day, from: f.start_date, to: f.end_date, mode: f.mode || 'defensive'
+    })
+    try {
+      const res = await fetch(`/api/savings-plans/backtest?${params}`, { headers: { 'Authorization': `Bearer ${token}` } })
+      const data = await res.json()
+      if (!res.ok) {
+        setSavingsError(data.error || 'Backtest fehlgeschlagen')
+        setSavingsBacktest(null)
+      } else {
+        setSavingsBacktest(data)
+      }
+    } finally {
+      setBacktesting(false)
+    }
+  }
+
+  const fetchRisk = async (params = riskParams) => {
+    const base = params.owner === 'me' ? '/api/portfolio/risk' : `/api/portfolios/risk/${params.owner}`
+    setRiskReport(null)
+    try {
+      const res = await fetch(withPortfolio(`${base}?years=${params.years}`), { headers: { 'Authorization': `Bearer ${token}` } })
+      if (res.ok) setRiskReport(await res.json())
+    } catch (err) {
+      console.error('Failed to fetch risk report:', err)
+    }
+  }
+
+  const updateRiskParams = (changes) => {
+    const params = { ...riskParams, ...changes }
+    setRiskParams(params)
+    fetchRisk(params)
+  }
+
+  const fetchDividends = async (owner = dividendOwner) => {
+    const url = owner === 'me' ? '/api/portfolio/dividends' : `/api/portfolios/dividends/${owner}`
+    try {
+      const res = await fetch(withPortfolio(url), { headers: { 'Authorization': `Bearer ${token}` } })
+      if (res.ok) setDividendReport(await res.json())
+    } catch (err) {
+      console.error('Failed to fetch dividends:', err)
+    }
+  }
+
+  const syncDividends = async () => {
+    const res = await fetch(withPortfolio('/api/portfolio/dividends/sync'), {
+      method: 'POST',
+      headers: { 'Authorization': `Bearer ${token}` }
+    })
+    if (res.ok) {
+      refreshAll()
+      fetchDividends()
+    }
+  }
+
+  const fetchTaxReport = async () => {
+    try {
+      const res = await fetch(taxReportUrl(), { headers: { 'Authorization': `Bearer ${token}` } })
+      if (res.ok) setTaxReport(await res.json())
+    } catch (err) {
+      console.error('Failed to fetch tax report:', err)
+    }
+  }
+
+  const downloadTaxReport = async (format) => {
+    const res = await fetch(taxReportUrl(format), { headers: { 'Authorization': `Bearer ${token}` } })
+    if (!res.ok) return
+    const url = URL.createObjectURL(await res.blob())
+    const a = document.createElement('a')
+    a.href = url
+    a.download = `steuerreport_${taxParams.owner}_${taxParams.year}.${format}`
+    a.click()
+    URL.revokeObjectURL(url)
+  }
+
   const fetchTrades = async () => {
     try {
-      const res = await fetch('/api/portfolio/trades', {
+      const res = await fetch(withPortfolio('/api/portfolio/trades'), {
         headers: { 'Authorization': `Bearer ${token}` }
       })
       const data = await res.json()
@@ -133,6 +691,19 @@ function PortfolioContent({ token }) {
     }
     setSearching(true)
     try {
+      // ISIN (12 chars) or WKN (6 chars with a digit) as shown by German brokers
+      const id = q.trim().toUpperCase()
+      if (/^[A-Z]{2}[A-Z0-9]{9}[0-9]$/.test(id) || /^(?=.*[0-9])[A-Z0-9]{6}$/.test(id)) {
+        const res = await fetch(`/api/securities/resolve?q=${encodeURIComponent(id)}`, {
+          headers: { 'Authorization': `Bearer ${token}` }
+        })
+        if (res.ok) {
+          const sec = await res.json()
+          setSearchResults([{ symbol: sec.symbol, name: sec.name || sec.isin || id, exchange: sec.exchange || sec.wkn || 'ISIN' }])
+          setShowDropdown(true)
+          return
+        }
+      }
       const res = await fetch(`/api/search?q=${encodeURIComponent(q)}`)
       const data = await res.json()
       setSearchResults(data)
@@ -180,9 +751,9 @@ function PortfolioContent({ token }) {
         quantity: formData.quantity ? parseFloat(formData.quantity) : null
       }
 
-      const url = editingPosition
+      const url = withPortfolio(editingPosition
         ? `/api/portfolio/${editingPosition.id}`
-        : '/api/portfolio'
+        : '/api/portfolio')
       const method = editingPosition ? 'PUT' : 'POST'
 
       const res = await fetch(url, {
@@ -197,9 +768,11 @@ function PortfolioContent({ token }) {
       if (res.ok) {
         setShowForm(false)
         setEditingPosition(null)
-        setFormData({ symbol: '', name: '', purchase_date: '', avg_price: '', currency: 'EUR', quantity: '' })
-        fetchPortfolio()
-        fetchPerformance()
+        setFormData({ symbol: '', name: '', purchase_date: '', avg_price: '', currency: portfolioCurrency, quantity: '' })
+        refreshAll()
+      } else {
+        const data = await res.json()
+        alert(data.error || 'Speichern fehlgeschlagen')
       }
     } catch (err) {
       console.error('Failed to save position:', err)
@@ -222,15 +795,14 @@ function PortfolioContent({ token }) {
   }
 
   const handleDelete = async (id) => {
-    if (!confirm('Position wirklich löschen? (Wird NICHT in der History gespeichert)')) return
+    if (!confirm('Position wirklich löschen? (Ohne Verkäufe werden die Käufe entfernt, sonst wird der Bestand ausgebucht)')) return
     try {
-      const res = await fetch(`/api/portfolio/${id}`, {
+      const res = await fetch(withPortfolio(`/api/portfolio/${id}`), {
         method: 'DELETE',
         headers: { 'Authorization': `Bearer ${token}` }
       })
       if (res.ok) {
-        fetchPortfolio()
-        fetchPerformance()
+        refreshAll()
       }
     } catch (err) {
       console.error('Failed to delete position:', err)
@@ -243,7 +815,7 @@ function PortfolioContent({ token }) {
 
     setSubmitting(true)
     try {
-      const res = await fetch(`/api/portfolio/${sellingPosition.id}/sell`, {
+      const res = await fetch(withPortfolio(`/api/portfolio/${sellingPosition.id}/sell`), {
         method: 'POST',
         headers: {
           'Content-Type': 'application/json',
@@ -251,15 +823,18 @@ function PortfolioContent({ token }) {
         },
         body: JSON.stringify({
           sell_price: parseFloat(sellPrice),
-          quantity: sellingPosition.quantity
+          quantity: sellQuantity ? parseFloat(sellQuantity) : sellingPosition.quantity,
+          date: sellDate || undefined,
+          fees: sellFees ? parseFloat(sellFees) : 0
         })
       })
       if (res.ok) {
         setSellingPosition(null)
         setSellPrice('')
-        fetchPortfolio()
-        fetchPerformance()
-        fetchTrades()
+        refreshAll()
+      } else {
+        const data = await res.json()
+        alert(data.error || 'Verkauf fehlgeschlagen')
       }
     } catch (err) {
       console.error('Failed to sell position:', err)
@@ -271,12 +846,15 @@ function PortfolioContent({ token }) {
   const openSellModal = (pos) => {
     setSellingPosition(pos)
     setSellPrice(pos.current_price?.toFixed(2) || '')
+    setSellQuantity(pos.quantity ? pos.quantity.toString() : '')
+    setSellDate('')
+    setSellFees('')
   }
 
   const handleCancel = () => {
     setShowForm(false)
     setEditingPosition(null)
-    setFormData({ symbol: '', name: '', purchase_date: '', avg_price: '', currency: 'EUR', quantity: '' })
+    setFormData({ symbol: '', name: '', purchase_date: '', avg_price: '', currency: portfolioCurrency, quantity: '' })
   }
 
   const formatPercent = (value) => {
@@ -295,15 +873,83 @@ function PortfolioContent({ token }) {
     <div className="flex-1 p-4 md:p-6 overflow-auto">
       <div className="max-w-6xl mx-auto">
         {/* Header */}
-        <div className="mb-4 md:mb-6">
-          <h1 className="text-xl md:text-2xl font-bold text-white">Mein Portfolio</h1>
-          <p className="text-gray-500 text-sm">Verwalte deine Aktien und Investitionen</p>
+        <div className="mb-4 md:mb-6 flex flex-col sm:flex-row sm:items-end sm:justify-between gap-2">
+          <div>
+            <h1 className="text-xl md:text-2xl font-bold text-white">Mein Portfolio</h1>
+            <p className="text-gray-500 text-sm">Verwalte deine Aktien und Investitionen</p>
+          </div>
+          <div className="flex flex-wrap items-center gap-2 text-sm">
+            <select
+              value={selectedPortfolio?.is_default ? '' : (portfolioId || '')}
+              onChange={(e) => { setPortfolioForm(null); setPortfolioId(e.target.value ? Number(e.target.value) : null) }}
+              className="px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white"
+            >
+              {portfolioList.own.map(p => (
+                <option key={p.id} value={p.is_default ? '' : p.id}>{p.name} ({p.currency}){p.visibility === 'public' ? ' · öffentlich' : ''}{p.paper ? ' · Musterdepot' : ''}</option>
+              ))}
+              {portfolioList.shared.length > 0 && (
+                <optgroup label="Mit mir geteilt">
+                  {portfolioList.shared.map(p => <option key={p.id} value={p.id}>{p.name} von {p.owner}</option>)}
+                </optgroup>
+              )}
+            </select>
+            {selectedPortfolio && !readOnly && (
+              <button onClick={() => editPortfolio(selectedPortfolio)} className="px-2 py-1.5 bg-dark-600 text-gray-300 rounded hover:bg-dark-500">Einstellungen</button>
+            )}
+            <button onClick={() => editPortfolio(null)} className="px-2 py-1.5 bg-accent-500 text-white rounded hover:bg-accent-600">+ Portfolio</button>
+          </div>
         </div>
 
+        {readOnly && (
+          <div className="mb-4 md:mb-6 px-3 py-2 rounded-lg bg-dark-700 text-sm text-gray-300">
+            Portfolio von {selectedPortfolio.owner} – nur lesend geteilt, Änderungen sind nicht möglich.
+          </div>
+        )}
+
+        {portfolioForm && (
+          <div className="bg-dark-800 rounded-xl border border-dark-600 p-4 md:p-6 mb-4 md:mb-6 text-sm">
+            <h2 className="text-lg font-semibold text-white mb-3">{portfolioForm.id ? 'Portfolio bearbeiten' : 'Neues Portfolio'}</h2>
+            <div className="flex flex-wrap gap-2 items-center text-xs text-gray-400">
+              <input value={portfolioForm.name} onChange={(e) => setPortfolioForm({ ...portfolioForm, name: e.target.value })}
+                placeholder="Name, z.B. Altersvorsorge" className="w-48 px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white" />
+              <select value={portfolioForm.currency} onChange={(e) => setPortfolioForm({ ...portfolioForm, currency: e.target.value })}
+                className="px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white">
+                {['EUR', 'USD', 'CHF', 'GBP'].map(c => <option key={c} value={c}>{c}</option>)}
+              </select>
+              <select value={portfolioForm.visibility} onChange={(e) => setPortfolioForm({ ...portfolioForm, visibility: e.target.value })}
+                className="px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white">
+                <option value="private">Privat</option>
+                <option value="public">Öffentlich im Ranking</option>
+              </select>
+              <label className="flex items-center gap-1">
+                <input type="checkbox" checked={!!portfolioForm.paper}
+                  onChange={(e) => setPortfolioForm({ ...portfolioForm, paper: e.target.checked })} />
+                Musterdepot
+              </label>
+              <input value={portfolioForm.shared_with} onChange={(e) => setPortfolioForm({ ...portfolioForm, shared_with: e.target.value })}
+                placeholder="Lesend teilen mit (Benutzernamen, kommagetrennt)" className="flex-1 min-w-[16rem] px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white" />
+            </div>
+            <div className="flex flex-wrap gap-2 items-center mt-3">
+              <button onClick={() => savePortfolio()} className="px-3 py-1.5 bg-accent-500 text-white rounded hover:bg-accent-600">
+                {portfolioForm.id ? 'Speichern' : 'Anlegen'}
+              </button>
+              {portfolioForm.id && !portfolioForm.is_default && (
+                <>
+                  <button onClick={() => savePortfolio(true)} className="px-3 py-1.5 bg-dark-600 text-gray-200 rounded hover:bg-dark-500">Als Standard festlegen</button>
+                  <button onClick={() => deletePortfolio(portfolioForm)} className="px-3 py-1.5 bg-dark-600 text-red-400 rounded hover:bg-dark-500">Löschen</button>
+                </>
+              )}
+              <button onClick={() => setPortfolioForm(null)} className="px-3 py-1.5 bg-dark-600 text-gray-200 rounded hover:bg-dark-500">Abbrechen</button>
+            </div>
+            <p className="text-xs text-gray-500 mt-2">Die Währung gilt als Vorgabe für neue Transaktionen, Importe und Sparpläne. Das Standard-Portfolio bestimmt die Sichtbarkeit im Ranking. Musterdepots zählen nicht zum Steuerreport.</p>
+            {portfolioError && <p className="text-xs text-red-400 mt-2">{portfolioError}</p>}
+          </div>
+        )}
+
         {/* Portfolio Performance Chart */}
         {positions.length > 0 && (
           <div className="mb-4 md:mb-6">
-            <PortfolioChart token={token} height={250} />
+            <PortfolioChart token={token} height={250} portfolioId={portfolioId} />
           </div>
         )}
 
@@ -340,6 +986,94 @@ function PortfolioContent({ token }) {
               </div>
             </div>
 
+            {/* Time- and money-weighted returns per period */}
+            {performance.time_weighted && Object.keys(performance.time_weighted).length > 0 && (
+              <div className="mb-4 overflow-x-auto">
+                <table className="w-full text-xs md:text-sm">
+                  <thead>
+                    <tr className="text-gray-500">
+                      <th className="text-left font-normal py-1 pr-2">Zeitraum</th>
+                      {RETURN_PERIODS.filter(([key]) => performance.time_weighted[key] !== undefined).map(([key, label]) => (
+                        <th key={key} className="text-right font-normal py-1 px-2">{label}</th>
+                      ))}
+                    </tr>
+                  </thead>
+                  <tbody>
+                    {[['time_weighted', 'Zeitgewichtet (TWR)'], ['money_weighted', 'Geldgewichtet (MWR)']].map(([field, label]) => (
+                      <tr key={field} className="border-t border-dark-600">
+                        <td className="text-gray-400 py-1 pr-2 whitespace-nowrap">{label}</td>
+                        {RETURN_PERIODS.filter(([key]) => performance.time_weighted[key] !== undefined).map(([key]) => {
+                          const value = performance[field]?.[key] || 0
+                          return (
+                            <td key={key} className={`text-right py-1 px-2 ${value >= 0 ? 'text-green-400' : 'text-red-400'}`}>
+                              {formatPercent(value)}
+                            </td>
+                          )
+                        })}
+                      </tr>
+                    ))}
+                  </tbody>
+                </table>
+                {performance.xirr !== undefined && (
+                  <p className="text-xs text-gray-500 mt-2">
+                    XIRR seit Beginn: <span className={performance.xirr >= 0 ? 'text-green-400' : 'text-red-400'}>{formatPercent(performance.xirr)}</span>
+                    {' '}· TWR misst die Anlageentscheidungen unabhängig vom Zeitpunkt der Einzahlungen
+                  </p>
+                )}
+              </div>
+            )}
+
+            {/* Comparison with the selected benchmark since the first transaction */}
+            {benchmarks && (
+              <div className="mb-4 bg-dark-700 rounded-lg p-3">
+                <div className="flex flex-wrap items-center justify-between gap-2 mb-2">
+                  <div className="text-xs text-gray-500">
+                    Vergleich mit Benchmark
+                    {performance.benchmark && (
+                      <span> · {performance.benchmark.name}: <span className={performance.benchmark.benchmark_return >= 0 ? 'text-green-400' : 'text-red-400'}>{formatPercent(performance.benchmark.benchmark_return)}</span>
+                        {' '}vs. Portfolio <span className={performance.benchmark.portfolio_return >= 0 ? 'text-green-400' : 'text-red-400'}>{formatPercent(performance.benchmark.portfolio_return)}</span></span>
+                    )}
+                  </div>
+                  <div className="flex items-center gap-2">
+                    <select
+                      value={benchmarks.presets?.some(b => b.symbol === benchmarks.selected) ? benchmarks.selected : 'custom'}
+                      onChange={(e) => {
+                        if (e.target.value === 'custom') {
+                          const symbol = prompt('Symbol des Benchmarks (z.B. QQQ)', benchmarks.selected)
+                          if (symbol) saveBenchmark(symbol)
+                        } else {
+                          saveBenchmark(e.target.value)
+                        }
+                      }}
+                      className="bg-dark-800 text-gray-300 text-xs rounded-md px-2 py-1 border border-dark-600"
+                    >
+                      {(benchmarks.presets || []).map(b => (
+                        <option key={b.symbol} value={b.symbol}>{b.name}</option>
+                      ))}
+                      <option value="custom">{benchmarks.presets?.some(b => b.symbol === benchmarks.selected) ? 'Anderes Symbol…' : benchmarks.selected}</option>
+                    </select>
+                  </div>
+                </div>
+                {performance.benchmark ? (
+                  <div className="grid grid-cols-3 md:grid-cols-6 gap-2">
+                    {BENCHMARK_METRICS.map(([key, label, percent]) => {
+                      const value = performance.benchmark[key] || 0
+                      return (
+                        <div key={key}>
+                          <div className="text-xs text-gray-500">{label}</div>
+                          <div className={`text-sm font-medium ${key === 'beta' || key === 'tracking_error' ? 'text-white' : value >= 0 ? 'text-green-400' : 'text-red-400'}`}>
+                            {percent ? formatPercent(value) : value.toFixed(2)}
+                          </div>
+                        </div>
+                      )
+                    })}
+                  </div>
+                ) : (
+                  <p className="text-xs text-gray-500">Noch nicht genug Kursdaten für einen Vergleich</p>
+                )}
+              </div>
+            )}
+
             {/* Trading Stats: Win Rate, Risk-Reward (all trades + open positions) */}
             {(() => {
               const allItems = [
@@ -395,46 +1129,48 @@ function PortfolioContent({ token }) {
             <h2 className="text-lg font-semibold text-white">Positionen ({positions.length})</h2>
 
             {/* Search Input */}
-            <div className="relative flex-1 max-w-full md:max-w-md" ref={searchRef}>
-              <input
-                type="text"
-                placeholder="Aktie suchen (z.B. AAPL, Tesla)"
-                value={searchQuery}
-                onChange={handleSearchChange}
-                onFocus={() => searchResults.length > 0 && setShowDropdown(true)}
-                className="w-full px-4 py-2.5 bg-dark-700 border border-dark-600 rounded-lg text-sm text-white placeholder-gray-500 focus:outline-none focus:border-accent-500"
-              />
-              {searching && (
-                <div className="absolute right-3 top-1/2 -translate-y-1/2">
-                  <div className="w-4 h-4 border-2 border-accent-500 border-t-transparent rounded-full animate-spin"></div>
-                </div>
-              )}
+            {!readOnly && (
+              <div className="relative flex-1 max-w-full md:max-w-md" ref={searchRef}>
+                <input
+                  type="text"
+                  placeholder="Aktie suchen (Symbol, Name, ISIN oder WKN)"
+                  value={searchQuery}
+                  onChange={handleSearchChange}
+                  onFocus={() => searchResults.length > 0 && setShowDropdown(true)}
+                  className="w-full px-4 py-2.5 bg-dark-700 border border-dark-600 rounded-lg text-sm text-white placeholder-gray-500 focus:outline-none focus:border-accent-500"
+                />
+                {searching && (
+                  <div className="absolute right-3 top-1/2 -translate-y-1/2">
+                    <div className="w-4 h-4 border-2 border-accent-500 border-t-transparent rounded-full animate-spin"></div>
+                  </div>
+                )}
 
-              {showDropdown && searchResults.length > 0 && (
-                <div className="absolute z-50 w-full mt-1 bg-dark-700 border border-dark-600 rounded-lg shadow-xl max-h-64 overflow-auto">
-                  {searchResults.map((result) => (
-                    <button
-                      key={result.symbol}
-                      onClick={() => handleSelectStock(result)}
-                      className="w-full px-4 py-3 text-left hover:bg-dark-600 transition-colors flex items-center justify-between"
-                    >
-                      <div>
-                        <div className="flex items-center gap-2">
-                          <span className="font-medium text-white">{result.symbol}</span>
-                          <span className="text-xs px-1.5 py-0.5 bg-dark-800 text-gray-400 rounded">
-                            {result.exchange}
-                          </span>
+                {showDropdown && searchResults.length > 0 && (
+                  <div className="absolute z-50 w-full mt-1 bg-dark-700 border border-dark-600 rounded-lg shadow-xl max-h-64 overflow-auto">
+                    {searchResults.map((result) => (
+                      <button
+                        key={result.symbol}
+                        onClick={() => handleSelectStock(result)}
+                        className="w-full px-4 py-3 text-left hover:bg-dark-600 transition-colors flex items-center justify-between"
+                      >
+                        <div>
+                          <div className="flex items-center gap-2">
+                            <span className="font-medium text-white">{result.symbol}</span>
+                            <span className="text-xs px-1.5 py-0.5 bg-dark-800 text-gray-400 rounded">
+                              {result.exchange}
+                            </span>
+                          </div>
+                          <p className="text-xs text-gray-500 truncate">{result.name}</p>
                         </div>
-                        <p className="text-xs text-gray-500 truncate">{result.name}</p>
-                      </div>
-                      <svg className="w-4 h-4 text-accent-400" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-                        <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M12 4v16m8-8H4" />
-                      </svg>
-                    </button>
-                  ))}
-                </div>
-              )}
-            </div>
+                        <svg className="w-4 h-4 text-accent-400" fill="none" stroke="currentColor" viewBox="0 0 24 24">
+                          <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M12 4v16m8-8H4" />
+                        </svg>
+                      </button>
+                    ))}
+                  </div>
+                )}
+              </div>
+            )}
           </div>
 
           {/* Add/Edit Form */}
@@ -754,6 +1490,865 @@ function PortfolioContent({ token }) {
             <p className="mt-4 text-gray-500 text-sm">Noch keine abgeschlossenen Trades.</p>
           )}
         </div>
+
+        {/* Savings Plans Section */}
+        <div className="bg-dark-800 rounded-xl border border-dark-600 p-4 md:p-6">
+          <button
+            onClick={() => { if (!showSavings) fetchSavingsPlans(); setShowSavings(!showSavings) }}
+            className="w-full flex items-center justify-between"
+          >
+            <h2 className="text-lg font-semibold text-white">Sparpläne</h2>
+            <svg className={`w-5 h-5 text-gray-400 transition-transform ${showSavings ? 'rotate-180' : ''}`} fill="none" stroke="currentColor" viewBox="0 0 24 24">
+              <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M19 9l-7 7-7-7" />
+            </svg>
+          </button>
+
+          {showSavings && (
+            <div className="mt-4 space-y-4 text-sm">
+              {savingsPlans.length > 0 && (
+                <div className="space-y-2">
+                  {savingsPlans.map(plan => (
+                    <div key={plan.id} className="flex flex-wrap items-center justify-between gap-2 bg-dark-700 rounded-lg p-2">
+                      <div className="text-xs text-gray-300">
+                        <span className="text-white font-medium">{plan.symbol}</span> · {plan.amount.toLocaleString('de-DE', { minimumFractionDigits: 2 })} {plan.currency}
+                        {' '}· {SAVINGS_INTERVALS[plan.interval]}{plan.interval === 'monthly' || plan.interval === 'quarterly' ? ` zum ${plan.execution_day}.` : ''}
+                        {' '}· seit {formatDate(plan.start_date)}{plan.end_date ? ` bis ${formatDate(plan.end_date)}` : ''}
+                        {plan.last_executed_at && <> · zuletzt {formatDate(plan.last_executed_at)}</>}
+                        {!plan.active && <span className="text-orange-400"> · pausiert</span>}
+                      </div>
+                      <div className="flex gap-2">
+                        {plan.active && <button onClick={() => runSavingsPlan(plan)} className="px-2 py-1 text-xs bg-accent-500 text-white rounded hover:bg-accent-600">Jetzt buchen</button>}
+                        <button onClick={() => toggleSavingsPlan(plan)} className="px-2 py-1 text-xs bg-dark-600 text-gray-300 rounded hover:bg-dark-500">{plan.active ? 'Pausieren' : 'Fortsetzen'}</button>
+                        <button onClick={() => editSavingsPlan(plan)} className="px-2 py-1 text-xs bg-dark-600 text-gray-300 rounded hover:bg-dark-500">Bearbeiten</button>
+                        <button onClick={() => deleteSavingsPlan(plan)} className="px-2 py-1 text-xs bg-dark-600 text-gray-300 rounded hover:bg-dark-500">Löschen</button>
+                      </div>
+                    </div>
+                  ))}
+                </div>
+              )}
+
+              <div className="flex flex-wrap gap-2 items-center text-xs text-gray-400">
+                <input value={savingsForm.symbol} onChange={(e) => setSavingsForm({ ...savingsForm, symbol: e.target.value.toUpperCase() })}
+                  placeholder="Symbol" className="w-28 px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white" />
+                <input type="number" value={savingsForm.amount} onChange={(e) => setSavingsForm({ ...savingsForm, amount: e.target.value })}
+                  placeholder="Rate" className="w-24 px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white text-right" />
+                <select value={savingsForm.currency} onChange={(e) => setSavingsForm({ ...savingsForm, currency: e.target.value })}
+                  className="px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white">
+                  {['EUR', 'USD', 'CHF', 'GBP'].map(c => <option key={c} value={c}>{c}</option>)}
+                </select>
+                <label className="flex items-center gap-1">Gebühr
+                  <input type="number" value={savingsForm.fee} onChange={(e) => setSavingsForm({ ...savingsForm, fee: e.target.value })}
+                    className="w-16 px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white text-right" />
+                </label>
+                <select value={savingsForm.interval} onChange={(e) => setSavingsForm({ ...savingsForm, interval: e.target.value })}
+                  className="px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white">
+                  {Object.entries(SAVINGS_INTERVALS).map(([key, label]) => <option key={key} value={key}>{label}</option>)}
+                </select>
+                {(savingsForm.interval === 'monthly' || savingsForm.interval === 'quarterly') && (
+                  <label className="flex items-center gap-1">Tag
+                    <input type="number" min="1" max="28" value={savingsForm.execution_day} onChange={(e) => setSavingsForm({ ...savingsForm, execution_day: e.target.value })}
+                      className="w-14 px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white text-right" />
+                  </label>
+                )}
+                <label className="flex items-center gap-1">Start
+                  <input type="date" value={savingsForm.start_date} onChange={(e) => setSavingsForm({ ...savingsForm, start_date: e.target.value })}
+                    className="px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white" />
+                </label>
+                <label className="flex items-center gap-1">Ende
+                  <input type="date" value={savingsForm.end_date} onChange={(e) => setSavingsForm({ ...savingsForm, end_date: e.target.value })}
+                    className="px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white" />
+                </label>
+              </div>
+
+              <div className="flex flex-wrap gap-2 items-center">
+                <button onClick={saveSavingsPlan} className="px-3 py-1.5 bg-accent-500 text-white rounded hover:bg-accent-600">
+                  {savingsForm.id ? 'Sparplan aktualisieren' : 'Sparplan anlegen'}
+                </button>
+                {savingsForm.id && (
+                  <button onClick={() => setSavingsForm({ ...EMPTY_SAVINGS_PLAN, currency: portfolioCurrency })} className="px-3 py-1.5 bg-dark-600 text-gray-200 rounded hover:bg-dark-500">Abbrechen</button>
+                )}
+                <button onClick={runSavingsBacktest} disabled={backtesting || !savingsForm.symbol}
+                  className="px-3 py-1.5 bg-dark-600 text-gray-200 rounded hover:bg-dark-500 disabled:opacity-50">
+                  {backtesting ? 'Backtest läuft…' : 'Backtest vs. BX-Trender'}
+                </button>
+                <select value={savingsForm.mode || 'defensive'} onChange={(e) => setSavingsForm({ ...savingsForm, mode: e.target.value })}
+                  className="px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white text-xs">
+                  <option value="defensive">Defensiv</option>
+                  <option value="aggressive">Aggressiv</option>
+                </select>
+              </div>
+              <p className="text-xs text-gray-500">Vergangene Ausführungen werden beim Anlegen zum historischen Schlusskurs gebucht.</p>
+              {savingsError && <p className="text-xs text-red-400">{savingsError}</p>}
+
+              {savingsBacktest && (
+                <div className="overflow-x-auto">
+                  <table className="w-full text-xs md:text-sm">
+                    <thead>
+                      <tr className="text-gray-500 border-b border-dark-600">
+                        <th className="text-left py-1 pr-2 font-normal">Strategie</th>
+                        <th className="text-right py-1 px-2 font-normal">Eingezahlt</th>
+                        <th className="text-right py-1 px-2 font-normal">Endwert</th>
+                        <th className="text-right py-1 px-2 font-normal">Rendite</th>
+                        <th className="text-right py-1 px-2 font-normal">IZF p.a.</th>
+                        <th className="text-right py-1 pl-2 font-normal">Käufe / ausgesetzt</th>
+                      </tr>
+                    </thead>
+                    <tbody>
+                      {[['plain', 'Sparplan'], ['bxtrender', 'Nur bei BX-Trender BUY']].map(([key, label]) => {
+                        const run = savingsBacktest[key]
+                        const money = (v) => `${v.toLocaleString('de-DE', { minimumFractionDigits: 2, maximumFractionDigits: 2 })} ${savingsBacktest.currency}`
+                        return (
+                          <tr key={key} className="border-b border-dark-700">
+                            <td className="py-1 pr-2 text-white">{label}</td>
+                            <td className="py-1 px-2 text-right text-gray-300">{money(run.invested)}</td>
+                            <td className="py-1 px-2 text-right text-gray-300">{money(run.final_value)}{run.cash > 0 && <span className="text-gray-500"> (davon {money(run.cash)} Cash)</span>}</td>
+                            <td className={`py-1 px-2 text-right ${run.return_pct >= 0 ? 'text-green-400' : 'text-red-400'}`}>{run.return_pct.toFixed(1)}%</td>
+                            <td className="py-1 px-2 text-right text-gray-300">{run.xirr.toFixed(1)}%</td>
+                            <td className="py-1 pl-2 text-right text-gray-300">{run.executions} / {run.skipped}</td>
+                          </tr>
+                        )
+                      })}
+                    </tbody>
+                  </table>
+                </div>
+              )}
+            </div>
+          )}
+        </div>
+
+        {/* Rebalancing Section */}
+        <div className="bg-dark-800 rounded-xl border border-dark-600 p-4 md:p-6">
+          <button
+            onClick={() => { if (!showRebalance) fetchAllocation(); setShowRebalance(!showRebalance) }}
+            className="w-full flex items-center justify-between"
+          >
+            <h2 className="text-lg font-semibold text-white">Zielallokation & Rebalancing</h2>
+            <svg className={`w-5 h-5 text-gray-400 transition-transform ${showRebalance ? 'rotate-180' : ''}`} fill="none" stroke="currentColor" viewBox="0 0 24 24">
+              <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M19 9l-7 7-7-7" />
+            </svg>
+          </button>
+
+          {showRebalance && allocation && (
+            <div className="mt-4 space-y-4 text-sm">
+              <div className="space-y-2">
+                {allocation.targets.map((t, i) => (
+                  <div key={i} className="flex flex-wrap gap-2 items-center">
+                    <select value={t.category_id ? 'category' : 'symbol'}
+                      onChange={(e) => updateTarget(i, e.target.value === 'category'
+                        ? { symbol: '', category_id: allocation.categories[0]?.id || null }
+                        : { symbol: '', category_id: null })}
+                      className="px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white">
+                      <option value="symbol">Aktie</option>
+                      {allocation.categories.length > 0 && <option value="category">Kategorie</option>}
+                    </select>
+                    {t.category_id ? (
+                      <select value={t.category_id} onChange={(e) => updateTarget(i, { category_id: Number(e.target.value) })}
+                        className="px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white">
+                        {allocation.categories.map(cat => <option key={cat.id} value={cat.id}>{cat.name}</option>)}
+                      </select>
+                    ) : (
+                      <input value={t.symbol} onChange={(e) => updateTarget(i, { symbol: e.target.value.toUpperCase() })}
+                        placeholder="Symbol" className="w-28 px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white" />
+                    )}
+                    <input type="number" value={t.weight} onChange={(e) => updateTarget(i, { weight: e.target.value })}
+                      className="w-20 px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white text-right" />
+                    <span className="text-gray-500">%</span>
+                    <button onClick={() => setAllocation({ ...allocation, targets: allocation.targets.filter((_, j) => j !== i) })}
+                      className="text-gray-500 hover:text-red-400">✕</button>
+                  </div>
+                ))}
+                <div className="flex items-center gap-3">
+                  <button onClick={() => setAllocation({ ...allocation, targets: [...allocation.targets, { symbol: '', category_id: null, weight: 0 }] })}
+                    className="px-3 py-1.5 bg-dark-600 text-gray-200 rounded hover:bg-dark-500">+ Ziel</button>
+                  {(() => {
+                    const sum = allocation.targets.reduce((s, t) => s + (Number(t.weight) || 0), 0)
+                    return <span className={`text-xs ${Math.abs(sum - 100) < 0.01 ? 'text-green-400' : 'text-orange-400'}`}>Summe {sum.toFixed(2)}%</span>
+                  })()}
+                </div>
+              </div>
+
+              <div className="flex flex-wrap gap-3 items-center text-xs text-gray-400">
+                <label className="flex items-center gap-1">Schwelle
+                  <input type="number" value={allocation.settings.threshold}
+                    onChange={(e) => setAllocation({ ...allocation, settings: { ...allocation.settings, threshold: e.target.value } })}
+                    className="w-16 px-2 py-1 bg-dark-700 border border-dark-600 rounded text-white text-right" /> %-Pkt.
+                </label>
+                <label className="flex items-center gap-1">Mindestorder
+                  <input type="number" value={allocation.settings.min_trade}
+                    onChange={(e) => setAllocation({ ...allocation, settings: { ...allocation.settings, min_trade: e.target.value } })}
+                    className="w-20 px-2 py-1 bg-dark-700 border border-dark-600 rounded text-white text-right" /> USD
+                </label>
+                <label className="flex items-center gap-1">
+                  <input type="checkbox" checked={allocation.settings.fractional}
+                    onChange={(e) => setAllocation({ ...allocation, settings: { ...allocation.settings, fractional: e.target.checked } })} />
+                  Bruchstücke
+                </label>
+                <label className="flex items-center gap-1">
+                  <input type="checkbox" checked={allocation.settings.tax_aware}
+                    onChange={(e) => setAllocation({ ...allocation, settings: { ...allocation.settings, tax_aware: e.target.checked } })} />
+                  Steueroptimiert (Verluste zuerst verkaufen)
+                </label>
+                <label className="flex items-center gap-1">Ein-/Auszahlung
+                  <input type="number" value={rebalanceCash} onChange={(e) => setRebalanceCash(e.target.value)} placeholder="0"
+                    className="w-24 px-2 py-1 bg-dark-700 border border-dark-600 rounded text-white text-right" /> {currency}
+                </label>
+              </div>
+
+              <div className="flex gap-2">
+                <button onClick={saveAllocation} className="px-3 py-1.5 bg-dark-600 text-gray-200 rounded hover:bg-dark-500">Ziele speichern</button>
+                <button onClick={proposeRebalance} className="px-3 py-1.5 bg-accent-500 text-white rounded hover:bg-accent-600">Vorschlag berechnen</button>
+              </div>
+              {rebalanceError && <p className="text-xs text-red-400">{rebalanceError}</p>}
+
+              {rebalanceProposal && (
+                <div className="space-y-3">
+                  <div className="overflow-x-auto">
+                    <table className="w-full text-xs md:text-sm">
+                      <thead>
+                        <tr className="text-gray-500 border-b border-dark-600">
+                          <th className="text-left py-1 pr-2 font-normal">Ziel</th>
+                          <th className="text-right py-1 px-2 font-normal">Ist</th>
+                          <th className="text-right py-1 px-2 font-normal">Soll</th>
+                          <th className="text-right py-1 pl-2 font-normal">Abweichung</th>
+                        </tr>
+                      </thead>
+                      <tbody>
+                        {rebalanceProposal.buckets.map(b => (
+                          <tr key={b.key} className="border-b border-dark-700">
+                            <td className="py-1 pr-2 text-white">{b.label}</td>
+                            <td className="py-1 px-2 text-right text-gray-300">{b.current_weight.toFixed(1)}%</td>
+                            <td className="py-1 px-2 text-right text-gray-300">{b.target_weight.toFixed(1)}%</td>
+                            <td className={`py-1 pl-2 text-right ${Math.abs(b.drift) >= allocation.settings.threshold ? 'text-orange-400' : 'text-gray-400'}`}>{b.drift >= 0 ? '+' : ''}{b.drift.toFixed(1)} Pkt.</td>
+                          </tr>
+                        ))}
+                      </tbody>
+                    </table>
+                  </div>
+                  {!rebalanceProposal.needs_rebalance ? (
+                    <p className="text-xs text-green-400">Alle Ziele liegen innerhalb der Schwelle – kein Rebalancing nötig.</p>
+                  ) : (
+                    <>
+                      <div className="overflow-x-auto">
+                        <table className="w-full text-xs md:text-sm">
+                          <thead>
+                            <tr className="text-gray-500 border-b border-dark-600">
+                              <th className="text-left py-1 pr-2 font-normal">Order</th>
+                              <th className="text-left py-1 px-2 font-normal">Aktie</th>
+                              <th className="text-right py-1 px-2 font-normal">Stück</th>
+                              <th className="text-right py-1 px-2 font-normal">Wert</th>
+                              <th className="text-right py-1 pl-2 font-normal">Realisiert</th>
+                            </tr>
+                          </thead>
+                          <tbody>
+                            {rebalanceProposal.trades.map((tr, i) => (
+                              <tr key={i} className="border-b border-dark-700">
+                                <td className={`py-1 pr-2 ${tr.side === 'buy' ? 'text-green-400' : 'text-red-400'}`}>{tr.side === 'buy' ? 'Kauf' : 'Verkauf'}</td>
+                                <td className="py-1 px-2 text-white">{tr.symbol}</td>
+                                <td className="py-1 px-2 text-right text-gray-300">{tr.quantity.toLocaleString('de-DE', { maximumFractionDigits: 4 })}</td>
+                                <td className="py-1 px-2 text-right text-gray-300">{formatPrice(tr.value)}</td>
+                                <td className={`py-1 pl-2 text-right ${tr.realized_gain >= 0 ? 'text-gray-300' : 'text-red-400'}`}>{tr.side === 'sell' ? formatPrice(tr.realized_gain) : ''}</td>
+                              </tr>
+                            ))}
+                          </tbody>
+                        </table>
+                      </div>
+                      <p className="text-xs text-gray-500">
+                        Käufe {formatPrice(rebalanceProposal.buy_total)} · Verkäufe {formatPrice(rebalanceProposal.sell_total)} · Restbetrag {formatPrice(rebalanceProposal.cash_remaining)}
+                        {' '}· realisiert {formatPrice(rebalanceProposal.realized_gain)} · geschätzte Steuer {formatPrice(rebalanceProposal.tax_estimate)}
+                      </p>
+                      {rebalanceProposal.trades.length > 0 && (
+                        <button onClick={saveRebalancePlan} className="px-3 py-1.5 bg-dark-600 text-gray-200 rounded hover:bg-dark-500">Plan speichern</button>
+                      )}
+                    </>
+                  )}
+                </div>
+              )}
+
+              {rebalancePlans.length > 0 && (
+                <div className="space-y-2">
+                  <div className="text-xs text-gray-500">Gespeicherte Pläne</div>
+                  {rebalancePlans.map(plan => (
+                    <div key={plan.id} className="flex flex-wrap items-center justify-between gap-2 bg-dark-700 rounded-lg p-2">
+                      <div className="text-xs text-gray-300">
+                        #{plan.id} · {formatDate(plan.created_at)} · {plan.trades.length} Trades · {plan.trades.map(tr => `${tr.side === 'buy' ? '+' : '−'}${tr.quantity} ${tr.symbol}`).join(', ')}
+                      </div>
+                      <div className="flex gap-2">
+                        {plan.status === 'executed' ? (
+                          <span className="text-xs text-green-400">ausgeführt {formatDate(plan.executed_at)}</span>
+                        ) : (
+                          <button onClick={() => executeRebalancePlan(plan)} className="px-2 py-1 text-xs bg-accent-500 text-white rounded hover:bg-accent-600">Ausführen</button>
+                        )}
+                        <button onClick={() => deleteRebalancePlan(plan)} className="px-2 py-1 text-xs bg-dark-600 text-gray-300 rounded hover:bg-dark-500">Löschen</button>
+                      </div>
+                    </div>
+                  ))}
+                </div>
+              )}
+            </div>
+          )}
+        </div>
+
+        {/* Risk Section */}
+        <div className="bg-dark-800 rounded-xl border border-dark-600 p-4 md:p-6">
+          <button
+            onClick={() => { if (!showRisk) fetchRisk(); setShowRisk(!showRisk) }}
+            className="w-full flex items-center justify-between"
+          >
+            <h2 className="text-lg font-semibold text-white">Risikoanalyse</h2>
+            <svg className={`w-5 h-5 text-gray-400 transition-transform ${showRisk ? 'rotate-180' : ''}`} fill="none" stroke="currentColor" viewBox="0 0 24 24">
+              <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M19 9l-7 7-7-7" />
+            </svg>
+          </button>
+
+          {showRisk && (
+            <div className="mt-4 space-y-4 text-sm">
+              <div className="flex flex-wrap gap-2 items-center">
+                <select value={riskParams.owner} onChange={(e) => updateRiskParams({ owner: e.target.value })}
+                  className="px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white">
+                  <option value="me">Mein Portfolio</option>
+                  <option value="flipper">FlipperBot</option>
+                  <option value="lutz">Lutz</option>
+                  <option value="quant">Quant</option>
+                  <option value="ditz">Ditz</option>
+                  <option value="trader">Trader</option>
+                </select>
+                <select value={riskParams.years} onChange={(e) => updateRiskParams({ years: Number(e.target.value) })}
+                  className="px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white">
+                  {[1, 3, 5, 10].map(y => <option key={y} value={y}>{y} Jahr{y !== 1 ? 'e' : ''} Historie</option>)}
+                </select>
+              </div>
+
+              {!riskReport && <p className="text-xs text-gray-500">Lade Kursdaten…</p>}
+              {riskReport && riskReport.positions.length === 0 && (
+                <p className="text-xs text-gray-500">Keine offenen Positionen.</p>
+              )}
+              {riskReport && riskReport.positions.length > 0 && (() => {
+                const pct = (v) => `${(v || 0).toFixed(2)}%`
+                const corrColor = (v) => v > 0.7 ? 'bg-red-500/60' : v > 0.4 ? 'bg-orange-500/40' : v < -0.2 ? 'bg-blue-500/40' : 'bg-dark-600'
+                return (
+                  <>
+                    <div className="grid grid-cols-2 md:grid-cols-4 gap-3">
+                      <div className="bg-dark-700 rounded-lg p-3">
+                        <div className="text-xs text-gray-500">Volatilität p.a.</div>
+                        <div className="font-semibold text-white">{pct(riskReport.volatility)}</div>
+                      </div>
+                      <div className="bg-dark-700 rounded-lg p-3">
+                        <div className="text-xs text-gray-500">Max Drawdown ({riskReport.years}J)</div>
+                        <div className="font-semibold text-red-400">{pct(riskReport.max_drawdown)}</div>
+                      </div>
+                      <div className="bg-dark-700 rounded-lg p-3">
+                        <div className="text-xs text-gray-500">Diversifikationsquote</div>
+                        <div className="font-semibold text-white">{(riskReport.diversification_ratio || 0).toFixed(2)}</div>
+                      </div>
+                      <div className="bg-dark-700 rounded-lg p-3">
+                        <div className="text-xs text-gray-500">Effektive Positionen</div>
+                        <div className="font-semibold text-white">{(riskReport.effective_positions || 0).toFixed(1)} von {riskReport.positions.length}</div>
+                      </div>
+                    </div>
+
+                    {riskReport.correlated_pairs.length > 0 && (
+                      <div className="bg-red-500/10 border border-red-500/30 rounded-lg p-3 text-xs text-red-300">
+                        Stark korreliert: {riskReport.correlated_pairs.slice(0, 6).map(p => `${p.a}/${p.b} (${p.correlation.toFixed(2)})`).join(', ')}
+                      </div>
+                    )}
+
+                    {riskReport.var.length > 0 && (
+                      <div className="overflow-x-auto">
+                        <table className="w-full text-xs md:text-sm">
+                          <thead>
+                            <tr className="text-gray-500 border-b border-dark-600">
+                              <th className="text-left py-1 pr-2 font-normal">Methode</th>
+                              <th className="text-right py-1 px-2 font-normal">Konfidenz</th>
+                              <th className="text-right py-1 px-2 font-normal">VaR (1 Tag)</th>
+                              <th className="text-right py-1 pl-2 font-normal">CVaR</th>
+                            </tr>
+                          </thead>
+                          <tbody>
+                            {riskReport.var.map(v => (
+                              <tr key={`${v.method}-${v.confidence}`} className="border-b border-dark-700">
+                                <td className="py-1 pr-2 text-gray-300">{v.method === 'historical' ? 'Historisch' : 'Parametrisch'}</td>
+                                <td className="py-1 px-2 text-right text-gray-300">{v.confidence}%</td>
+                                <td className="py-1 px-2 text-right text-red-400">{formatPrice(v.var)} ({pct(v.var_pct)})</td>
+                                <td className="py-1 pl-2 text-right text-red-400">{formatPrice(v.cvar)} ({pct(v.cvar_pct)})</td>
+                              </tr>
+                            ))}
+                          </tbody>
+                        </table>
+                      </div>
+                    )}
+
+                    <div className="grid md:grid-cols-2 gap-4">
+                      {[['Sektoren', riskReport.sectors], ['Länder', riskReport.countries]].map(([label, buckets]) => (
+                        <div key={label}>
+                          <div className="text-xs text-gray-500 mb-1">{label}</div>
+                          {buckets.map(b => (
+                            <div key={b.name} className="flex items-center gap-2 mb-1">
+                              <div className="w-28 truncate text-gray-300 text-xs">{b.name}</div>
+                              <div className="flex-1 h-2 bg-dark-700 rounded">
+                                <div className={`h-2 rounded ${b.weight > 40 ? 'bg-red-500/70' : 'bg-accent-500/70'}`} style={{ width: `${b.weight}%` }} />
+                              </div>
+                              <div className="w-14 text-right text-xs text-gray-400">{pct(b.weight)}</div>
+                            </div>
+                          ))}
+                        </div>
+                      ))}
+                    </div>
+
+                    {riskReport.symbols.length > 1 && (
+                      <div className="overflow-x-auto">
+                        <div className="text-xs text-gray-500 mb-1">Korrelationsmatrix (tägliche Renditen)</div>
+                        <table className="text-[10px] md:text-xs">
+                          <thead>
+                            <tr>
+                              <th></th>
+                              {riskReport.symbols.map(s => <th key={s} className="px-1 font-normal text-gray-400">{s}</th>)}
+                            </tr>
+                          </thead>
+                          <tbody>
+                            {riskReport.correlation.map((row, i) => (
+                              <tr key={riskReport.symbols[i]}>
+                                <td className="pr-2 text-gray-400">{riskReport.symbols[i]}</td>
+                                {row.map((v, j) => (
+                                  <td key={j} className={`px-1 text-center text-white ${i === j ? 'bg-dark-700' : corrColor(v)}`}>{v.toFixed(2)}</td>
+                                ))}
+                              </tr>
+                            ))}
+                          </tbody>
+                        </table>
+                      </div>
+                    )}
+
+                    <p className="text-xs text-gray-500">
+                      Die aktuellen Bestände werden mit den Tageskursen der letzten {riskReport.years} Jahre gehalten ({riskReport.days} Handelstage).
+                      {riskReport.missing_data.length > 0 && ` Ohne Kursdaten: ${riskReport.missing_data.join(', ')}.`}
+                    </p>
+                  </>
+                )
+              })()}
+            </div>
+          )}
+        </div>
+
+        {/* Dividends Section */}
+        <div className="bg-dark-800 rounded-xl border border-dark-600 p-4 md:p-6">
+          <button
+            onClick={() => { if (!showDividends) fetchDividends(); setShowDividends(!showDividends) }}
+            className="w-full flex items-center justify-between"
+          >
+            <h2 className="text-lg font-semibold text-white">Dividenden & Prognose</h2>
+            <svg className={`w-5 h-5 text-gray-400 transition-transform ${showDividends ? 'rotate-180' : ''}`} fill="none" stroke="currentColor" viewBox="0 0 24 24">
+              <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M19 9l-7 7-7-7" />
+            </svg>
+          </button>
+
+          {showDividends && (
+            <div className="mt-4 space-y-4 text-sm">
+              <div className="flex flex-wrap gap-2 items-center">
+                <select value={dividendOwner} onChange={(e) => { setDividendOwner(e.target.value); fetchDividends(e.target.value) }}
+                  className="px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white">
+                  <option value="me">Mein Portfolio</option>
+                  <option value="flipper">FlipperBot</option>
+                  <option value="lutz">Lutz</option>
+                  <option value="quant">Quant</option>
+                  <option value="ditz">Ditz</option>
+                  <option value="trader">Trader</option>
+                </select>
+                {dividendOwner === 'me' && (
+                  <button onClick={syncDividends} className="px-3 py-1.5 bg-dark-600 text-gray-200 rounded hover:bg-dark-500">
+                    Dividenden abgleichen
+                  </button>
+                )}
+              </div>
+
+              {dividendReport && (() => {
+                const money = (v) => (v || 0).toLocaleString('de-DE', { style: 'currency', currency: dividendReport.currency })
+                const maxMonth = Math.max(...dividendReport.monthly.map(m => m.amount), 0)
+                return (
+                  <>
+                    <div className="grid grid-cols-2 gap-3">
+                      <div className="bg-dark-700 rounded-lg p-3">
+                        <div className="text-xs text-gray-500">Erhalten (12 Monate, netto)</div>
+                        <div className="font-semibold text-white">{money(dividendReport.received_12m)}</div>
+                      </div>
+                      <div className="bg-dark-700 rounded-lg p-3">
+                        <div className="text-xs text-gray-500">Prognose nächste 12 Monate</div>
+                        <div className="font-semibold text-green-400">{money(dividendReport.forecast_12m)}</div>
+                      </div>
+                    </div>
+                    <div className="flex items-end gap-1 h-24">
+                      {dividendReport.monthly.map(m => (
+                        <div key={m.month} className="flex-1 flex flex-col items-center justify-end h-full" title={`${m.month}: ${money(m.amount)}`}>
+                          <div className="w-full bg-accent-500/70 rounded-t" style={{ height: maxMonth > 0 ? `${(m.amount / maxMonth) * 100}%` : 0 }} />
+                          <div className="text-[10px] text-gray-500 mt-1">{m.month.slice(5)}</div>
+                        </div>
+                      ))}
+                    </div>
+                    {dividendReport.forecast.length > 0 ? (
+                      <div className="overflow-x-auto">
+                        <table className="w-full text-xs md:text-sm">
+                          <thead>
+                            <tr className="text-gray-500 border-b border-dark-600">
+                              <th className="text-left py-1 pr-2 font-normal">Ex-Tag</th>
+                              <th className="text-left py-1 px-2 font-normal">Aktie</th>
+                              <th className="text-right py-1 px-2 font-normal">Stück</th>
+                              <th className="text-right py-1 px-2 font-normal">je Aktie</th>
+                              <th className="text-right py-1 pl-2 font-normal">Betrag</th>
+                            </tr>
+                          </thead>
+                          <tbody>
+                            {dividendReport.forecast.map(f => (
+                              <tr key={`${f.symbol}-${f.ex_date}`} className="border-b border-dark-700">
+                                <td className="py-1 pr-2 text-gray-300">{formatDate(f.ex_date)}{f.estimated && <span className="text-gray-500"> (geschätzt)</span>}</td>
+                                <td className="py-1 px-2 text-white">{f.symbol}</td>
+                                <td className="py-1 px-2 text-right text-gray-300">{f.quantity.toLocaleString('de-DE', { maximumFractionDigits: 4 })}</td>
+                                <td className="py-1 px-2 text-right text-gray-300">{f.amount_per_share.toFixed(4)} {f.quote_currency}</td>
+                                <td className="py-1 pl-2 text-right text-green-400">{money(f.amount)}</td>
+                              </tr>
+                            ))}
+                          </tbody>
+                        </table>
+                      </div>
+                    ) : (
+                      <p className="text-xs text-gray-500">Keine Dividenden in den nächsten 12 Monaten erwartet.</p>
+                    )}
+                    <p className="text-xs text-gray-500">
+                      Dividenden werden am Ex-Tag für den Bestand am Vortag gebucht. Die Prognose nutzt angekündigte Dividenden, sonst die Zahlungen der letzten 12 Monate.
+                    </p>
+                  </>
+                )
+              })()}
+            </div>
+          )}
+        </div>
+
+        {/* Tax Report Section */}
+        <div className="bg-dark-800 rounded-xl border border-dark-600 p-4 md:p-6">
+          <button
+            onClick={() => { if (!showTax) fetchTaxReport(); setShowTax(!showTax) }}
+            className="w-full flex items-center justify-between"
+          >
+            <h2 className="text-lg font-semibold text-white">Steuerreport (Abgeltungsteuer)</h2>
+            <svg className={`w-5 h-5 text-gray-400 transition-transform ${showTax ? 'rotate-180' : ''}`} fill="none" stroke="currentColor" viewBox="0 0 24 24">
+              <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M19 9l-7 7-7-7" />
+            </svg>
+          </button>
+
+          {showTax && (
+            <div className="mt-4 space-y-4 text-sm">
+              <div className="flex flex-wrap gap-2 items-center">
+                <select value={taxParams.owner} onChange={(e) => setTaxParams({ ...taxParams, owner: e.target.value })}
+                  className="px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white">
+                  <option value="me">Mein Portfolio</option>
+                  <option value="flipper">FlipperBot</option>
+                  <option value="lutz">Lutz</option>
+                  <option value="quant">Quant</option>
+                  <option value="ditz">Ditz</option>
+                  <option value="trader">Trader</option>
+                </select>
+                <select value={taxParams.year} onChange={(e) => setTaxParams({ ...taxParams, year: e.target.value })}
+                  className="px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white">
+                  {[0, 1, 2, 3, 4].map(i => new Date().getFullYear() - i).map(y => <option key={y} value={y}>{y}</option>)}
+                </select>
+                <select value={taxParams.allowance} onChange={(e) => setTaxParams({ ...taxParams, allowance: e.target.value })}
+                  className="px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white">
+                  <option value={1000}>Pauschbetrag 1.000 € (ledig)</option>
+                  <option value={2000}>Pauschbetrag 2.000 € (verheiratet)</option>
+                  <option value={0}>Pauschbetrag ausgeschöpft</option>
+                </select>
+                <select value={taxParams.church_tax} onChange={(e) => setTaxParams({ ...taxParams, church_tax: e.target.value })}
+                  className="px-2 py-1.5 bg-dark-700 border border-dark-600 rounded text-white">
+                  <option value={0}>Keine Kirchensteuer</option>
+                  <option value={8}>Kirchensteuer 8 % (BY, BW)</option>
+                  <option value={9}>Kirchensteuer 9 %</option>
+                </select>
+                <button onClick={fetchTaxReport} className="px-3 py-1.5 bg-accent-500 text-white rounded hover:bg-accent-400">Berechnen</button>
+                <button onClick={() => downloadTaxReport('csv')} className="px-3 py-1.5 bg-dark-600 text-gray-200 rounded hover:bg-dark-500">CSV</button>
+                <button onClick={() => downloadTaxReport('pdf')} className="px-3 py-1.5 bg-dark-600 text-gray-200 rounded hover:bg-dark-500">PDF</button>
+              </div>
+
+              {taxReport && (
+                <>
+                  <div className="grid grid-cols-2 md:grid-cols-4 gap-3">
+                    {[
+                      ['Aktiengewinne', taxReport.equity_gains],
+                      ['Aktienverluste', -taxReport.equity_losses],
+                      ['Sonstige (Fonds/ETFs)', taxReport.other_gains - taxReport.other_losses],
+                      ['Dividenden', taxReport.dividends],
+                      ['Verlustvortrag Vorjahr', -(taxReport.carry_in_equity + taxReport.carry_in_other)],
+                      ['Pauschbetrag genutzt', taxReport.allowance_used],
+                      ['Bemessungsgrundlage', taxReport.tax_base],
+                      ['Anrechenbare Quellensteuer', taxReport.creditable_withholding],
+                      ['Kapitalertragsteuer', taxReport.kapitalertragsteuer],
+                      ['Solidaritätszuschlag', taxReport.soli],
+                      ['Kirchensteuer', taxReport.kirchensteuer],
+                      ['Steuer gesamt', taxReport.total_tax],
+                      ['Verlustvortrag Folgejahr', taxReport.carry_out_equity + taxReport.carry_out_other],
+                      ['Ergebnis vor Steuern', taxReport.pre_tax_result],
+                      ['Ergebnis nach Steuern', taxReport.after_tax_result]
+                    ].map(([label, value]) => (
+                      <div key={label} className="bg-dark-700 rounded-lg p-3">
+                        <div className="text-xs text-gray-500">{label}</div>
+                        <div className="font-semibold text-white">
+                          {(value || 0).toLocaleString('de-DE', { style: 'currency', currency: 'EUR' })}
+                        </div>
+                      </div>
+                    ))}
+                  </div>
+                  <p className="text-xs text-gray-500">{taxReport.items.length} steuerrelevante Vorgänge in {taxReport.year}</p>
+                  {taxReport.notes?.map((note) => <p key={note} className="text-xs text-gray-500">{note}</p>)}
+                </>
+              )}
+            </div>
+          )}
+        </div>
+
+        {/* Transactions & Tax Lots Section */}
+        <div className="bg-dark-800 rounded-xl border border-dark-600 p-4 md:p-6">
+          <button
+            onClick={() => { if (!showLedger) fetchLedger(); setShowLedger(!showLedger) }}
+            className="w-full flex items-center justify-between"
+          >
+            <h2 className="text-lg font-semibold text-white">Transaktionen & Steuer-Lots</h2>
+            <svg className={`w-5 h-5 text-gray-400 transition-transform ${showLedger ? 'rotate-180' : ''}`} fill="none" stroke="currentColor" viewBox="0 0 24 24">
+              <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M19 9l-7 7-7-7" />
+            </svg>
+          </button>
+
+          {showLedger && (
+            <div className="mt-4 space-y-6">
+              {lots && (
+                <div className="grid grid-cols-2 md:grid-cols-4 gap-3">
+                  <div className="bg-dark-700 rounded-lg p-3">
+                    <div className="text-xs text-gray-500">Realisiert (FIFO)</div>
+                    <div className={`font-semibold ${lots.realized_gain >= 0 ? 'text-green-400' : 'text-red-400'}`}>{formatPrice(lots.realized_gain)}</div>
+                  </div>
+                  <div className="bg-dark-700 rounded-lg p-3">
+                    <div className="text-xs text-gray-500">Dividenden</div>
+                    <div className="font-semibold text-white">{formatPrice(lots.dividends)}</div>
+                  </div>
+                  <div className="bg-dark-700 rounded-lg p-3">
+                    <div className="text-xs text-gray-500">Gebühren</div>
+                    <div className="font-semibold text-white">{formatPrice(lots.fees)}</div>
+                  </div>
+                  <div className="bg-dark-700 rounded-lg p-3">
+                    <div className="text-xs text-gray-500">Einbehaltene Steuern</div>
+                    <div className="font-semibold text-white">{formatPrice(lots.taxes)}</div>
+                  </div>
+                </div>
+              )}
+
+              {/* Broker import */}
+              <div className="bg-dark-700 rounded-lg p-3 space-y-3">
+                <div className="flex flex-wrap items-center gap-2 text-sm">
+                  <span className="text-gray-400">Depot-Import (CSV):</span>
+                  <select
+                    value={importBroker}
+                    onChange={(e) => setImportBroker(e.target.value)}
+                    className="px-2 py-1.5 bg-dark-800 border border-dark-600 rounded text-white"
+                  >
+                    <option value="auto">Automatisch erkennen</option>
+                    <option value="traderepublic">Trade Republic</option>
+                    <option value="scalable">Scalable Capital</option>
+                    <option value="comdirect">comdirect</option>
+                    <option value="ibkr">IBKR Flex Query</option>
+                  </select>
+                  <label className="px-3 py-1.5 bg-dark-600 text-gray-200 rounded cursor-pointer hover:bg-dark-500">
+                    Datei wählen
+                    <input type="file" accept=".csv,.txt" onChange={handleImportFile} className="hidden" />
+                  </label>
+                  {importing && <span className="text-gray-500">Verarbeite...</span>}
+                </div>
+                {importPreview && (
+                  <div className="text-sm space-y-2">
+                    {importPreview.error && <p className="text-red-400">{importPreview.error}</p>}
+                    {importPreview.broker && (
+                      <p className="text-gray-400">
+                        {importPreview.broker}: {importPreview.summary?.new || 0} neu, {importPreview.summary?.duplicate || 0} bereits vorhanden,
+                        {' '}{importPreview.summary?.skipped || 0} übersprungen, {importPreview.summary?.error || 0} Fehler
+                        {importPreview.imported > 0 && <span className="text-green-400"> – {importPreview.imported} importiert</span>}
+                      </p>
+                    )}
+                    {importPreview.rows?.length > 0 && (
+                      <div className="max-h-64 overflow-y-auto">
+                        <table className="w-full text-xs">
+                          <tbody>
+                            {importPreview.rows.map((row) => (
+                              <tr key={row.line} className="border-b border-dark-600/50 last:border-0">
+                                <td className="py-1 pr-2 text-gray-500">{row.line}</td>
+                                <td className={`py-1 pr-2 ${row.status === 'new' ? 'text-green-400' : row.status === 'error' ? 'text-red-400' : 'text-gray-500'}`}>{row.status}</td>
+                                <td className="py-1 pr-2 text-gray-400">{row.transaction.type ? formatDate(row.transaction.date) : ''}</td>
+                                <td className="py-1 pr-2 text-gray-300">{TX_TYPES[row.transaction.type] || ''}</td>
+                                <td className="py-1 pr-2 text-white">{row.transaction.symbol || row.isin}</td>
+                                <td className="py-1 pr-2 text-right text-gray-300">{row.transaction.quantity ? +row.transaction.quantity.toFixed(6) : ''}</td>
+                                <td className="py-1 pr-2 text-right text-gray-300">
+                                  {row.transaction.price ? row.transaction.price.toFixed(2) : row.transaction.amount ? row.transaction.amount.toFixed(2) : ''}
+                                  {row.original_currency && <span className="text-gray-500"> ({row.original_currency}→{row.transaction.currency})</span>}
+                                </td>
+                                <td className="py-1 text-gray-500">{row.message}</td>
+                              </tr>
+                            ))}
+                          </tbody>
+                        </table>
+                      </div>
+                    )}
+                    {importData && !importPreview.error && importPreview.summary?.new > 0 && !importPreview.imported && (
+                      <button
+                        onClick={() => runImport(false)}
+                        disabled={importing}
+                        className="px-3 py-1.5 bg-accent-500 text-white rounded hover:bg-accent-400 disabled:opacity-50"
+                      >
+                        {importPreview.summary.new} Transaktionen übernehmen
+                      </button>
+                    )}
+                  </div>
+                )}
+              </div>
+
+              {/* Transaction form */}
+              <form onSubmit={handleTxSubmit} className="bg-dark-700 rounded-lg p-3 grid grid-cols-2 md:grid-cols-6 gap-2 text-sm">
+                <select
+                  value={txForm.type}
+                  onChange={(e) => setTxForm({ ...txForm, type: e.target.value })}
+                  className="px-2 py-1.5 bg-dark-800 border border-dark-600 rounded text-white"
+                >
+                  {Object.entries(TX_TYPES).map(([key, label]) => <option key={key} value={key}>{label}</option>)}
+                </select>
+                <input
+                  value={txForm.symbol}
+                  onChange={(e) => setTxForm({ ...txForm, symbol: e.target.value })}
+                  placeholder="Symbol / ISIN / WKN"
+                  className="px-2 py-1.5 bg-dark-800 border border-dark-600 rounded text-white"
+                />
+                <input
+                  type="date"
+                  value={txForm.date}
+                  onChange={(e) => setTxForm({ ...txForm, date: e.target.value })}
+                  className="px-2 py-1.5 bg-dark-800 border border-dark-600 rounded text-white"
+                />
+                {['buy', 'sell', 'transfer_in', 'transfer_out'].includes(txForm.type) && (
+                  <input type="number" step="any" value={txForm.quantity} placeholder="Stück"
+                    onChange={(e) => setTxForm({ ...txForm, quantity: e.target.value })}
+                    className="px-2 py-1.5 bg-dark-800 border border-dark-600 rounded text-white" />
+                )}
+                {['buy', 'sell', 'transfer_in'].includes(txForm.type) && (
+                  <input type="number" step="any" value={txForm.price} placeholder={txForm.type === 'transfer_in' ? 'Einstandskurs' : 'Kurs'}
+                    onChange={(e) => setTxForm({ ...txForm, price: e.target.value })}
+                    className="px-2 py-1.5 bg-dark-800 border border-dark-600 rounded text-white" />
+                )}
+                {['dividend', 'fee'].includes(txForm.type) && (
+                  <input type="number" step="0.01" value={txForm.amount} placeholder="Betrag"
+                    onChange={(e) => setTxForm({ ...txForm, amount: e.target.value })}
+                    className="px-2 py-1.5 bg-dark-800 border border-dark-600 rounded text-white" />
+                )}
+                {txForm.type === 'split' && (
+                  <input type="number" step="any" value={txForm.ratio} placeholder="Verhältnis (z.B. 4)"
+                    onChange={(e) => setTxForm({ ...txForm, ratio: e.target.value })}
+                    className="px-2 py-1.5 bg-dark-800 border border-dark-600 rounded text-white" />
+                )}
+                {['buy', 'sell'].includes(txForm.type) && (
+                  <input type="number" step="0.01" value={txForm.fees} placeholder="Gebühren"
+                    onChange={(e) => setTxForm({ ...txForm, fees: e.target.value })}
+                    className="px-2 py-1.5 bg-dark-800 border border-dark-600 rounded text-white" />
+                )}
+                {['sell', 'dividend'].includes(txForm.type) && (
+                  <input type="number" step="0.01" value={txForm.taxes} placeholder="Steuern"
+                    onChange={(e) => setTxForm({ ...txForm, taxes: e.target.value })}
+                    className="px-2 py-1.5 bg-dark-800 border border-dark-600 rounded text-white" />
+                )}
+                <select
+                  value={txForm.currency}
+                  onChange={(e) => setTxForm({ ...txForm, currency: e.target.value })}
+                  className="px-2 py-1.5 bg-dark-800 border border-dark-600 rounded text-white"
+                >
+                  {Object.keys(CURRENCY_SYMBOLS).map(c => <option key={c} value={c}>{c}</option>)}
+                </select>
+                <div className="flex gap-2 col-span-2 md:col-span-1">
+                  <button type="submit" className="flex-1 px-3 py-1.5 bg-accent-500 text-white rounded hover:bg-accent-400">
+                    {editingTx ? 'Speichern' : 'Buchen'}
+                  </button>
+                  {editingTx && (
+                    <button type="button" onClick={() => { setEditingTx(null); setTxForm({ ...EMPTY_TX, currency: portfolioCurrency }) }} className="px-3 py-1.5 bg-dark-600 text-gray-300 rounded">
+                      ✕
+                    </button>
+                  )}
+                </div>
+              </form>
+              {ledgerError && <p className="text-sm text-red-400">{ledgerError}</p>}
+
+              {/* Open lots */}
+              {lots?.lots?.length > 0 && (
+                <div className="overflow-x-auto">
+                  <h3 className="text-sm font-medium text-gray-400 mb-2">Offene Lots (FIFO)</h3>
+                  <table className="w-full text-sm">
+                    <thead>
+                      <tr className="text-left text-xs text-gray-500 border-b border-dark-600">
+                        <th className="pb-2 pr-4">Symbol</th>
+                        <th className="pb-2 pr-4">Anschaffung</th>
+                        <th className="pb-2 pr-4 text-right">Stück</th>
+                        <th className="pb-2 text-right">Einstand/Stück</th>
+                      </tr>
+                    </thead>
+                    <tbody>
+                      {lots.lots.map((lot, i) => (
+                        <tr key={`${lot.transaction_id}-${i}`} className="border-b border-dark-700/50 last:border-0">
+                          <td className="py-1.5 pr-4 text-white">{lot.symbol}</td>
+                          <td className="py-1.5 pr-4 text-gray-400">{formatDate(lot.date)}</td>
+                          <td className="py-1.5 pr-4 text-right text-gray-300">{+lot.quantity.toFixed(6)}</td>
+                          <td className="py-1.5 text-right text-gray-300">{CURRENCY_SYMBOLS[lot.currency] || '€'}{lot.cost_per_share.toFixed(2)}</td>
+                        </tr>
+                      ))}
+                    </tbody>
+                  </table>
+                </div>
+              )}
+
+              {/* Ledger */}
+              <div className="overflow-x-auto">
+                <h3 className="text-sm font-medium text-gray-400 mb-2">Transaktionen ({transactions.length})</h3>
+                {transactions.length === 0 ? (
+                  <p className="text-gray-500 text-sm">Noch keine Transaktionen.</p>
+                ) : (
+                  <table className="w-full text-sm">
+                    <thead>
+                      <tr className="text-left text-xs text-gray-500 border-b border-dark-600">
+                        <th className="pb-2 pr-4">Datum</th>
+                        <th className="pb-2 pr-4">Typ</th>
+                        <th className="pb-2 pr-4">Symbol</th>
+                        <th className="pb-2 pr-4 text-right">Stück</th>
+                        <th className="pb-2 pr-4 text-right">Kurs / Betrag</th>
+                        <th className="pb-2 pr-4 text-right">Gebühren</th>
+                        <th className="pb-2"></th>
+                      </tr>
+                    </thead>
+                    <tbody>
+                      {transactions.map((tx) => {
+                        const sym = CURRENCY_SYMBOLS[tx.currency] || '€'
+                        return (
+                          <tr key={tx.id} className="border-b border-dark-700/50 last:border-0">
+                            <td className="py-1.5 pr-4 text-gray-400">{formatDate(tx.date)}</td>
+                            <td className="py-1.5 pr-4 text-gray-300">{TX_TYPES[tx.type] || tx.type}</td>
+                            <td className="py-1.5 pr-4 text-white" title={tx.note}>{tx.symbol}</td>
+                            <td className="py-1.5 pr-4 text-right text-gray-300">
+                              {tx.type === 'split' ? `${tx.ratio}:1` : tx.quantity ? +tx.quantity.toFixed(6) : '-'}
+                            </td>
+                            <td className="py-1.5 pr-4 text-right text-gray-300">
+                              {tx.price ? `${sym}${tx.price.toFixed(2)}` : tx.amount ? `${sym}${tx.amount.toFixed(2)}` : '-'}
+                            </td>
+                            <td className="py-1.5 pr-4 text-right text-gray-500">{tx.fees ? `${sym}${tx.fees.toFixed(2)}` : '-'}</td>
+                            <td className="py-1.5 text-right whitespace-nowrap">
+                              <button onClick={() => handleTxEdit(tx)} className="text-gray-400 hover:text-white mr-2">Bearbeiten</button>
+                              <button onClick={() => handleTxDelete(tx)} className="text-red-400 hover:text-red-300">Löschen</button>
+                            </td>
+                          </tr>
+                        )
+                      })}
+                    </tbody>
+                  </table>
+                )}
+              </div>
+            </div>
+          )}
+        </div>
       </div>
 
       {/* Sell Modal */}
@@ -793,6 +2388,40 @@ function PortfolioContent({ token }) {
                 />
               </div>
 
+              <div className="grid grid-cols-3 gap-3 mb-4">
+                <div>
+                  <label className="block text-sm text-gray-400 mb-1">Stück</label>
+                  <input
+                    type="number"
+                    step="any"
+                    value={sellQuantity}
+                    onChange={(e) => setSellQuantity(e.target.value)}
+                    className="w-full px-3 py-2 bg-dark-700 border border-dark-600 rounded-lg text-white focus:outline-none focus:border-accent-500"
+                  />
+                </div>
+                <div>
+                  <label className="block text-sm text-gray-400 mb-1">Datum</label>
+                  <input
+                    type="date"
+                    value={sellDate}
+                    onChange={(e) => setSellDate(e.target.value)}
+                    className="w-full px-3 py-2 bg-dark-700 border border-dark-600 rounded-lg text-white focus:outline-none focus:border-accent-500"
+                  />
+                </div>
+                <div>
+                  <label className="block text-sm text-gray-400 mb-1">Gebühren</label>
+                  <input
+                    type="number"
+                    step="0.01"
+                    value={sellFees}
+                    onChange={(e) => setSellFees(e.target.value)}
+                    className="w-full px-3 py-2 bg-dark-700 border border-dark-600 rounded-lg text-white focus:outline-none focus:border-accent-500"
+                    placeholder="0.00"
+                  />
+                </div>
+              </div>
+              <p className="text-xs text-gray-500 mb-4">Teilverkäufe werden nach FIFO den ältesten Kauf-Lots zugeordnet.</p>
+
               {sellPrice && (
                 <div className="mb-4 p-3 bg-dark-700 rounded-lg">
                   <div className="text-sm text-gray-400">Voraussichtliche Rendite:</div>
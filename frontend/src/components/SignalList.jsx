@@ -1,5 +1,7 @@
 import { useState, useEffect, useMemo } from 'react'
 import { useCurrency } from '../context/CurrencyContext'
+import FundamentalFilterFields, { fundamentalFilterBody } from './FundamentalFilterFields'
+import UniverseSelect from './UniverseSelect'
 
 const MODE_COLORS = {
   defensive:  { bg: 'bg-blue-500/20', text: 'text-blue-400', border: 'border-blue-500/30', label: 'Defensiv' },
@@ -38,9 +40,14 @@ function SignalList({ token, isAdmin }) {
     minAvgReturn: '', maxAvgReturn: '', minMarketCap: ''
   })
   const [filtersOpen, setFiltersOpen] = useState(false)
+  // Fundamental filter is evaluated by the backend (admin default, point-in-time per signal month)
+  const [fundamentalFilter, setFundamentalFilter] = useState({})
+  const [showFundamentalExcluded, setShowFundamentalExcluded] = useState(false)
+  const [reloadKey, setReloadKey] = useState(0)
   const [sortField, setSortField] = useState('mode_count')
   const [sortDir, setSortDir] = useState('desc')
   const [signalFilter, setSignalFilter] = useState(null)
+  const [universeId, setUniverseId] = useState('')
 
   // Load admin default filters on mount
   useEffect(() => {
@@ -57,6 +64,7 @@ function SignalList({ token, isAdmin }) {
             maxAvgReturn: config.max_avg_return ?? '',
             minMarketCap: config.min_market_cap ?? '',
           })
+          setFundamentalFilter(config)
         }
       })
       .catch(() => {})
@@ -66,12 +74,13 @@ function SignalList({ token, isAdmin }) {
   useEffect(() => {
     setLoading(true)
     const headers = token ? { 'Authorization': `Bearer ${token}` } : {}
-    fetch(`/api/signal-list?month=${selectedMonth}`, { headers })
+    const universeParam = universeId ? `&universe_id=${universeId}` : ''
+    fetch(`/api/signal-list?month=${selectedMonth}${universeParam}`, { headers })
       .then(r => r.json())
       .then(data => setEntries(data.entries || []))
       .catch(() => setEntries([]))
       .finally(() => setLoading(false))
-  }, [selectedMonth, token])
+  }, [selectedMonth, universeId, token, reloadKey])
 
   const handleFilterChange = (key, value) => {
     setFilters(prev => ({ ...prev, [key]: value }))
@@ -96,8 +105,10 @@ function SignalList({ token, isAdmin }) {
           min_avg_return: filters.minAvgReturn !== '' ? parseFloat(filters.minAvgReturn) : null,
           max_avg_return: filters.maxAvgReturn !== '' ? parseFloat(filters.maxAvgReturn) : null,
           min_market_cap: filters.minMarketCap !== '' ? parseFloat(filters.minMarketCap) : null,
+          ...fundamentalFilterBody(fundamentalFilter),
         })
       })
+      setReloadKey(k => k + 1)
     } catch (err) {
       console.error('Failed to save filters:', err)
     }
@@ -148,9 +159,12 @@ function SignalList({ token, isAdmin }) {
       if (filters.minAvgReturn !== '' && entry.avg_return < parseFloat(filters.minAvgReturn)) return false
       if (filters.maxAvgReturn !== '' && entry.avg_return > parseFloat(filters.maxAvgReturn)) return false
       if (filters.minMarketCap !== '' && entry.market_cap < parseFloat(filters.minMarketCap) * 1e9) return false
+      if (entry.fundamental_reason && !showFundamentalExcluded) return false
       return true
     })
-  }, [entries, searchQuery, signalFilter, filters])
+  }, [entries, searchQuery, signalFilter, filters, showFundamentalExcluded])
+
+  const fundamentalExcludedCount = entries.filter(e => e.fundamental_reason).length
 
   const sortedEntries = useMemo(() => {
     return [...filteredEntries].sort((a, b) => {
@@ -246,6 +260,8 @@ function SignalList({ token, isAdmin }) {
 
             {/* Page Navigation */}
             <div className="flex items-center gap-3">
+              <UniverseSelect value={universeId} onChange={setUniverseId} emptyLabel="Alle Aktien"
+                className="px-3 py-2 bg-dark-700 border border-dark-600 rounded-lg text-sm text-white focus:outline-none focus:border-accent-500" />
               <button
                 onClick={() => navigateMonth(-1)}
                 disabled={currentPageIdx <= 0}
@@ -346,6 +362,18 @@ function SignalList({ token, isAdmin }) {
                     className="w-full px-2 py-1.5 text-sm bg-dark-700 border border-dark-600 rounded text-white placeholder-gray-500" />
                 </div>
               </div>
+              {isAdmin && (
+                <div className="mt-3">
+                  <label className="block text-xs text-gray-400 mb-2">Fundamentaldaten (Standard für alle)</label>
+                  <FundamentalFilterFields value={fundamentalFilter} onChange={setFundamentalFilter} />
+                </div>
+              )}
+              {fundamentalExcludedCount > 0 && (
+                <label className="mt-3 flex items-center gap-2 text-xs text-gray-400 cursor-pointer">
+                  <input type="checkbox" checked={showFundamentalExcluded} onChange={e => setShowFundamentalExcluded(e.target.checked)} />
+                  {fundamentalExcludedCount} durch Fundamentalfilter ausgeblendete Aktien anzeigen
+                </label>
+              )}
               <div className="mt-2 flex justify-end gap-2">
                 {hasActiveFilters && (
                   <button onClick={clearFilters} className="px-3 py-1 text-xs text-gray-400 hover:text-white transition-colors">Filter zur&uuml;cksetzen</button>
@@ -513,6 +541,8 @@ function SignalList({ token, isAdmin }) {
                         <td className={`px-2 py-1.5 ${!entry.visible ? 'line-through' : ''}`}>
                           <span className="font-medium text-white">{entry.name}</span>
                           <span className="text-gray-500 text-xs ml-1">({entry.symbol})</span>
+                          {entry.fundamentals?.sector && <span className="text-gray-600 text-[10px] ml-1">{entry.fundamentals.sector}</span>}
+                          {entry.fundamental_reason && <span className="text-yellow-500 text-[10px] ml-1" title={entry.fundamental_reason}>⚠ Fundamental</span>}
                         </td>
                         <td className="px-2 py-1.5">
                           <span className={`px-1.5 py-0.5 text-xs font-bold rounded border ${getSignalStyle(entry.signal)}`}>
@@ -4,6 +4,8 @@ import ArenaChart from './ArenaChart'
 import ArenaBacktestPanel from './ArenaBacktestPanel'
 import ArenaIndicatorChart from './ArenaIndicatorChart'
 import ArenaCalendarHeatmap from './ArenaCalendarHeatmap'
+import FundamentalFilterFields, { fundamentalFilterBody, hasFundamentalFilter } from './FundamentalFilterFields'
+import UniverseSelect from './UniverseSelect'
 import { useCurrency } from '../context/CurrencyContext'
 import { INTERVALS, INTERVAL_MAP, TV_INTERVAL_MAP, STRATEGIES, STRATEGY_PARAMS, STRATEGY_DEFAULT_INTERVAL, STRATEGY_ALGORITHMS, getDefaultParams, getPresetParams } from '../utils/arenaConfig'
 
@@ -851,6 +853,14 @@ function TradingArena({ isAdmin, token }) {
   const [longOnly, setLongOnly] = useState(true)
   const [usOnly, setUsOnly] = useState(true)
   const dataSource = 'yahoo'
+  // Fundamental filter is applied server-side per trade entry, so changing it re-runs the batch
+  const [fundamentalFilter, setFundamentalFilter] = useState({})
+  const [showFundamentals, setShowFundamentals] = useState(false)
+  const fundamentalFilterRef = useRef({})
+  fundamentalFilterRef.current = fundamentalFilter
+  // Universe replaces the trading watchlist in the batch (server-side, membership per trade entry)
+  const [universeId, setUniverseId] = useState('')
+  const universeIdRef = useRef('')
   const [hideFiltered, setHideFiltered] = useState(true)
   const [isFilterPending, startFilterTransition] = useTransition()
   const [showSimulation, setShowSimulation] = useState(false)
@@ -1517,6 +1527,8 @@ function TradingArena({ isAdmin, token }) {
           params,
           us_only: usOnlyFlag,
           data_source: src || dataSource,
+          ...fundamentalFilterBody(fundamentalFilterRef.current),
+          ...(universeIdRef.current ? { universe_id: universeIdRef.current } : {}),
         }),
         signal: controller.signal,
       })
@@ -2153,6 +2165,20 @@ function TradingArena({ isAdmin, token }) {
             <span className={`text-xs font-medium ${usOnly ? 'text-accent-400' : 'text-gray-400'}`}>US Only</span>
           </label>
 
+          {/* Universe (admin batch) */}
+          {isAdmin && (
+            <UniverseSelect
+              value={universeId}
+              emptyLabel="Trading Watchlist"
+              onChange={v => {
+                setUniverseId(v)
+                universeIdRef.current = v
+                runBatchBacktest(backtestStrategy, interval, strategyParams, usOnly)
+              }}
+              className="px-3 py-2 rounded-lg bg-dark-700 border border-dark-600 text-xs text-gray-300 focus:outline-none"
+            />
+          )}
+
           {/* Trades ab Datum */}
           <div className="flex items-center gap-1.5 px-3 py-1.5 rounded-lg bg-dark-700 border border-dark-600">
             <span className="text-xs text-gray-400 whitespace-nowrap">Trades ab</span>
@@ -2605,7 +2631,24 @@ function TradingArena({ isAdmin, token }) {
               >
                 {filtersActive ? 'Filter aktiv' : 'Filter anwenden'}
               </button>
+              <button type="button" onClick={() => setShowFundamentals(!showFundamentals)}
+                className={`px-3 py-1 text-xs rounded font-medium transition-colors whitespace-nowrap border ${
+                  hasFundamentalFilter(fundamentalFilter) ? 'bg-accent-600/20 text-accent-400 border-accent-500/50' : 'bg-dark-700 text-gray-400 border-dark-500 hover:text-white'
+                }`}>
+                Fundamentaldaten {showFundamentals ? '\u25B2' : '\u25BC'}
+              </button>
             </form>
+            {showFundamentals && (
+              <div className="mt-3">
+                <FundamentalFilterFields value={fundamentalFilter} onChange={setFundamentalFilter} />
+                <div className="flex justify-end mt-2">
+                  <button type="button" onClick={() => runBatchBacktest(backtestStrategy, interval, strategyParams, usOnly)}
+                    className="px-3 py-1 text-xs rounded font-medium bg-accent-600 text-white hover:bg-accent-500">
+                    Batch neu berechnen
+                  </button>
+                </div>
+              </div>
+            )}
           </div>
         )}
 
@@ -0,0 +1,27 @@
+import { useEffect, useState } from 'react'
+
+// Dropdown of the stored universes; value is the universe id or '' for the default symbol list.
+export default function UniverseSelect({ value, onChange, emptyLabel = 'Watchlist', className = '' }) {
+  const [universes, setUniverses] = useState([])
+
+  useEffect(() => {
+    fetch('/api/universes')
+      .then(r => (r.ok ? r.json() : []))
+      .then(list => setUniverses(Array.isArray(list) ? list : []))
+      .catch(() => setUniverses([]))
+  }, [])
+
+  return (
+    <select
+      value={value ?? ''}
+      onChange={e => onChange(e.target.value === '' ? '' : Number(e.target.value))}
+      className={className || 'bg-dark-700 border border-dark-500 rounded px-2 py-1.5 text-white text-sm'}
+      title="Universe: historische Zugehörigkeit wird pro Trade berücksichtigt"
+    >
+      <option value="">{emptyLabel}</option>
+      {universes.map(u => (
+        <option key={u.id} value={u.id}>{u.name} ({u.member_count})</option>
+      ))}
+    </select>
+  )
+}